@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+)
+
+var statusByCode = map[handler.ErrorCode]int{
+	handler.CodeInvalidRequest:      http.StatusBadRequest,
+	handler.CodeUnauthorized:        http.StatusUnauthorized,
+	handler.CodeForbidden:           http.StatusForbidden,
+	handler.CodeNotFound:            http.StatusNotFound,
+	handler.CodeMethodNotAllowed:    http.StatusMethodNotAllowed,
+	handler.CodeConflict:            http.StatusConflict,
+	handler.CodeRateLimited:         http.StatusTooManyRequests,
+	handler.CodeUpstreamUnavailable: http.StatusBadGateway,
+	handler.CodeUpstreamError:       http.StatusBadGateway,
+	handler.CodeTimeout:             http.StatusGatewayTimeout,
+	handler.CodeCanceled:            http.StatusGatewayTimeout,
+	handler.CodeInternal:            http.StatusInternalServerError,
+}
+
+type apiRequest struct {
+	Method  string
+	Path    string
+	Headers map[string]string
+	Body    string
+}
+
+type apiResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       string
+}
+
+func (a *app) handleEvent(ctx context.Context, raw json.RawMessage) (events.APIGatewayProxyResponse, error) {
+	req, err := parseEvent(raw)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to parse Lambda event")
+		return toProxyResponse(errorResponse(&handler.Error{Code: handler.CodeInvalidRequest, Err: err})), nil
+	}
+
+	return toProxyResponse(a.route(ctx, req)), nil
+}
+
+func parseEvent(raw json.RawMessage) (apiRequest, error) {
+	var probe struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return apiRequest{}, errors.New("invalid event")
+	}
+
+	var req apiRequest
+	var body string
+	var base64Encoded bool
+	var headers map[string]string
+
+	if probe.Version == "2.0" {
+		var event events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return apiRequest{}, errors.New("invalid HTTP API event")
+		}
+		req.Method = event.RequestContext.HTTP.Method
+		req.Path = event.RawPath
+		headers, body, base64Encoded = event.Headers, event.Body, event.IsBase64Encoded
+	} else {
+		var event events.APIGatewayProxyRequest
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return apiRequest{}, errors.New("invalid REST API event")
+		}
+		req.Method = event.HTTPMethod
+		req.Path = event.Path
+		headers, body, base64Encoded = event.Headers, event.Body, event.IsBase64Encoded
+	}
+
+	if req.Method == "" {
+		req.Method = http.MethodPost
+	}
+	req.Method = strings.ToUpper(req.Method)
+
+	req.Headers = make(map[string]string, len(headers))
+	for k, v := range headers {
+		req.Headers[strings.ToLower(k)] = v
+	}
+
+	if base64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return apiRequest{}, errors.New("invalid base64 body")
+		}
+		body = string(decoded)
+	}
+	req.Body = body
+
+	return req, nil
+}
+
+func respond(status int, result interface{}, err error) apiResponse {
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal response")
+		return errorResponse(err)
+	}
+
+	return apiResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
+
+func errorResponse(err error) apiResponse {
+	code := handler.ErrorCodeOf(err)
+	status, ok := statusByCode[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	message := err.Error()
+	if code == handler.CodeInternal {
+		message = "internal error"
+	}
+
+	body, _ := json.Marshal(models.ErrorResponse{Error: string(code), Message: message})
+	resp := apiResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+
+	var xrpcErr *atproto.XRPCError
+	if code == handler.CodeRateLimited && errors.As(err, &xrpcErr) {
+		if retryAfter := xrpcErr.Headers.Get("Retry-After"); retryAfter != "" {
+			resp.Headers["Retry-After"] = retryAfter
+		}
+	}
+
+	return resp
+}
+
+func toProxyResponse(resp apiResponse) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Headers,
+		Body:       resp.Body,
+	}
+}
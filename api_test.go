@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/auth"
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/ShareFrame/posting-service/idempotency"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApp(t *testing.T, pdsHandler http.HandlerFunc) *app {
+	pds := httptest.NewServer(pdsHandler)
+	t.Cleanup(pds.Close)
+
+	return &app{client: atproto.NewATProtoService(pds.Client(), atproto.Config{
+		BaseURL: pds.URL,
+		Retry:   atproto.RetryPolicy{MaxAttempts: 1},
+	})}
+}
+
+func pdsReplying(status int, body string, headers map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func restEvent(method, body string) json.RawMessage {
+	raw, _ := json.Marshal(map[string]interface{}{
+		"httpMethod": method,
+		"path":       "/posts",
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       body,
+	})
+	return raw
+}
+
+func TestHandleEvent(t *testing.T) {
+	const validBody = `{"authToken":"valid_token","did":"did:plc:alice","text":"Hello World!"}`
+	const created = `{"uri":"at://did:plc:alice/social.shareframe.feed.post/xyz","cid":"bafyre123456"}`
+
+	tests := []struct {
+		name         string
+		event        json.RawMessage
+		pds          http.HandlerFunc
+		expectStatus int
+		expectCode   string
+		expectHeader map[string]string
+		hiddenText   string
+	}{
+		{
+			name:         "Created via REST API event",
+			event:        restEvent(http.MethodPost, validBody),
+			pds:          pdsReplying(http.StatusOK, created, nil),
+			expectStatus: http.StatusCreated,
+		},
+		{
+			name: "Created via HTTP API v2 event with base64 body",
+			event: json.RawMessage(`{
+				"version": "2.0",
+				"rawPath": "/posts",
+				"headers": {"content-type": "application/json"},
+				"requestContext": {"http": {"method": "POST"}},
+				"isBase64Encoded": true,
+				"body": "` + base64.StdEncoding.EncodeToString([]byte(validBody)) + `"
+			}`),
+			pds:          pdsReplying(http.StatusOK, created, nil),
+			expectStatus: http.StatusCreated,
+		},
+		{
+			name:         "Legacy payload without a method creates a post",
+			event:        json.RawMessage(`{"body":` + string(mustMarshal(validBody)) + `}`),
+			pds:          pdsReplying(http.StatusOK, created, nil),
+			expectStatus: http.StatusCreated,
+		},
+		{
+			name:         "Malformed JSON body",
+			event:        restEvent(http.MethodPost, `{not json`),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   "invalid_request",
+		},
+		{
+			name:         "Validation failure",
+			event:        restEvent(http.MethodPost, `{"authToken":"valid_token","did":"did:plc:alice","imageUris":["https://example.com/doc.pdf"]}`),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   "invalid_request",
+		},
+		{
+			name:         "Client-supplied engagement counters",
+			event:        restEvent(http.MethodPost, `{"authToken":"valid_token","did":"did:plc:alice","text":"hi","likes":1000000}`),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   "invalid_request",
+		},
+		{
+			name:         "Missing auth token",
+			event:        restEvent(http.MethodPost, `{"did":"did:plc:alice","text":"hi"}`),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   "invalid_request",
+		},
+		{
+			name:         "PDS rejects the token",
+			event:        restEvent(http.MethodPost, validBody),
+			pds:          pdsReplying(http.StatusUnauthorized, `{"error":"InvalidToken","message":"Token could not be verified"}`, nil),
+			expectStatus: http.StatusUnauthorized,
+			expectCode:   "unauthorized",
+			hiddenText:   "could not be verified",
+		},
+		{
+			name:         "PDS rate limits",
+			event:        restEvent(http.MethodPost, validBody),
+			pds:          pdsReplying(http.StatusTooManyRequests, `{"error":"RateLimitExceeded","message":"slow down"}`, map[string]string{"Retry-After": "30"}),
+			expectStatus: http.StatusTooManyRequests,
+			expectCode:   "rate_limited",
+			expectHeader: map[string]string{"Retry-After": "30"},
+		},
+		{
+			name:         "PDS failure",
+			event:        restEvent(http.MethodPost, validBody),
+			pds:          pdsReplying(http.StatusInternalServerError, `{"error":"InternalServerError","message":"boom"}`, nil),
+			expectStatus: http.StatusBadGateway,
+			expectCode:   "upstream_error",
+			hiddenText:   "boom",
+		},
+		{
+			name:         "PDS failure that is not XRPC",
+			event:        restEvent(http.MethodPost, validBody),
+			pds:          pdsReplying(http.StatusBadGateway, `<html>internal host 10.0.3.7</html>`, nil),
+			expectStatus: http.StatusBadGateway,
+			expectCode:   "upstream_unavailable",
+			hiddenText:   "10.0.3.7",
+		},
+		{
+			name:         "Delete another user's post",
+			event:        restEvent(http.MethodDelete, `{"authToken":"valid_token","did":"did:plc:mallory","uri":"at://did:plc:alice/social.shareframe.feed.post/xyz"}`),
+			expectStatus: http.StatusForbidden,
+			expectCode:   "forbidden",
+		},
+		{
+			name:         "Unsupported method",
+			event:        restEvent(http.MethodGet, ""),
+			expectStatus: http.StatusMethodNotAllowed,
+			expectCode:   "method_not_allowed",
+		},
+		{
+			name:         "Event that is not JSON",
+			event:        json.RawMessage(`"just a string"`),
+			expectStatus: http.StatusBadRequest,
+			expectCode:   "invalid_request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pds := tt.pds
+			if pds == nil {
+				pds = func(w http.ResponseWriter, r *http.Request) {
+					t.Errorf("unexpected PDS call to %s", r.URL.Path)
+				}
+			}
+			a := newTestApp(t, pds)
+
+			resp, err := a.handleEvent(context.Background(), tt.event)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectStatus, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Headers["Content-Type"])
+			for k, v := range tt.expectHeader {
+				assert.Equal(t, v, resp.Headers[k])
+			}
+
+			if tt.expectCode != "" {
+				var body models.ErrorResponse
+				assert.NoError(t, json.Unmarshal([]byte(resp.Body), &body))
+				assert.Equal(t, tt.expectCode, body.Error)
+				assert.NotEmpty(t, body.Message)
+				if tt.hiddenText != "" {
+					assert.NotContains(t, resp.Body, tt.hiddenText)
+				}
+			} else {
+				var body models.PostResponse
+				assert.NoError(t, json.Unmarshal([]byte(resp.Body), &body))
+				assert.Equal(t, "bafyre123456", body.CID)
+			}
+		})
+	}
+}
+
+func TestCreatePostWritesOnlyClientFields(t *testing.T) {
+	var record map[string]interface{}
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Record map[string]interface{} `json:"record"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		record = req.Record
+		w.Write([]byte(`{"uri":"at://did:plc:alice/social.shareframe.feed.post/xyz","cid":"bafyre123456"}`))
+	})
+
+	body := `{"authToken":"valid_token","did":"did:plc:alice","text":"Tokyo!","city":"Tokyo","tags":["travel"]}`
+	resp, err := a.handleEvent(context.Background(), restEvent(http.MethodPost, body))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "Tokyo!", record["text"])
+	assert.Equal(t, "Tokyo", record["city"])
+	assert.Equal(t, []interface{}{"travel"}, record["tags"])
+	assert.Equal(t, "social.shareframe.feed.post", record["nsid"])
+	for _, name := range models.ServerOwnedFields {
+		assert.NotContains(t, record, name)
+	}
+}
+
+func TestCreatePostIdempotencyKey(t *testing.T) {
+	var rkeys []string
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RKey string `json:"rkey"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		rkeys = append(rkeys, req.RKey)
+		w.Write([]byte(`{"uri":"at://did:plc:alice/social.shareframe.feed.post/` + req.RKey + `","cid":"bafyre123456"}`))
+	})
+	a.idempotency = idempotency.NewMemoryStore()
+
+	event := func(key, body string) json.RawMessage {
+		return mustMarshal(map[string]interface{}{
+			"httpMethod": http.MethodPost,
+			"path":       "/posts",
+			"headers":    map[string]string{"Content-Type": "application/json", "Idempotency-Key": key},
+			"body":       body,
+		})
+	}
+	const body = `{"authToken":"valid_token","did":"did:plc:alice","text":"Hello World!"}`
+
+	first, err := a.handleEvent(context.Background(), event("abc", body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, first.StatusCode)
+
+	retry, err := a.handleEvent(context.Background(), event("abc", body))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, retry.StatusCode)
+	assert.Equal(t, first.Body, retry.Body)
+
+	fromBody, err := a.handleEvent(context.Background(), restEvent(http.MethodPost, `{"authToken":"valid_token","did":"did:plc:alice","text":"Hello World!","idempotencyKey":"abc"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, first.Body, fromBody.Body)
+
+	changed, err := a.handleEvent(context.Background(), event("abc", `{"authToken":"valid_token","did":"did:plc:alice","text":"Goodbye"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, changed.StatusCode)
+
+	assert.Len(t, rkeys, 1)
+	assert.Len(t, rkeys[0], 13)
+}
+
+func hs256Token(secret []byte, sub string, exp time.Time) string {
+	return hs256ScopedToken(secret, sub, "com.atproto.access", exp)
+}
+
+func hs256ScopedToken(secret []byte, sub, scope string, exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := base64.RawURLEncoding.EncodeToString(mustMarshal(map[string]interface{}{
+		"sub":   sub,
+		"aud":   "did:web:pds.test",
+		"exp":   exp.Unix(),
+		"scope": scope,
+	}))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + claims))
+	return header + "." + claims + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newTestVerifier(t *testing.T, cfg auth.Config) *auth.Verifier {
+	t.Helper()
+	verifier, err := auth.NewVerifier(cfg)
+	require.NoError(t, err)
+	return verifier
+}
+
+func TestHandleEventVerifiesAuthToken(t *testing.T) {
+	secret := []byte("test-secret")
+	valid := hs256Token(secret, "did:plc:alice", time.Now().Add(time.Hour))
+	expired := hs256Token(secret, "did:plc:alice", time.Now().Add(-time.Hour))
+	forged := hs256Token([]byte("guess"), "did:plc:alice", time.Now().Add(time.Hour))
+	other := hs256Token(secret, "did:plc:mallory", time.Now().Add(time.Hour))
+	refresh := hs256ScopedToken(secret, "did:plc:alice", "com.atproto.refresh", time.Now().Add(24*time.Hour))
+
+	body := func(token, refresh string) string {
+		return string(mustMarshal(map[string]string{
+			"authToken":    token,
+			"refreshToken": refresh,
+			"did":          "did:plc:alice",
+			"text":         "Hello World!",
+		}))
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		expectStatus int
+	}{
+		{
+			name:         "Valid token",
+			method:       http.MethodPost,
+			body:         body(valid, ""),
+			expectStatus: http.StatusCreated,
+		},
+		{
+			name:         "Token issued to another DID",
+			method:       http.MethodPost,
+			body:         body(other, ""),
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Forged signature",
+			method:       http.MethodPost,
+			body:         body(forged, ""),
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Expired token without refresh token",
+			method:       http.MethodPost,
+			body:         body(expired, ""),
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Expired token with valid refresh token",
+			method:       http.MethodPost,
+			body:         body(expired, refresh),
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Refresh token in place of an access token",
+			method:       http.MethodPost,
+			body:         body(refresh, ""),
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Expired token with refresh token for another DID",
+			method:       http.MethodPost,
+			body:         body(expired, other),
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "Delete with another DID's token",
+			method:       http.MethodDelete,
+			body:         `{"authToken":"` + other + `","did":"did:plc:alice","uri":"at://did:plc:alice/social.shareframe.feed.post/xyz"}`,
+			expectStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.expectStatus == http.StatusUnauthorized {
+					t.Errorf("unexpected PDS call to %s", r.URL.Path)
+				}
+				w.Write([]byte(`{"uri":"at://did:plc:alice/social.shareframe.feed.post/xyz","cid":"bafyre123456"}`))
+			})
+			a.verifier = newTestVerifier(t, auth.Config{
+				Keys:     auth.StaticKeys{"": secret},
+				Audience: "did:web:pds.test",
+			})
+
+			resp, err := a.handleEvent(context.Background(), restEvent(tt.method, tt.body))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectStatus, resp.StatusCode)
+			if tt.expectStatus == http.StatusUnauthorized {
+				var body models.ErrorResponse
+				assert.NoError(t, json.Unmarshal([]byte(resp.Body), &body))
+				assert.Equal(t, "unauthorized", body.Error)
+			}
+		})
+	}
+}
+
+func TestAuthorizeWithoutVerifier(t *testing.T) {
+	a := &app{}
+
+	err := a.authorize(context.Background(), "token", "did:plc:alice")
+	assert.Equal(t, handler.CodeForbidden, handler.ErrorCodeOf(err))
+
+	// Routes that forward the token leave it to the PDS.
+	assert.NoError(t, a.authorizeForwarded(context.Background(), "token", "did:plc:alice"))
+}
+
+func mustMarshal(v interface{}) []byte {
+	raw, _ := json.Marshal(v)
+	return raw
+}
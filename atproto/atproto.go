@@ -2,82 +2,238 @@ package atproto
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/ShareFrame/posting-service/media"
 	"github.com/ShareFrame/posting-service/models"
 	"github.com/sirupsen/logrus"
 )
 
 type ATProtoClient interface {
-	PostToFeed(post models.ShareFrameFeedPost, authToken, did string) (*models.PostResponse, error)
+	SessionClient
+	PostToFeed(ctx context.Context, post models.ShareFrameFeedPost, authToken, did, rkey string) (*models.PostResponse, error)
+	UploadBlob(ctx context.Context, authToken, did string, media models.MediaUpload) (*models.Blob, error)
+	DeletePost(ctx context.Context, authToken, did, rkey string) error
+	GetRecord(ctx context.Context, authToken, did, rkey string) (*models.GetRecordResponse, error)
+	PutRecord(ctx context.Context, authToken, did, rkey string, post models.ShareFrameFeedPost, swapRecord string) (*models.PostResponse, error)
+	ApplyWrites(ctx context.Context, authToken string, req models.ApplyWritesRequest) (*models.ApplyWritesResponse, error)
+	ResolveHandle(ctx context.Context, handle string) (string, error)
+}
+
+const DefaultBaseURL = "https://shareframe.social"
+
+type Config struct {
+	BaseURL  string
+	Resolver PDSResolver
+	Retry    RetryPolicy
+	// Media downloads media sent by URL. Defaults to a media.Verifier, which
+	// refuses private and reserved addresses.
+	Media MediaFetcher
+}
+
+type MediaFetcher interface {
+	Fetch(ctx context.Context, rawURL string, limit int64) ([]byte, string, error)
 }
 
 type ATProtoService struct {
-	client *http.Client
+	client   *http.Client
+	baseURL  string
+	resolver PDSResolver
+	retry    RetryPolicy
+	media    MediaFetcher
+	sleep    func(ctx context.Context, d time.Duration) error
+	jitter   func() float64
+	now      func() time.Time
 }
 
-func NewATProtoService(client *http.Client) *ATProtoService {
-	if client == nil {
+// NewATProtoService sends requests with client. When it is nil and PDS
+// endpoints are resolved from DID documents, the client refuses private and
+// reserved addresses.
+func NewATProtoService(client *http.Client, cfg Config) *ATProtoService {
+	switch {
+	case client != nil:
+	case cfg.Resolver != nil:
+		client = newPublicClient()
+	default:
 		client = &http.Client{Timeout: 10 * time.Second}
 	}
-	return &ATProtoService{client: client}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Media == nil {
+		cfg.Media = media.NewVerifier(media.Config{})
+	}
+	return &ATProtoService{
+		client:   client,
+		baseURL:  strings.TrimRight(cfg.BaseURL, "/"),
+		resolver: cfg.Resolver,
+		retry:    cfg.Retry.withDefaults(),
+		media:    cfg.Media,
+		sleep:    sleepContext,
+		jitter:   rand.Float64,
+		now:      time.Now,
+	}
 }
 
-func (s *ATProtoService) PostToFeed(post models.ShareFrameFeedPost, authToken, did string) (*models.PostResponse, error) {
-	const postURL = "https://shareframe.social/xrpc/com.atproto.repo.createRecord"
+func (s *ATProtoService) xrpcURL(ctx context.Context, did, method string) (string, error) {
+	if s.resolver == nil || did == "" {
+		return s.baseURL + "/xrpc/" + method, nil
+	}
+
+	pdsURL, err := s.resolver.ResolvePDS(ctx, did)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Failed to resolve PDS")
+		return "", fmt.Errorf("failed to resolve PDS: %w", err)
+	}
+	return pdsURL + "/xrpc/" + method, nil
+}
 
-	payload, err := json.Marshal(models.CreateRecordRequest{
+func (s *ATProtoService) PostToFeed(ctx context.Context, post models.ShareFrameFeedPost, authToken, did, rkey string) (*models.PostResponse, error) {
+	return s.CreateRecord(ctx, authToken, models.CreateRecordRequest{
 		Repo:       did,
-		Collection: "social.shareframe.feed.post",
+		Collection: models.FeedPostNSID,
+		RKey:       rkey,
 		Record:     post,
 	})
+}
 
+func (s *ATProtoService) CreateRecord(ctx context.Context, authToken string, req models.CreateRecordRequest) (*models.PostResponse, error) {
+	payload, err := json.Marshal(req)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to marshal JSON payload")
 		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", postURL, bytes.NewReader(payload))
+	var postResponse models.PostResponse
+	err = s.do(ctx, xrpcRequest{
+		method:      http.MethodPost,
+		nsid:        "com.atproto.repo.createRecord",
+		did:         req.Repo,
+		authToken:   authToken,
+		contentType: "application/json",
+		body:        payload,
+		// Without an rkey the PDS picks one, so a retry after a lost
+		// response would create a second record.
+		idempotent: req.RKey != "",
+	}, &postResponse)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create HTTP request")
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+authToken)
+	return &postResponse, nil
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		logrus.WithError(err).Error("HTTP request failed")
-		return nil, fmt.Errorf("failed to send request: %w", err)
+type xrpcRequest struct {
+	method      string
+	nsid        string
+	did         string
+	authToken   string
+	contentType string
+	body        []byte
+	query       url.Values
+
+	// idempotent marks a request that is safe to resend after a failure
+	// whose outcome is unknown. GETs always are.
+	idempotent bool
+}
+
+func (s *ATProtoService) do(ctx context.Context, r xrpcRequest, out interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return canceledError(r.nsid, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	endpoint, err := s.xrpcURL(ctx, r.did, r.nsid)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to read response body")
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		if ctx.Err() != nil {
+			return canceledError(r.nsid, ctx.Err())
+		}
+		return err
+	}
+	if len(r.query) > 0 {
+		endpoint += "?" + r.query.Encode()
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		logrus.WithField("status", resp.StatusCode).Error("Failed to post to feed")
-		return nil, fmt.Errorf("failed to post: %s", string(body))
+	var body []byte
+	attempt := 1
+	for ; ; attempt++ {
+		var resp *http.Response
+		resp, body, err = s.send(ctx, endpoint, r)
+		if err == nil && resp.StatusCode != http.StatusOK {
+			err = responseError(r.nsid, attempt, resp, body)
+		}
+
+		delay, retry := s.retryDelay(ctx, r, attempt, resp, err)
+		if !retry {
+			break
+		}
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"nsid":    r.nsid,
+			"attempt": attempt,
+			"delay":   delay.String(),
+		}).Warn("Retrying XRPC request")
+
+		if sleepErr := s.sleep(ctx, delay); sleepErr != nil {
+			return canceledError(r.nsid, sleepErr)
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return canceledError(r.nsid, ctx.Err())
+		}
+		return err
 	}
 
-	var postResponse models.PostResponse
-	if err := json.Unmarshal(body, &postResponse); err != nil {
-		logrus.WithError(err).Error("Failed to parse response JSON")
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			logrus.WithError(err).Error("Failed to parse response JSON")
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"status": resp.StatusCode,
-		"body":   string(body),
+		"nsid":     r.nsid,
+		"status":   http.StatusOK,
+		"attempts": attempt,
 	}).Info("ATProto response")
 
-	return &postResponse, nil
+	return nil
+}
+
+func (s *ATProtoService) send(ctx context.Context, endpoint string, r xrpcRequest) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, r.method, endpoint, bytes.NewReader(r.body))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create HTTP request")
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if r.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+r.authToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		logrus.WithError(err).WithField("nsid", r.nsid).Error("HTTP request failed")
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read response body")
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp, body, nil
 }
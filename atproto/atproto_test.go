@@ -1,9 +1,12 @@
 package atproto
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -129,9 +132,9 @@ func TestPostToFeed(t *testing.T) {
 			}
 
 			mockClient := &http.Client{Transport: mockTransport}
-			service := NewATProtoService(mockClient)
+			service := NewATProtoService(mockClient, Config{})
 
-			resp, err := service.PostToFeed(tt.post, tt.authToken, tt.did)
+			resp, err := service.PostToFeed(context.Background(), tt.post, tt.authToken, tt.did, "")
 
 			if tt.expectErr {
 				assert.Error(t, err)
@@ -143,3 +146,112 @@ func TestPostToFeed(t *testing.T) {
 		})
 	}
 }
+
+func TestPostToFeedUsesConfiguredBaseURL(t *testing.T) {
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"uri":"at://did:example:123/social.shareframe.feed.post/xyz","cid":"bafyre123456"}`))
+	}))
+	defer server.Close()
+
+	service := NewATProtoService(server.Client(), Config{BaseURL: server.URL + "/"})
+
+	resp, err := service.PostToFeed(context.Background(), models.ShareFrameFeedPost{
+		NSID:      "social.shareframe.feed.post",
+		Text:      "Hello staging!",
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}, "valid_token", "did:example:123", "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "at://did:example:123/social.shareframe.feed.post/xyz", resp.URI)
+	assert.Equal(t, "/xrpc/com.atproto.repo.createRecord", gotPath)
+	assert.Equal(t, "Bearer valid_token", gotAuth)
+}
+
+func TestPostToFeedSendsRKey(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Write([]byte(`{"uri":"at://did:example:123/social.shareframe.feed.post/3jzfcijpj2z2a","cid":"bafyre123456"}`))
+	}))
+	defer server.Close()
+
+	service := NewATProtoService(server.Client(), Config{BaseURL: server.URL})
+	post := models.ShareFrameFeedPost{NSID: "social.shareframe.feed.post", Text: "Hi", CreatedAt: time.Now().Format(time.RFC3339)}
+
+	_, err := service.PostToFeed(context.Background(), post, "valid_token", "did:example:123", "3jzfcijpj2z2a")
+	assert.NoError(t, err)
+	assert.Equal(t, "3jzfcijpj2z2a", body["rkey"])
+
+	_, err = service.PostToFeed(context.Background(), post, "valid_token", "did:example:123", "")
+	assert.NoError(t, err)
+	assert.NotContains(t, body, "rkey")
+}
+
+func TestCreateRecordOptionalParameters(t *testing.T) {
+	validate := false
+	tests := []struct {
+		name   string
+		req    models.CreateRecordRequest
+		expect map[string]interface{}
+	}{
+		{
+			name: "Only required parameters",
+			req:  models.CreateRecordRequest{Repo: "did:example:123", Collection: "social.shareframe.feed.post"},
+			expect: map[string]interface{}{
+				"repo":       "did:example:123",
+				"collection": "social.shareframe.feed.post",
+			},
+		},
+		{
+			name: "All parameters",
+			req: models.CreateRecordRequest{
+				Repo:       "did:example:123",
+				Collection: "social.shareframe.feed.post",
+				RKey:       "3jzfcijpj2z2a",
+				Validate:   &validate,
+				SwapCommit: "bafyreicommit",
+			},
+			expect: map[string]interface{}{
+				"repo":       "did:example:123",
+				"collection": "social.shareframe.feed.post",
+				"rkey":       "3jzfcijpj2z2a",
+				"validate":   false,
+				"swapCommit": "bafyreicommit",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				w.Write([]byte(`{"uri":"at://did:example:123/social.shareframe.feed.post/3jzfcijpj2z2a","cid":"bafyre123456"}`))
+			}))
+			defer server.Close()
+
+			service := NewATProtoService(server.Client(), Config{BaseURL: server.URL})
+			resp, err := service.CreateRecord(context.Background(), "valid_token", tt.req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "bafyre123456", resp.CID)
+			delete(body, "record")
+			assert.Equal(t, tt.expect, body)
+		})
+	}
+}
+
+func TestNewATProtoServiceDefaults(t *testing.T) {
+	service := NewATProtoService(nil, Config{})
+
+	postURL, err := service.xrpcURL(context.Background(), "did:example:123", "com.atproto.repo.createRecord")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, service.client)
+	assert.Equal(t, "https://shareframe.social/xrpc/com.atproto.repo.createRecord", postURL)
+}
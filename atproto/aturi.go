@@ -0,0 +1,44 @@
+package atproto
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ShareFrame/posting-service/atproto/syntax"
+)
+
+type ATURI struct {
+	DID        string
+	Collection string
+	RKey       string
+}
+
+func ParseATURI(s string) (ATURI, error) {
+	rest, ok := strings.CutPrefix(s, "at://")
+	if !ok {
+		return ATURI{}, fmt.Errorf("invalid AT-URI %q: must start with at://", s)
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 {
+		return ATURI{}, fmt.Errorf("invalid AT-URI %q: expected at://<did>/<collection>/<rkey>", s)
+	}
+
+	uri := ATURI{DID: parts[0], Collection: parts[1], RKey: parts[2]}
+
+	if !syntax.ValidDID(uri.DID) {
+		return ATURI{}, fmt.Errorf("invalid AT-URI %q: authority must be a DID", s)
+	}
+	if !syntax.ValidNSID(uri.Collection) {
+		return ATURI{}, fmt.Errorf("invalid AT-URI %q: invalid collection NSID", s)
+	}
+	if !syntax.ValidRecordKey(uri.RKey) {
+		return ATURI{}, fmt.Errorf("invalid AT-URI %q: invalid record key", s)
+	}
+
+	return uri, nil
+}
+
+func (u ATURI) String() string {
+	return "at://" + u.DID + "/" + u.Collection + "/" + u.RKey
+}
@@ -0,0 +1,54 @@
+package atproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseATURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		expected  ATURI
+		expectErr bool
+	}{
+		{
+			name: "Valid post URI",
+			uri:  "at://did:plc:ewvi7nxzyoun6zhxrhs64oiz/social.shareframe.feed.post/3jzfcijpj2z2a",
+			expected: ATURI{
+				DID:        "did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+				Collection: "social.shareframe.feed.post",
+				RKey:       "3jzfcijpj2z2a",
+			},
+		},
+		{
+			name: "did:web authority",
+			uri:  "at://did:web:example.com/social.shareframe.feed.post/self",
+			expected: ATURI{
+				DID:        "did:web:example.com",
+				Collection: "social.shareframe.feed.post",
+				RKey:       "self",
+			},
+		},
+		{name: "Wrong scheme", uri: "https://did:plc:abc/social.shareframe.feed.post/xyz", expectErr: true},
+		{name: "Handle authority", uri: "at://alice.example.com/social.shareframe.feed.post/xyz", expectErr: true},
+		{name: "Missing rkey", uri: "at://did:plc:abc/social.shareframe.feed.post", expectErr: true},
+		{name: "Extra path segment", uri: "at://did:plc:abc/social.shareframe.feed.post/xyz/extra", expectErr: true},
+		{name: "Invalid collection", uri: "at://did:plc:abc/post/xyz", expectErr: true},
+		{name: "Dot rkey", uri: "at://did:plc:abc/social.shareframe.feed.post/..", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := ParseATURI(tt.uri)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, uri)
+			assert.Equal(t, tt.uri, uri.String())
+		})
+	}
+}
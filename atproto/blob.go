@@ -0,0 +1,76 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+const MaxBlobSize = 50 << 20
+
+var ErrBlobTooLarge = fmt.Errorf("media exceeds the %d byte limit", MaxBlobSize)
+
+func (s *ATProtoService) UploadBlob(ctx context.Context, authToken, did string, upload models.MediaUpload) (*models.Blob, error) {
+	data, mimeType := upload.Data, upload.MimeType
+
+	if len(data) == 0 {
+		if upload.SourceURL == "" {
+			return nil, errors.New("media has neither data nor a source URL")
+		}
+
+		fetched, contentType, err := s.fetchMedia(ctx, upload.SourceURL)
+		if err != nil {
+			return nil, err
+		}
+		// The fetched bytes decide the type, whatever the caller declared.
+		data, mimeType = fetched, contentType
+	}
+
+	if len(data) > MaxBlobSize {
+		return nil, ErrBlobTooLarge
+	}
+
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	var uploadResponse models.UploadBlobResponse
+	err := s.do(ctx, xrpcRequest{
+		method:      http.MethodPost,
+		nsid:        "com.atproto.repo.uploadBlob",
+		did:         did,
+		authToken:   authToken,
+		contentType: mimeType,
+		body:        data,
+		idempotent:  true,
+	}, &uploadResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if uploadResponse.Blob.Ref.Link == "" {
+		logrus.WithField("DID", did).Error("uploadBlob returned no blob reference")
+		return nil, errors.New("uploadBlob returned no blob reference")
+	}
+
+	return &uploadResponse.Blob, nil
+}
+
+// fetchMedia downloads a media source URL with the media fetcher's address
+// checks, so a caller cannot point it at the service's own network.
+func (s *ATProtoService) fetchMedia(ctx context.Context, sourceURL string) ([]byte, string, error) {
+	data, contentType, err := s.media.Fetch(ctx, sourceURL, MaxBlobSize)
+	if errors.Is(err, media.ErrTooLarge) {
+		return nil, "", ErrBlobTooLarge
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("url", sourceURL).Error("Failed to fetch media")
+		return nil, "", fmt.Errorf("failed to fetch media: %w", err)
+	}
+	return data, contentType, nil
+}
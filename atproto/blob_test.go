@@ -0,0 +1,184 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func fakeUploadBlobPDS(t *testing.T, gotBody *[]byte, gotType *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/xrpc/com.atproto.repo.uploadBlob", r.URL.Path)
+		assert.Equal(t, "Bearer valid_token", r.Header.Get("Authorization"))
+
+		body, _ := io.ReadAll(r.Body)
+		*gotBody = body
+		*gotType = r.Header.Get("Content-Type")
+
+		w.Write([]byte(`{"blob":{"$type":"blob","ref":{"$link":"bafkreiexample"},"mimeType":"` + *gotType + `","size":` + strconv.Itoa(len(body)) + `}}`))
+	}))
+}
+
+func TestUploadBlob(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.png":
+			w.Header().Set("Content-Type", "image/png; charset=binary")
+			w.Write(pngHeader)
+		case "/untyped":
+			w.Header().Set("Content-Type", "")
+			w.Write(pngHeader)
+		case "/mislabelled.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(pngHeader)
+		case "/page.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("<html><body>not an image</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer source.Close()
+
+	tests := []struct {
+		name       string
+		media      models.MediaUpload
+		expectType string
+		expectErr  bool
+	}{
+		{
+			name:       "Raw data with declared type",
+			media:      models.MediaUpload{Data: []byte("video-bytes"), MimeType: "video/mp4"},
+			expectType: "video/mp4",
+		},
+		{
+			name:       "Raw data with sniffed type",
+			media:      models.MediaUpload{Data: pngHeader},
+			expectType: "image/png",
+		},
+		{
+			name:       "Fetched from source URL",
+			media:      models.MediaUpload{SourceURL: source.URL + "/photo.png"},
+			expectType: "image/png",
+		},
+		{
+			name:       "Fetched without content type",
+			media:      models.MediaUpload{SourceURL: source.URL + "/untyped"},
+			expectType: "image/png",
+		},
+		{
+			name:       "Fetched type comes from the bytes",
+			media:      models.MediaUpload{SourceURL: source.URL + "/mislabelled.jpg", MimeType: "image/jpeg"},
+			expectType: "image/png",
+		},
+		{
+			name:      "Fetched body is not media",
+			media:     models.MediaUpload{SourceURL: source.URL + "/page.png"},
+			expectErr: true,
+		},
+		{
+			name:      "Source URL not found",
+			media:     models.MediaUpload{SourceURL: source.URL + "/missing.png"},
+			expectErr: true,
+		},
+		{
+			name:      "Unsupported source scheme",
+			media:     models.MediaUpload{SourceURL: "file:///etc/passwd"},
+			expectErr: true,
+		},
+		{
+			name:      "No data and no source",
+			media:     models.MediaUpload{MimeType: "image/png"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody []byte
+			var gotType string
+			pds := fakeUploadBlobPDS(t, &gotBody, &gotType)
+			defer pds.Close()
+
+			service := NewATProtoService(pds.Client(), Config{
+				BaseURL: pds.URL,
+				Media:   media.NewVerifier(media.Config{Client: source.Client(), AllowPrivateNetworks: true}),
+			})
+
+			blob, err := service.UploadBlob(context.Background(), "valid_token", "did:example:123", tt.media)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, blob)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "blob", blob.Type)
+			assert.Equal(t, "bafkreiexample", blob.Ref.Link)
+			assert.Equal(t, tt.expectType, blob.MimeType)
+			assert.Equal(t, int64(len(gotBody)), blob.Size)
+			assert.Equal(t, tt.expectType, gotType)
+		})
+	}
+}
+
+func TestUploadBlobRejectsOversizedMedia(t *testing.T) {
+	service := NewATProtoService(nil, Config{BaseURL: "http://127.0.0.1:1"})
+
+	blob, err := service.UploadBlob(context.Background(), "valid_token", "did:example:123", models.MediaUpload{
+		Data:     []byte(strings.Repeat("a", MaxBlobSize+1)),
+		MimeType: "video/mp4",
+	})
+
+	assert.Nil(t, blob)
+	assert.True(t, errors.Is(err, ErrBlobTooLarge))
+}
+
+func TestUploadBlobRefusesPrivateSources(t *testing.T) {
+	var fetched bool
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		w.Write(pngHeader)
+	}))
+	defer source.Close()
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected PDS call to %s", r.URL.Path)
+	}))
+	defer pds.Close()
+
+	service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+	for _, sourceURL := range []string{source.URL + "/photo.png", "http://169.254.169.254/latest/meta-data"} {
+		blob, err := service.UploadBlob(context.Background(), "valid_token", "did:example:123", models.MediaUpload{SourceURL: sourceURL})
+
+		assert.ErrorIs(t, err, media.ErrBlockedAddress, sourceURL)
+		assert.Nil(t, blob)
+	}
+	assert.False(t, fetched)
+}
+
+func TestUploadBlobRejectsMissingRef(t *testing.T) {
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"blob":{}}`))
+	}))
+	defer pds.Close()
+
+	service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+	blob, err := service.UploadBlob(context.Background(), "valid_token", "did:example:123", models.MediaUpload{Data: pngHeader})
+
+	assert.Error(t, err)
+	assert.Nil(t, blob)
+}
@@ -0,0 +1,73 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostToFeedHonorsContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       func() (context.Context, context.CancelFunc)
+		expectErr error
+	}{
+		{
+			name: "Already canceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			expectErr: context.Canceled,
+		},
+		{
+			name: "Deadline passes while the PDS is slow",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			expectErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			release := make(chan struct{})
+			pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				select {
+				case <-r.Context().Done():
+				case <-release:
+				}
+			}))
+			defer pds.Close()
+			defer close(release)
+
+			service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			resp, err := service.PostToFeed(ctx, models.ShareFrameFeedPost{
+				NSID:      "social.shareframe.feed.post",
+				Text:      "Hello",
+				CreatedAt: time.Now().Format(time.RFC3339),
+			}, "valid_token", "did:plc:alice", "")
+
+			assert.Nil(t, resp)
+			assert.True(t, errors.Is(err, ErrCanceled), "expected ErrCanceled, got %v", err)
+			assert.True(t, errors.Is(err, tt.expectErr), "expected %v, got %v", tt.expectErr, err)
+			assert.Less(t, time.Since(start), 2*time.Second)
+			assert.LessOrEqual(t, requests.Load(), int32(1), "canceled requests must not be retried")
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package dagcbor encodes records in the DAG-CBOR form a PDS stores them in,
+// so a record's CID can be known before it is written.
+package dagcbor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	cidVersion   = 0x01
+	codecDAGCBOR = 0x71
+	hashSHA256   = 0x12
+
+	tagCID = 42
+)
+
+var base32Lower = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Marshal encodes v, which must follow the ATProto data model once rendered
+// as JSON: no floats, with CID links as {"$link": ...} and bytes as
+// {"$bytes": ...}.
+func Marshal(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode record: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CID returns the CIDv1 (dag-cbor, sha2-256) of v in its base32 string form.
+func CID(v interface{}) (string, error) {
+	data, err := Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	cid := append([]byte{cidVersion, codecDAGCBOR, hashSHA256, byte(len(sum))}, sum[:]...)
+	return "b" + base32Lower.EncodeToString(cid), nil
+}
+
+func encode(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return fmt.Errorf("number %s is not an integer", v)
+		}
+		if n < 0 {
+			writeHead(buf, 1, uint64(-(n + 1)))
+		} else {
+			writeHead(buf, 0, uint64(n))
+		}
+	case string:
+		writeHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		return encodeMap(buf, v)
+	default:
+		return fmt.Errorf("unsupported value of type %T", value)
+	}
+	return nil
+}
+
+func encodeMap(buf *bytes.Buffer, m map[string]interface{}) error {
+	if len(m) == 1 {
+		if link, ok := m["$link"].(string); ok {
+			cid, err := parseCID(link)
+			if err != nil {
+				return err
+			}
+			writeHead(buf, 6, tagCID)
+			writeHead(buf, 2, uint64(len(cid)+1))
+			buf.WriteByte(0x00)
+			buf.Write(cid)
+			return nil
+		}
+		if b64, ok := m["$bytes"].(string); ok {
+			data, err := base64.RawStdEncoding.DecodeString(b64)
+			if err != nil {
+				return fmt.Errorf("invalid $bytes: %w", err)
+			}
+			writeHead(buf, 2, uint64(len(data)))
+			buf.Write(data)
+			return nil
+		}
+	}
+
+	// DAG-CBOR orders map keys by length first, then bytewise.
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	writeHead(buf, 5, uint64(len(m)))
+	for _, k := range keys {
+		writeHead(buf, 3, uint64(len(k)))
+		buf.WriteString(k)
+		if err := encode(buf, m[k]); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func writeHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= 0xff:
+		buf.Write([]byte{major | 24, byte(n)})
+	case n <= 0xffff:
+		buf.Write([]byte{major | 25, byte(n >> 8), byte(n)})
+	case n <= 0xffffffff:
+		buf.Write([]byte{major | 26, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+	default:
+		buf.WriteByte(major | 27)
+		for shift := 56; shift >= 0; shift -= 8 {
+			buf.WriteByte(byte(n >> shift))
+		}
+	}
+}
+
+func parseCID(s string) ([]byte, error) {
+	if len(s) < 2 || s[0] != 'b' {
+		return nil, fmt.Errorf("invalid CID %q: only base32 CIDv1 is supported", s)
+	}
+	cid, err := base32Lower.DecodeString(s[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CID %q: %w", s, err)
+	}
+	if len(cid) == 0 || cid[0] != cidVersion {
+		return nil, errors.New("invalid CID " + s + ": not CIDv1")
+	}
+	return cid, nil
+}
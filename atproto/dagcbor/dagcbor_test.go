@@ -0,0 +1,197 @@
+package dagcbor
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const emptyMapCID = "bafyreigbtj4x7ip5legnfznufuopl4sg4knzc2cof6duas4b3q2fy6swua"
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       interface{}
+		expectedHex string
+		expectedErr string
+	}{
+		{
+			name:        "Keys are ordered by length then bytes",
+			value:       map[string]interface{}{"bb": []interface{}{true, nil}, "c": -2, "a": 1},
+			expectedHex: "a361610161632162626282f5f6",
+		},
+		{
+			name:        "Long string header",
+			value:       strings.Repeat("x", 256),
+			expectedHex: "790100" + hex.EncodeToString([]byte(strings.Repeat("x", 256))),
+		},
+		{
+			name:        "Large integer",
+			value:       int64(1) << 40,
+			expectedHex: "1b0000010000000000",
+		},
+		{
+			name:        "Bytes",
+			value:       map[string]interface{}{"$bytes": "AQID"},
+			expectedHex: "43010203",
+		},
+		{
+			name:        "CID link",
+			value:       map[string]interface{}{"$link": emptyMapCID},
+			expectedHex: "d82a58250001711220c19a797fa1fd590cd2e5b42d1cf5f246e29b91684e2f87404b81dc345c7a56a0",
+		},
+		{
+			name:        "Floats are not allowed",
+			value:       map[string]interface{}{"score": 1.5},
+			expectedErr: "score: number 1.5 is not an integer",
+		},
+		{
+			name:        "Invalid CID link",
+			value:       map[string]interface{}{"$link": "Qmnotbase32"},
+			expectedErr: "only base32 CIDv1 is supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Marshal(tt.value)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedHex, hex.EncodeToString(data))
+		})
+	}
+}
+
+func TestCID(t *testing.T) {
+	cid, err := CID(map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, emptyMapCID, cid)
+
+	for _, g := range goldenPosts() {
+		t.Run(g.name, func(t *testing.T) {
+			cid, err := CID(g.post)
+			require.NoError(t, err)
+			assert.Equal(t, g.cid, cid)
+		})
+	}
+}
+
+// TestFixtures checks the encoding against records encoded elsewhere:
+// data-model-fixtures.json is the data model suite from the atproto interop
+// tests, and feedpost-record.json is a post as a PDS stored it.
+func TestFixtures(t *testing.T) {
+	for _, file := range []string{"testdata/data-model-fixtures.json", "testdata/feedpost-record.json"} {
+		raw, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		var fixtures []struct {
+			JSON       json.RawMessage `json:"json"`
+			CBORBase64 string          `json:"cbor_base64"`
+			CID        string          `json:"cid"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &fixtures))
+		require.NotEmpty(t, fixtures)
+
+		for i, f := range fixtures {
+			t.Run(fmt.Sprintf("%s/%d", file, i), func(t *testing.T) {
+				expected, err := base64.RawStdEncoding.DecodeString(f.CBORBase64)
+				require.NoError(t, err)
+
+				data, err := Marshal(f.JSON)
+				require.NoError(t, err)
+				assert.Equal(t, hex.EncodeToString(expected), hex.EncodeToString(data))
+
+				cid, err := CID(f.JSON)
+				require.NoError(t, err)
+				assert.Equal(t, f.CID, cid)
+			})
+		}
+	}
+}
+
+// The golden CIDs were computed with the reference encoder in
+// github.com/bluesky-social/indigo/atproto/data.
+type goldenPost struct {
+	name string
+	post models.ShareFrameFeedPost
+	cid  string
+}
+
+func goldenPosts() []goldenPost {
+	image := models.Blob{
+		Type:     "blob",
+		Ref:      models.BlobLink{Link: "bafkreiccldh766hwcnuxnf2wh6jgzepf2nlu2lvcllt63eww5p6chi4ity"},
+		MimeType: "image/jpeg",
+		Size:     482133,
+	}
+	video := models.Blob{
+		Type:     "blob",
+		Ref:      models.BlobLink{Link: "bafkreia6tb3p4ggkumameimo6cp3l25r552lqb3pi4zzpifybm7bd34uqu"},
+		MimeType: "video/mp4",
+		Size:     10485760,
+	}
+	text := "Sunset with @alice.shareframe.social 🌅 #goldenhour https://shareframe.social/p/1"
+
+	return []goldenPost{
+		{
+			name: "Text only",
+			post: models.ShareFrameFeedPost{NSID: models.FeedPostNSID, Text: "Hello", CreatedAt: "2024-05-01T12:00:00Z"},
+			cid:  "bafyreiho3bn7lyrioghxy3bkwql2s2ia5rzapm3ihjrztxufgw6qitguaa",
+		},
+		{
+			name: "Facets, image blob and reply",
+			post: models.ShareFrameFeedPost{
+				NSID:      models.FeedPostNSID,
+				Text:      text,
+				CreatedAt: "2024-05-01T12:00:00.000Z",
+				Facets: []models.Facet{
+					{
+						Index:    models.FacetByteSlice{ByteStart: 12, ByteEnd: 36},
+						Features: []models.FacetFeature{{Mention: &models.FacetMention{DID: "did:plc:alice"}}},
+					},
+					{
+						Index:    models.FacetByteSlice{ByteStart: 42, ByteEnd: 53},
+						Features: []models.FacetFeature{{Tag: &models.FacetTag{Tag: "goldenhour"}}},
+					},
+					{
+						Index:    models.FacetByteSlice{ByteStart: 54, ByteEnd: 83},
+						Features: []models.FacetFeature{{Link: &models.FacetLink{URI: "https://shareframe.social/p/1"}}},
+					},
+				},
+				Images:        []models.Blob{image},
+				ImageMetadata: &models.ImageMetadata{Alt: "A sunset over the sea", Width: 4032, Height: 3024},
+				Reply: &models.ReplyRef{
+					Root:   models.StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/3jzfcijpj2z2a", CID: "bafyreihldkhcwijkde7gx4rpkkuw7pl6lbyu5gieunyc7ihactn5bkd2nm"},
+					Parent: models.StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/3jzfcijpj2z2b", CID: "bafyreiclp443lavogvhj3d2ob2cxbfuscni2k5jk7bebjzg7khl3esabwq"},
+				},
+				Tags:     []string{"sunset", "sea"},
+				Language: "en",
+			},
+			cid: "bafyreiftxfqyw6rastcr5grbilfogxf3k6j2ncfdfebg6xsvbacm26nit4",
+		},
+		{
+			name: "Story with video blob and quote",
+			post: models.ShareFrameFeedPost{
+				NSID:          models.FeedPostNSID,
+				CreatedAt:     "2024-05-01T12:00:00Z",
+				ExpiresAt:     "2024-05-02T12:00:00Z",
+				IsStory:       true,
+				Videos:        []models.Blob{video},
+				VideoMetadata: &models.VideoMetadata{DurationMs: 15000, Width: 1080, Height: 1920},
+				QuoteOf:       &models.StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/3jzfcijpj2z2a", CID: "bafyreihldkhcwijkde7gx4rpkkuw7pl6lbyu5gieunyc7ihactn5bkd2nm"},
+			},
+			cid: "bafyreib2gubuvzdzlpdogkpzfnjrscyfetpmxmfgzw7u5qhue4hrg6q2vq",
+		},
+	}
+}
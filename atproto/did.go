@@ -0,0 +1,213 @@
+package atproto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultPLCDirectoryURL = "https://plc.directory"
+	DefaultDIDCacheTTL     = 10 * time.Minute
+
+	pdsServiceID   = "#atproto_pds"
+	pdsServiceType = "AtprotoPersonalDataServer"
+)
+
+var (
+	ErrUnsupportedDID = errors.New("unsupported DID method")
+	ErrPDSNotFound    = errors.New("DID document has no atproto PDS endpoint")
+	// ErrInvalidPDSEndpoint is returned for an endpoint that is not an https
+	// URL.
+	ErrInvalidPDSEndpoint = errors.New("DID document has an invalid atproto PDS endpoint")
+)
+
+type PDSResolver interface {
+	ResolvePDS(ctx context.Context, did string) (string, error)
+}
+
+type DIDDocument struct {
+	ID          string       `json:"id"`
+	AlsoKnownAs []string     `json:"alsoKnownAs,omitempty"`
+	Service     []DIDService `json:"service,omitempty"`
+}
+
+type DIDService struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+func (d *DIDDocument) PDSEndpoint() (string, error) {
+	for _, svc := range d.Service {
+		if svc.ID != pdsServiceID && svc.ID != d.ID+pdsServiceID {
+			continue
+		}
+		if svc.Type != pdsServiceType {
+			continue
+		}
+		// Anyone can publish a DID document, so the endpoint is not echoed
+		// back in the error.
+		u, err := url.Parse(svc.ServiceEndpoint)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			logrus.WithField("endpoint", svc.ServiceEndpoint).Warn("Rejected PDS endpoint")
+			return "", ErrInvalidPDSEndpoint
+		}
+		return strings.TrimRight(svc.ServiceEndpoint, "/"), nil
+	}
+	return "", ErrPDSNotFound
+}
+
+type DIDResolverConfig struct {
+	PLCDirectoryURL string
+	CacheTTL        time.Duration
+}
+
+type cachedEndpoint struct {
+	endpoint  string
+	expiresAt time.Time
+}
+
+type DIDResolver struct {
+	client *http.Client
+	plcURL string
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedEndpoint
+}
+
+// NewDIDResolver fetches documents with client. When it is nil, a client
+// that refuses private and reserved addresses is used, since a did:web
+// names whatever host its creator likes.
+func NewDIDResolver(client *http.Client, cfg DIDResolverConfig) *DIDResolver {
+	if client == nil {
+		client = newPublicClient()
+	}
+	if cfg.PLCDirectoryURL == "" {
+		cfg.PLCDirectoryURL = DefaultPLCDirectoryURL
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultDIDCacheTTL
+	}
+	return &DIDResolver{
+		client: client,
+		plcURL: strings.TrimRight(cfg.PLCDirectoryURL, "/"),
+		ttl:    cfg.CacheTTL,
+		now:    time.Now,
+		cache:  make(map[string]cachedEndpoint),
+	}
+}
+
+func (r *DIDResolver) ResolvePDS(ctx context.Context, did string) (string, error) {
+	r.mu.Lock()
+	entry, ok := r.cache[did]
+	r.mu.Unlock()
+	if ok && r.now().Before(entry.expiresAt) {
+		return entry.endpoint, nil
+	}
+
+	doc, err := r.ResolveDID(ctx, did)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint, err := doc.PDSEndpoint()
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Failed to find PDS endpoint")
+		return "", fmt.Errorf("failed to resolve PDS for %s: %w", did, err)
+	}
+
+	r.mu.Lock()
+	r.cache[did] = cachedEndpoint{endpoint: endpoint, expiresAt: r.now().Add(r.ttl)}
+	r.mu.Unlock()
+
+	return endpoint, nil
+}
+
+func (r *DIDResolver) ResolveDID(ctx context.Context, did string) (*DIDDocument, error) {
+	docURL, err := r.documentURL(did)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create DID document request")
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/did+ld+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("DID document request failed")
+		return nil, fmt.Errorf("failed to fetch DID document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DID document: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		logrus.WithFields(logrus.Fields{
+			"DID":    did,
+			"status": resp.StatusCode,
+		}).Error("Failed to fetch DID document")
+		return nil, fmt.Errorf("failed to fetch DID document for %s: status %d", did, resp.StatusCode)
+	}
+
+	var doc DIDDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse DID document: %w", err)
+	}
+
+	if doc.ID != did {
+		logrus.WithFields(logrus.Fields{"DID": did, "id": doc.ID}).Error("DID document is for another DID")
+		return nil, fmt.Errorf("DID document id does not match %s", did)
+	}
+
+	return &doc, nil
+}
+
+func (r *DIDResolver) documentURL(did string) (string, error) {
+	switch {
+	case strings.HasPrefix(did, "did:plc:"):
+		return r.plcURL + "/" + did, nil
+	case strings.HasPrefix(did, "did:web:"):
+		host, err := url.PathUnescape(strings.TrimPrefix(did, "did:web:"))
+		if err != nil || host == "" || !isHostPort(host) {
+			return "", fmt.Errorf("invalid did:web identifier %q", did)
+		}
+		return "https://" + host + "/.well-known/did.json", nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDID, did)
+	}
+}
+
+// newPublicClient dials through media.CheckDial, so requests to hosts taken
+// from DID documents cannot reach loopback, link-local or VPC addresses.
+func newPublicClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: media.CheckDial}
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport, Timeout: 10 * time.Second}
+}
+
+func isHostPort(host string) bool {
+	u, err := url.Parse("https://" + host)
+	return err == nil && u.Host == host && u.Path == ""
+}
@@ -0,0 +1,206 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func didDocumentJSON(did, endpoint string) string {
+	return `{
+		"id": "` + did + `",
+		"alsoKnownAs": ["at://alice.example.com"],
+		"service": [{
+			"id": "#atproto_pds",
+			"type": "AtprotoPersonalDataServer",
+			"serviceEndpoint": "` + endpoint + `"
+		}]
+	}`
+}
+
+func TestResolvePDSWithPLC(t *testing.T) {
+	const did = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+
+	requests := 0
+	plc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/"+did, r.URL.Path)
+		w.Write([]byte(didDocumentJSON(did, "https://pds.example.com/")))
+	}))
+	defer plc.Close()
+
+	resolver := NewDIDResolver(plc.Client(), DIDResolverConfig{PLCDirectoryURL: plc.URL})
+	now := time.Now()
+	resolver.now = func() time.Time { return now }
+
+	endpoint, err := resolver.ResolvePDS(context.Background(), did)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://pds.example.com", endpoint)
+
+	_, err = resolver.ResolvePDS(context.Background(), did)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests, "second lookup should be served from cache")
+
+	now = now.Add(DefaultDIDCacheTTL + time.Second)
+	_, err = resolver.ResolvePDS(context.Background(), did)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests, "expired cache entry should be refreshed")
+}
+
+func TestResolvePDSWithDIDWeb(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	did := "did:web:" + strings.Replace(host, ":", "%3A", 1)
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/.well-known/did.json", r.URL.Path)
+		w.Write([]byte(didDocumentJSON(did, "https://pds.example.org")))
+	})
+
+	resolver := NewDIDResolver(server.Client(), DIDResolverConfig{})
+
+	endpoint, err := resolver.ResolvePDS(context.Background(), did)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://pds.example.org", endpoint)
+}
+
+func TestResolvePDSErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		did       string
+		document  string
+		status    int
+		expectErr error
+	}{
+		{
+			name:      "Unsupported DID method",
+			did:       "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK",
+			expectErr: ErrUnsupportedDID,
+		},
+		{
+			name:      "Missing PDS service",
+			did:       "did:plc:abc",
+			document:  `{"id":"did:plc:abc","service":[]}`,
+			status:    http.StatusOK,
+			expectErr: ErrPDSNotFound,
+		},
+		{
+			name:     "Document for a different DID",
+			did:      "did:plc:abc",
+			document: didDocumentJSON("did:plc:other", "https://pds.example.com"),
+			status:   http.StatusOK,
+		},
+		{
+			name:   "DID not found",
+			did:    "did:plc:abc",
+			status: http.StatusNotFound,
+		},
+		{
+			name:      "Invalid service endpoint",
+			did:       "did:plc:abc",
+			document:  didDocumentJSON("did:plc:abc", "ftp://pds.example.com"),
+			status:    http.StatusOK,
+			expectErr: ErrInvalidPDSEndpoint,
+		},
+		{
+			name:      "Plain http service endpoint",
+			did:       "did:plc:abc",
+			document:  didDocumentJSON("did:plc:abc", "http://pds.example.com"),
+			status:    http.StatusOK,
+			expectErr: ErrInvalidPDSEndpoint,
+		},
+		{
+			name: "did:web with path",
+			did:  "did:web:example.com:users:alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.document))
+			}))
+			defer plc.Close()
+
+			resolver := NewDIDResolver(plc.Client(), DIDResolverConfig{PLCDirectoryURL: plc.URL})
+
+			endpoint, err := resolver.ResolvePDS(context.Background(), tt.did)
+			assert.Error(t, err)
+			assert.Empty(t, endpoint)
+			if tt.expectErr != nil {
+				assert.True(t, errors.Is(err, tt.expectErr))
+			}
+		})
+	}
+}
+
+func TestPostToFeedUsesResolvedPDS(t *testing.T) {
+	const did = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+
+	pds := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/xrpc/com.atproto.repo.createRecord", r.URL.Path)
+		w.Write([]byte(`{"uri":"at://` + did + `/social.shareframe.feed.post/xyz","cid":"bafyre123456"}`))
+	}))
+	defer pds.Close()
+
+	plc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(didDocumentJSON(did, pds.URL)))
+	}))
+	defer plc.Close()
+
+	service := NewATProtoService(pds.Client(), Config{
+		BaseURL:  "http://127.0.0.1:1",
+		Resolver: NewDIDResolver(plc.Client(), DIDResolverConfig{PLCDirectoryURL: plc.URL}),
+	})
+
+	resp, err := service.PostToFeed(context.Background(), models.ShareFrameFeedPost{
+		NSID:      "social.shareframe.feed.post",
+		Text:      "Hello from a third-party PDS",
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}, "valid_token", did, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "at://"+did+"/social.shareframe.feed.post/xyz", resp.URI)
+}
+
+func TestDefaultClientsRefusePrivateAddresses(t *testing.T) {
+	const did = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+
+	pds := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	}))
+	defer pds.Close()
+
+	plc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(didDocumentJSON(did, pds.URL)))
+	}))
+	defer plc.Close()
+
+	t.Run("DID documents", func(t *testing.T) {
+		resolver := NewDIDResolver(nil, DIDResolverConfig{PLCDirectoryURL: plc.URL})
+
+		_, err := resolver.ResolvePDS(context.Background(), did)
+		assert.ErrorIs(t, err, media.ErrBlockedAddress)
+	})
+
+	t.Run("Resolved PDS", func(t *testing.T) {
+		service := NewATProtoService(nil, Config{
+			Resolver: NewDIDResolver(plc.Client(), DIDResolverConfig{PLCDirectoryURL: plc.URL}),
+			Retry:    RetryPolicy{MaxAttempts: 1},
+		})
+
+		_, err := service.PostToFeed(context.Background(), models.ShareFrameFeedPost{Text: "Hello"}, "valid_token", did, "")
+		assert.ErrorIs(t, err, media.ErrBlockedAddress)
+	})
+}
@@ -0,0 +1,118 @@
+package atproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxLoggedBody caps how much of a response body that is not an XRPC error
+// is kept for the logs.
+const maxLoggedBody = 1 << 10
+
+var (
+	ErrInvalidSwap    = errors.New("record was modified concurrently")
+	ErrRecordNotFound = errors.New("record not found")
+	ErrExpiredToken   = errors.New("access token has expired")
+	ErrInvalidToken   = errors.New("access token is invalid")
+	ErrRateLimited    = errors.New("rate limit exceeded")
+	ErrCanceled       = errors.New("request canceled")
+)
+
+// errorNamePattern matches the error names XRPC defines. Anything else a PDS
+// sends is dropped, since the error text can reach the client.
+var errorNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]{0,63}$`)
+
+// XRPCError is a failed XRPC response. Message is whatever the PDS sent, so
+// it is logged but left out of Error: a PDS named in a DID document can send
+// anything, and the error text is returned to the client.
+type XRPCError struct {
+	NSID       string
+	StatusCode int
+	ErrorName  string
+	Message    string
+	Headers    http.Header
+}
+
+func (e *XRPCError) Error() string {
+	name := e.ErrorName
+	if name == "" {
+		name = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s failed with status %d: %s", e.NSID, e.StatusCode, name)
+}
+
+func (e *XRPCError) Is(target error) bool {
+	switch target {
+	case ErrInvalidSwap:
+		return e.ErrorName == "InvalidSwap"
+	case ErrRecordNotFound:
+		return e.ErrorName == "RecordNotFound"
+	case ErrExpiredToken:
+		return e.ErrorName == "ExpiredToken"
+	case ErrInvalidToken:
+		return e.ErrorName == "InvalidToken"
+	case ErrRateLimited:
+		return e.IsRateLimited()
+	}
+	return false
+}
+
+func (e *XRPCError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.ErrorName == "RateLimitExceeded"
+}
+
+func (e *XRPCError) IsAuthError() bool {
+	switch e.ErrorName {
+	case "AuthenticationRequired", "InvalidToken", "ExpiredToken", "AuthMissing":
+		return true
+	}
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *XRPCError) IsServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+func canceledError(nsid string, err error) error {
+	logrus.WithError(err).WithField("nsid", nsid).Warn("XRPC request abandoned")
+	return fmt.Errorf("%s: %w: %w", nsid, ErrCanceled, err)
+}
+
+func responseError(nsid string, attempt int, resp *http.Response, body []byte) error {
+	xrpcErr := &XRPCError{
+		NSID:       nsid,
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+	}
+
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if errorNamePattern.MatchString(payload.Error) {
+			xrpcErr.ErrorName = payload.Error
+		}
+		xrpcErr.Message = payload.Message
+	} else {
+		if len(body) > maxLoggedBody {
+			body = body[:maxLoggedBody]
+		}
+		xrpcErr.Message = strings.TrimSpace(string(body))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"nsid":      nsid,
+		"status":    resp.StatusCode,
+		"attempt":   attempt,
+		"errorName": xrpcErr.ErrorName,
+		"message":   xrpcErr.Message,
+	}).Error("XRPC request failed")
+	return xrpcErr
+}
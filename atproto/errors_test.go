@@ -0,0 +1,106 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXRPCErrorParsing(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		expected    XRPCError
+		is          []error
+		isNot       []error
+		rateLimited bool
+		authError   bool
+		serverError bool
+	}{
+		{
+			name:      "Invalid token",
+			status:    http.StatusUnauthorized,
+			body:      `{"error":"InvalidToken","message":"Token could not be verified"}`,
+			expected:  XRPCError{StatusCode: http.StatusUnauthorized, ErrorName: "InvalidToken", Message: "Token could not be verified"},
+			is:        []error{ErrInvalidToken},
+			isNot:     []error{ErrExpiredToken, ErrRateLimited},
+			authError: true,
+		},
+		{
+			name:        "Rate limited",
+			status:      http.StatusTooManyRequests,
+			body:        `{"error":"RateLimitExceeded","message":"Rate Limit Exceeded"}`,
+			expected:    XRPCError{StatusCode: http.StatusTooManyRequests, ErrorName: "RateLimitExceeded", Message: "Rate Limit Exceeded"},
+			is:          []error{ErrRateLimited},
+			rateLimited: true,
+		},
+		{
+			name:     "Invalid request",
+			status:   http.StatusBadRequest,
+			body:     `{"error":"InvalidRequest","message":"Input/record must be an object"}`,
+			expected: XRPCError{StatusCode: http.StatusBadRequest, ErrorName: "InvalidRequest", Message: "Input/record must be an object"},
+			isNot:    []error{ErrInvalidSwap, ErrRecordNotFound},
+		},
+		{
+			name:     "Error name that is not a token",
+			status:   http.StatusBadRequest,
+			body:     `{"error":"<script>alert(1)</script>","message":"hi"}`,
+			expected: XRPCError{StatusCode: http.StatusBadRequest, Message: "hi"},
+		},
+		{
+			name:        "Non-JSON gateway error",
+			status:      http.StatusBadGateway,
+			body:        "<html>Bad Gateway</html>\n",
+			expected:    XRPCError{StatusCode: http.StatusBadGateway, Message: "<html>Bad Gateway</html>"},
+			serverError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-123")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer pds.Close()
+
+			service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+			err := service.DeletePost(context.Background(), "valid_token", "did:plc:alice", "3jzfcijpj2z2a")
+
+			var xrpcErr *XRPCError
+			if !assert.True(t, errors.As(err, &xrpcErr)) {
+				return
+			}
+			assert.Equal(t, "com.atproto.repo.deleteRecord", xrpcErr.NSID)
+			assert.Equal(t, tt.expected.StatusCode, xrpcErr.StatusCode)
+			assert.Equal(t, tt.expected.ErrorName, xrpcErr.ErrorName)
+			assert.Equal(t, tt.expected.Message, xrpcErr.Message)
+			assert.Equal(t, "req-123", xrpcErr.Headers.Get("X-Request-Id"))
+			assert.Equal(t, tt.rateLimited, xrpcErr.IsRateLimited())
+			assert.Equal(t, tt.authError, xrpcErr.IsAuthError())
+			assert.Equal(t, tt.serverError, xrpcErr.IsServerError())
+
+			for _, target := range tt.is {
+				assert.True(t, errors.Is(err, target), "expected errors.Is(%v)", target)
+			}
+			for _, target := range tt.isNot {
+				assert.False(t, errors.Is(err, target), "unexpected errors.Is(%v)", target)
+			}
+		})
+	}
+}
+
+func TestXRPCErrorMessage(t *testing.T) {
+	err := &XRPCError{NSID: "com.atproto.repo.createRecord", StatusCode: 400, ErrorName: "InvalidRequest", Message: "bad record"}
+	assert.Equal(t, "com.atproto.repo.createRecord failed with status 400: InvalidRequest", err.Error(), "the PDS's message is not repeated")
+
+	err = &XRPCError{NSID: "com.atproto.repo.createRecord", StatusCode: 503}
+	assert.Equal(t, "com.atproto.repo.createRecord failed with status 503: Service Unavailable", err.Error())
+}
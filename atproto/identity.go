@@ -0,0 +1,29 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/ShareFrame/posting-service/atproto/syntax"
+	"github.com/ShareFrame/posting-service/models"
+)
+
+func (s *ATProtoService) ResolveHandle(ctx context.Context, handle string) (string, error) {
+	var resolved models.ResolveHandleResponse
+	err := s.do(ctx, xrpcRequest{
+		method: http.MethodGet,
+		nsid:   "com.atproto.identity.resolveHandle",
+		query:  url.Values{"handle": {handle}},
+	}, &resolved)
+	if err != nil {
+		return "", err
+	}
+
+	if !syntax.ValidDID(resolved.DID) {
+		return "", errors.New("resolveHandle returned an invalid DID")
+	}
+
+	return resolved.DID, nil
+}
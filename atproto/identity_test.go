@@ -0,0 +1,43 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveHandle(t *testing.T) {
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/xrpc/com.atproto.identity.resolveHandle", r.URL.Path)
+
+		switch r.URL.Query().Get("handle") {
+		case "alice.shareframe.social":
+			w.Write([]byte(`{"did":"did:plc:alice"}`))
+		case "broken.shareframe.social":
+			w.Write([]byte(`{"did":"not-a-did"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"InvalidRequest","message":"Unable to resolve handle"}`))
+		}
+	}))
+	defer pds.Close()
+
+	service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+	did, err := service.ResolveHandle(context.Background(), "alice.shareframe.social")
+	assert.NoError(t, err)
+	assert.Equal(t, "did:plc:alice", did)
+
+	did, err = service.ResolveHandle(context.Background(), "broken.shareframe.social")
+	assert.Error(t, err)
+	assert.Empty(t, did)
+
+	did, err = service.ResolveHandle(context.Background(), "nobody.shareframe.social")
+	var xrpcErr *XRPCError
+	assert.True(t, errors.As(err, &xrpcErr))
+	assert.Empty(t, did)
+}
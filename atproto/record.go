@@ -0,0 +1,119 @@
+package atproto
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *ATProtoService) DeletePost(ctx context.Context, authToken, did, rkey string) error {
+	payload, err := json.Marshal(models.DeleteRecordRequest{
+		Repo:       did,
+		Collection: models.FeedPostNSID,
+		RKey:       rkey,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal JSON payload")
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	return s.do(ctx, xrpcRequest{
+		method:      http.MethodPost,
+		nsid:        "com.atproto.repo.deleteRecord",
+		did:         did,
+		authToken:   authToken,
+		contentType: "application/json",
+		body:        payload,
+		idempotent:  true,
+	}, nil)
+}
+
+func (s *ATProtoService) GetRecord(ctx context.Context, authToken, did, rkey string) (*models.GetRecordResponse, error) {
+	var record models.GetRecordResponse
+	err := s.do(ctx, xrpcRequest{
+		method:    http.MethodGet,
+		nsid:      "com.atproto.repo.getRecord",
+		did:       did,
+		authToken: authToken,
+		query: url.Values{
+			"repo":       {did},
+			"collection": {models.FeedPostNSID},
+			"rkey":       {rkey},
+		},
+	}, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (s *ATProtoService) PutRecord(ctx context.Context, authToken, did, rkey string, post models.ShareFrameFeedPost, swapRecord string) (*models.PostResponse, error) {
+	payload, err := json.Marshal(models.PutRecordRequest{
+		Repo:       did,
+		Collection: models.FeedPostNSID,
+		RKey:       rkey,
+		Record:     post,
+		SwapRecord: swapRecord,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal JSON payload")
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	var postResponse models.PostResponse
+	err = s.do(ctx, xrpcRequest{
+		method:      http.MethodPost,
+		nsid:        "com.atproto.repo.putRecord",
+		did:         did,
+		authToken:   authToken,
+		contentType: "application/json",
+		body:        payload,
+		idempotent:  swapRecord != "",
+	}, &postResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postResponse, nil
+}
+
+// ApplyWrites commits every write in req as a single repo commit, so either
+// all of them land or none do.
+func (s *ATProtoService) ApplyWrites(ctx context.Context, authToken string, req models.ApplyWritesRequest) (*models.ApplyWritesResponse, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal JSON payload")
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	var resp models.ApplyWritesResponse
+	err = s.do(ctx, xrpcRequest{
+		method:      http.MethodPost,
+		nsid:        "com.atproto.repo.applyWrites",
+		did:         req.Repo,
+		authToken:   authToken,
+		contentType: "application/json",
+		body:        payload,
+		idempotent:  hasExplicitRKeys(req.Writes),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func hasExplicitRKeys(writes []models.ApplyWritesCreate) bool {
+	for _, w := range writes {
+		if w.RKey == "" {
+			return false
+		}
+	}
+	return len(writes) > 0
+}
@@ -0,0 +1,193 @@
+package atproto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeletePost(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		response  string
+		expectErr bool
+	}{
+		{
+			name:     "Record deleted",
+			status:   http.StatusOK,
+			response: `{"commit":{"cid":"commit123","rev":"rev123"}}`,
+		},
+		{
+			name:      "PDS rejects the delete",
+			status:    http.StatusBadRequest,
+			response:  `{"error":"InvalidRequest","message":"Could not locate record"}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got models.DeleteRecordRequest
+			pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/xrpc/com.atproto.repo.deleteRecord", r.URL.Path)
+				assert.Equal(t, "Bearer valid_token", r.Header.Get("Authorization"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer pds.Close()
+
+			service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+			err := service.DeletePost(context.Background(), "valid_token", "did:plc:alice", "3jzfcijpj2z2a")
+
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, models.DeleteRecordRequest{
+				Repo:       "did:plc:alice",
+				Collection: "social.shareframe.feed.post",
+				RKey:       "3jzfcijpj2z2a",
+			}, got)
+		})
+	}
+}
+
+func TestGetRecord(t *testing.T) {
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/xrpc/com.atproto.repo.getRecord", r.URL.Path)
+		assert.Equal(t, "did:plc:alice", r.URL.Query().Get("repo"))
+		assert.Equal(t, "social.shareframe.feed.post", r.URL.Query().Get("collection"))
+
+		switch r.URL.Query().Get("rkey") {
+		case "3jzfcijpj2z2a":
+			w.Write([]byte(`{
+				"uri": "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a",
+				"cid": "bafyreiold",
+				"value": {"nsid": "social.shareframe.feed.post", "text": "Hello"}
+			}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"RecordNotFound","message":"Could not locate record"}`))
+		}
+	}))
+	defer pds.Close()
+
+	service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+	record, err := service.GetRecord(context.Background(), "valid_token", "did:plc:alice", "3jzfcijpj2z2a")
+	assert.NoError(t, err)
+	assert.Equal(t, "bafyreiold", record.CID)
+	assert.Equal(t, "Hello", record.Value.Text)
+
+	record, err = service.GetRecord(context.Background(), "valid_token", "did:plc:alice", "missing")
+	assert.Nil(t, record)
+	assert.True(t, errors.Is(err, ErrRecordNotFound))
+}
+
+func TestPutRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		swapRecord string
+		expectErr  error
+	}{
+		{name: "Swap matches current CID", swapRecord: "bafyreiold"},
+		{name: "Swap on stale CID", swapRecord: "bafyreistale", expectErr: ErrInvalidSwap},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/xrpc/com.atproto.repo.putRecord", r.URL.Path)
+
+				var got models.PutRecordRequest
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				assert.Equal(t, "3jzfcijpj2z2a", got.RKey)
+				assert.Equal(t, "Edited", got.Record.Text)
+
+				if got.SwapRecord != "bafyreiold" {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":"InvalidSwap","message":"Record was at bafyreiold"}`))
+					return
+				}
+				w.Write([]byte(`{"uri":"at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a","cid":"bafyreinew"}`))
+			}))
+			defer pds.Close()
+
+			service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+			resp, err := service.PutRecord(context.Background(), "valid_token", "did:plc:alice", "3jzfcijpj2z2a",
+				models.ShareFrameFeedPost{NSID: "social.shareframe.feed.post", Text: "Edited"}, tt.swapRecord)
+
+			if tt.expectErr != nil {
+				assert.True(t, errors.Is(err, tt.expectErr))
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "bafyreinew", resp.CID)
+			}
+		})
+	}
+}
+
+func TestApplyWrites(t *testing.T) {
+	var got map[string]interface{}
+	pds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/xrpc/com.atproto.repo.applyWrites", r.URL.Path)
+		assert.Equal(t, "Bearer valid_token", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		w.Write([]byte(`{
+			"commit": {"cid": "commit123", "rev": "rev123"},
+			"results": [{
+				"$type": "com.atproto.repo.applyWrites#createResult",
+				"uri": "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a",
+				"cid": "bafyrei123",
+				"validationStatus": "valid"
+			}]
+		}`))
+	}))
+	defer pds.Close()
+
+	service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+
+	resp, err := service.ApplyWrites(context.Background(), "valid_token", models.ApplyWritesRequest{
+		Repo: "did:plc:alice",
+		Writes: []models.ApplyWritesCreate{{
+			Type:       models.ApplyWritesCreateType,
+			Collection: models.FeedPostNSID,
+			RKey:       "3jzfcijpj2z2a",
+			Value:      models.ShareFrameFeedPost{NSID: models.FeedPostNSID, Text: "Hello", CreatedAt: "2024-05-01T12:00:00Z"},
+		}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "commit123", resp.Commit.CID)
+	assert.Equal(t, []models.ApplyWritesResult{{
+		Type:             "com.atproto.repo.applyWrites#createResult",
+		URI:              "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a",
+		CID:              "bafyrei123",
+		ValidationStatus: "valid",
+	}}, resp.Results)
+
+	assert.Equal(t, "did:plc:alice", got["repo"])
+	writes := got["writes"].([]interface{})
+	assert.Len(t, writes, 1)
+	write := writes[0].(map[string]interface{})
+	assert.Equal(t, "com.atproto.repo.applyWrites#create", write["$type"])
+	assert.Equal(t, "3jzfcijpj2z2a", write["rkey"])
+	assert.Equal(t, "social.shareframe.feed.post", write["value"].(map[string]interface{})["$type"])
+}
@@ -0,0 +1,134 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	return p
+}
+
+func (s *ATProtoService) retryDelay(ctx context.Context, r xrpcRequest, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err == nil || attempt >= s.retry.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+	if !isRetryable(err, r.idempotent || r.method == http.MethodGet) {
+		return 0, false
+	}
+
+	delay := s.backoff(attempt)
+	if resp != nil {
+		if wait, ok := s.rateLimitWait(resp.Header); ok {
+			if wait > s.retry.MaxDelay {
+				logrus.WithField("wait", wait.String()).Warn("Rate limit reset is beyond the retry window")
+				return 0, false
+			}
+			if wait > delay {
+				delay = wait
+			}
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && s.now().Add(delay).After(deadline) {
+		logrus.WithField("attempt", attempt).Warn("Not retrying: context deadline would pass before the next attempt")
+		return 0, false
+	}
+
+	return delay, true
+}
+
+func (s *ATProtoService) backoff(attempt int) time.Duration {
+	ceiling := s.retry.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > s.retry.MaxDelay {
+		ceiling = s.retry.MaxDelay
+	}
+	return ceiling/2 + time.Duration(s.jitter()*float64(ceiling/2))
+}
+
+func (s *ATProtoService) rateLimitWait(header http.Header) (time.Duration, bool) {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return nonNegative(at.Sub(s.now())), true
+		}
+	}
+
+	if value := header.Get("RateLimit-Reset"); value != "" {
+		if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return nonNegative(time.Unix(epoch, 0).Sub(s.now())), true
+		}
+	}
+
+	return 0, false
+}
+
+// isRetryable reports whether err is worth another attempt. A rate-limited
+// request was never processed, so it can always be resent; after a server
+// error or a dropped connection the request may already have taken effect,
+// so only idempotent requests are.
+func isRetryable(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var xrpcErr *XRPCError
+	if errors.As(err, &xrpcErr) && xrpcErr.IsRateLimited() {
+		return true
+	}
+	if !idempotent {
+		return false
+	}
+	if xrpcErr != nil {
+		return xrpcErr.IsServerError() && xrpcErr.StatusCode != http.StatusNotImplemented
+	}
+
+	return true
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
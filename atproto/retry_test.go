@@ -0,0 +1,281 @@
+package atproto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type scriptedResponse struct {
+	status int
+	header http.Header
+	body   string
+	err    error
+}
+
+type scriptedTransport struct {
+	responses []scriptedResponse
+	calls     int
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	step := s.responses[s.calls]
+	s.calls++
+	if step.err != nil {
+		return nil, step.err
+	}
+	header := step.header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: step.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(step.body)),
+	}, nil
+}
+
+func newScriptedService(transport *scriptedTransport, now time.Time, delays *[]time.Duration) *ATProtoService {
+	service := NewATProtoService(&http.Client{Transport: transport}, Config{
+		BaseURL: "https://pds.example.com",
+		Retry:   RetryPolicy{MaxAttempts: 4, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second},
+	})
+	service.jitter = func() float64 { return 1 }
+	service.now = func() time.Time { return now }
+	service.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+	return service
+}
+
+const createdResponse = `{"uri":"at://did:plc:alice/social.shareframe.feed.post/xyz","cid":"bafyre123456"}`
+
+func TestRetries(t *testing.T) {
+	now := time.Now().Truncate(time.Second).Add(time.Second)
+
+	tests := []struct {
+		name         string
+		responses    []scriptedResponse
+		ctx          func() (context.Context, context.CancelFunc)
+		expectErr    bool
+		expectCalls  int
+		expectDelays []time.Duration
+	}{
+		{
+			name: "Recovers from a transient 502",
+			responses: []scriptedResponse{
+				{status: http.StatusBadGateway, body: "Bad Gateway"},
+				{status: http.StatusOK, body: createdResponse},
+			},
+			expectCalls:  2,
+			expectDelays: []time.Duration{100 * time.Millisecond},
+		},
+		{
+			name: "Recovers from connection resets with exponential backoff",
+			responses: []scriptedResponse{
+				{err: errors.New("connection reset by peer")},
+				{err: errors.New("connection reset by peer")},
+				{status: http.StatusOK, body: createdResponse},
+			},
+			expectCalls:  3,
+			expectDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name: "Honors Retry-After seconds",
+			responses: []scriptedResponse{
+				{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"1"}}, body: `{"error":"RateLimitExceeded"}`},
+				{status: http.StatusOK, body: createdResponse},
+			},
+			expectCalls:  2,
+			expectDelays: []time.Duration{time.Second},
+		},
+		{
+			name: "Honors ratelimit-reset",
+			responses: []scriptedResponse{
+				{
+					status: http.StatusTooManyRequests,
+					header: http.Header{"Ratelimit-Reset": {strconv.FormatInt(now.Add(1500*time.Millisecond).Unix(), 10)}},
+					body:   `{"error":"RateLimitExceeded"}`,
+				},
+				{status: http.StatusOK, body: createdResponse},
+			},
+			expectCalls:  2,
+			expectDelays: []time.Duration{time.Second},
+		},
+		{
+			name: "Gives up when the rate limit resets beyond the retry window",
+			responses: []scriptedResponse{
+				{status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"3600"}}, body: `{"error":"RateLimitExceeded"}`},
+			},
+			expectErr:   true,
+			expectCalls: 1,
+		},
+		{
+			name: "Stops after max attempts",
+			responses: []scriptedResponse{
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusServiceUnavailable},
+			},
+			expectErr:    true,
+			expectCalls:  4,
+			expectDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond},
+		},
+		{
+			name: "Does not retry client errors",
+			responses: []scriptedResponse{
+				{status: http.StatusBadRequest, body: `{"error":"InvalidRequest","message":"bad record"}`},
+			},
+			expectErr:   true,
+			expectCalls: 1,
+		},
+		{
+			name: "Does not retry past the context deadline",
+			responses: []scriptedResponse{
+				{status: http.StatusBadGateway},
+			},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithDeadline(context.Background(), now.Add(50*time.Millisecond))
+			},
+			expectErr:   true,
+			expectCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			var delays []time.Duration
+			transport := &scriptedTransport{responses: tt.responses}
+			service := newScriptedService(transport, now, &delays)
+
+			var resp models.PostResponse
+			err := service.do(ctx, xrpcRequest{
+				method:      http.MethodPost,
+				nsid:        "com.atproto.repo.createRecord",
+				contentType: "application/json",
+				body:        []byte(`{}`),
+				idempotent:  true,
+			}, &resp)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "bafyre123456", resp.CID)
+			}
+			assert.Equal(t, tt.expectCalls, transport.calls)
+			assert.Equal(t, tt.expectDelays, delays)
+		})
+	}
+}
+
+func TestRetryOnlyIdempotentRequests(t *testing.T) {
+	post := models.ShareFrameFeedPost{NSID: models.FeedPostNSID, Text: "hi"}
+	create := func(rkey string) func(s *ATProtoService) error {
+		return func(s *ATProtoService) error {
+			_, err := s.PostToFeed(context.Background(), post, "token", "did:plc:alice", rkey)
+			return err
+		}
+	}
+	applyWrites := func(rkeys ...string) func(s *ATProtoService) error {
+		return func(s *ATProtoService) error {
+			req := models.ApplyWritesRequest{Repo: "did:plc:alice"}
+			for _, rkey := range rkeys {
+				req.Writes = append(req.Writes, models.ApplyWritesCreate{Type: models.ApplyWritesCreateType, Collection: models.FeedPostNSID, RKey: rkey, Value: post})
+			}
+			_, err := s.ApplyWrites(context.Background(), "token", req)
+			return err
+		}
+	}
+	put := func(swap string) func(s *ATProtoService) error {
+		return func(s *ATProtoService) error {
+			_, err := s.PutRecord(context.Background(), "token", "did:plc:alice", "xyz", post, swap)
+			return err
+		}
+	}
+
+	tests := []struct {
+		name        string
+		call        func(s *ATProtoService) error
+		failure     scriptedResponse
+		expectCalls int
+	}{
+		{"createRecord with an rkey", create("3jzfcijpj2z2a"), scriptedResponse{status: http.StatusBadGateway}, 2},
+		{"createRecord without an rkey", create(""), scriptedResponse{status: http.StatusBadGateway}, 1},
+		{"createRecord without an rkey after a dropped connection", create(""), scriptedResponse{err: errors.New("connection reset by peer")}, 1},
+		{"createRecord without an rkey when rate limited", create(""), scriptedResponse{status: http.StatusTooManyRequests, body: `{"error":"RateLimitExceeded"}`}, 2},
+		{"applyWrites with every rkey", applyWrites("3jzfcijpj2z2a", "3jzfcijpj2z2b"), scriptedResponse{status: http.StatusBadGateway}, 2},
+		{"applyWrites missing an rkey", applyWrites("3jzfcijpj2z2a", ""), scriptedResponse{status: http.StatusBadGateway}, 1},
+		{"putRecord with a swap", put("bafyreiold"), scriptedResponse{status: http.StatusBadGateway}, 2},
+		{"putRecord without a swap", put(""), scriptedResponse{status: http.StatusBadGateway}, 1},
+		{"deleteRecord", func(s *ATProtoService) error {
+			return s.DeletePost(context.Background(), "token", "did:plc:alice", "xyz")
+		}, scriptedResponse{status: http.StatusBadGateway}, 2},
+		{"refreshSession", func(s *ATProtoService) error {
+			_, err := s.RefreshSession(context.Background(), "did:plc:alice", "refresh")
+			return err
+		}, scriptedResponse{status: http.StatusBadGateway}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delays []time.Duration
+			transport := &scriptedTransport{responses: []scriptedResponse{tt.failure, {status: http.StatusOK, body: createdResponse}}}
+			service := newScriptedService(transport, time.Now(), &delays)
+
+			err := tt.call(service)
+
+			assert.Equal(t, tt.expectCalls, transport.calls)
+			assert.Equal(t, tt.expectCalls == 1, err != nil)
+		})
+	}
+}
+
+func TestRetryResendsRequestBody(t *testing.T) {
+	var bodies []string
+	service := NewATProtoService(&http.Client{Transport: &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+			status := http.StatusOK
+			if len(bodies) == 1 {
+				status = http.StatusInternalServerError
+			}
+			return &http.Response{
+				StatusCode: status,
+				Header:     make(http.Header),
+				Body:       io.NopCloser(strings.NewReader(createdResponse)),
+			}, nil
+		},
+	}}, Config{})
+	service.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	err := service.DeletePost(context.Background(), "valid_token", "did:plc:alice", "xyz")
+
+	assert.NoError(t, err)
+	if assert.Len(t, bodies, 2) {
+		assert.Equal(t, bodies[0], bodies[1])
+		assert.Contains(t, bodies[1], `"rkey":"xyz"`)
+	}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	assert.Equal(t, DefaultRetryPolicy, RetryPolicy{}.withDefaults())
+	assert.Equal(t, 1, RetryPolicy{MaxAttempts: 1}.withDefaults().MaxAttempts)
+}
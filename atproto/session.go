@@ -0,0 +1,138 @@
+package atproto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+type SessionClient interface {
+	CreateSession(ctx context.Context, identifier, password string) (*models.Session, error)
+	RefreshSession(ctx context.Context, did, refreshJwt string) (*models.Session, error)
+}
+
+func (s *ATProtoService) CreateSession(ctx context.Context, identifier, password string) (*models.Session, error) {
+	payload, err := json.Marshal(models.CreateSessionRequest{
+		Identifier: identifier,
+		Password:   password,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to marshal JSON payload")
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	did := ""
+	if strings.HasPrefix(identifier, "did:") {
+		did = identifier
+	}
+
+	var session models.Session
+	err = s.do(ctx, xrpcRequest{
+		method:      http.MethodPost,
+		nsid:        "com.atproto.server.createSession",
+		did:         did,
+		contentType: "application/json",
+		body:        payload,
+	}, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *ATProtoService) RefreshSession(ctx context.Context, did, refreshJwt string) (*models.Session, error) {
+	var session models.Session
+	err := s.do(ctx, xrpcRequest{
+		method:    http.MethodPost,
+		nsid:      "com.atproto.server.refreshSession",
+		did:       did,
+		authToken: refreshJwt,
+	}, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+type SessionManager struct {
+	client SessionClient
+
+	mu        sync.Mutex
+	session   models.Session
+	refreshed bool
+}
+
+func NewSessionManager(client SessionClient, session models.Session) *SessionManager {
+	return &SessionManager{client: client, session: session}
+}
+
+func (m *SessionManager) Login(ctx context.Context, identifier, password string) error {
+	session, err := m.client.CreateSession(ctx, identifier, password)
+	if err != nil {
+		logrus.WithError(err).WithField("identifier", identifier).Error("Failed to create session")
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	m.mu.Lock()
+	m.session = *session
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *SessionManager) Refresh(ctx context.Context) error {
+	current := m.Session()
+	if current.RefreshJwt == "" {
+		return errors.New("no refresh token available")
+	}
+
+	session, err := m.client.RefreshSession(ctx, current.DID, current.RefreshJwt)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", current.DID).Error("Failed to refresh session")
+		return fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	if session.DID == "" {
+		session.DID = current.DID
+	}
+
+	m.mu.Lock()
+	m.session = *session
+	m.refreshed = true
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *SessionManager) Session() models.Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.session
+}
+
+func (m *SessionManager) Refreshed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refreshed
+}
+
+func (m *SessionManager) Do(ctx context.Context, call func(accessJwt string) error) error {
+	err := call(m.Session().AccessJwt)
+	if !errors.Is(err, ErrExpiredToken) || m.Refreshed() || m.Session().RefreshJwt == "" {
+		return err
+	}
+
+	logrus.WithField("DID", m.Session().DID).Info("Access token expired, refreshing session")
+	if err := m.Refresh(ctx); err != nil {
+		return err
+	}
+
+	return call(m.Session().AccessJwt)
+}
@@ -0,0 +1,134 @@
+package atproto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeSessionPDS(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			var req models.CreateSessionRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.Identifier != "alice.shareframe.social" || req.Password != "app-pass-word" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"AuthenticationRequired","message":"Invalid identifier or password"}`))
+				return
+			}
+			w.Write([]byte(`{"did":"did:plc:alice","handle":"alice.shareframe.social","accessJwt":"access-1","refreshJwt":"refresh-1"}`))
+		case "/xrpc/com.atproto.server.refreshSession":
+			if r.Header.Get("Authorization") != "Bearer refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"ExpiredToken","message":"Token has expired"}`))
+				return
+			}
+			w.Write([]byte(`{"did":"did:plc:alice","handle":"alice.shareframe.social","accessJwt":"access-2","refreshJwt":"refresh-2"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestSessionManagerLoginAndRefresh(t *testing.T) {
+	pds := fakeSessionPDS(t)
+	defer pds.Close()
+
+	service := NewATProtoService(pds.Client(), Config{BaseURL: pds.URL})
+	manager := NewSessionManager(service, models.Session{})
+
+	err := manager.Login(context.Background(), "alice.shareframe.social", "wrong")
+	assert.Error(t, err)
+
+	err = manager.Login(context.Background(), "alice.shareframe.social", "app-pass-word")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Session{
+		DID:        "did:plc:alice",
+		Handle:     "alice.shareframe.social",
+		AccessJwt:  "access-1",
+		RefreshJwt: "refresh-1",
+	}, manager.Session())
+	assert.False(t, manager.Refreshed())
+
+	err = manager.Refresh(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-2", manager.Session().AccessJwt)
+	assert.Equal(t, "refresh-2", manager.Session().RefreshJwt)
+	assert.True(t, manager.Refreshed())
+}
+
+func TestSessionManagerDo(t *testing.T) {
+	expired := fmt.Errorf("createRecord failed: %w", ErrExpiredToken)
+
+	tests := []struct {
+		name         string
+		session      models.Session
+		results      map[string]error
+		expectTokens []string
+		expectErr    error
+	}{
+		{
+			name:         "Valid token is used once",
+			session:      models.Session{DID: "did:plc:alice", AccessJwt: "access-1", RefreshJwt: "refresh-1"},
+			results:      map[string]error{"access-1": nil},
+			expectTokens: []string{"access-1"},
+		},
+		{
+			name:         "Expired token is refreshed and retried",
+			session:      models.Session{DID: "did:plc:alice", AccessJwt: "access-1", RefreshJwt: "refresh-1"},
+			results:      map[string]error{"access-1": expired, "access-2": nil},
+			expectTokens: []string{"access-1", "access-2"},
+		},
+		{
+			name:         "Retry only happens once",
+			session:      models.Session{DID: "did:plc:alice", AccessJwt: "access-1", RefreshJwt: "refresh-1"},
+			results:      map[string]error{"access-1": expired, "access-2": expired},
+			expectTokens: []string{"access-1", "access-2"},
+			expectErr:    ErrExpiredToken,
+		},
+		{
+			name:         "Without a refresh token the error is returned",
+			session:      models.Session{DID: "did:plc:alice", AccessJwt: "access-1"},
+			results:      map[string]error{"access-1": expired},
+			expectTokens: []string{"access-1"},
+			expectErr:    ErrExpiredToken,
+		},
+		{
+			name:         "Other errors are not retried",
+			session:      models.Session{DID: "did:plc:alice", AccessJwt: "access-1", RefreshJwt: "refresh-1"},
+			results:      map[string]error{"access-1": ErrRecordNotFound},
+			expectTokens: []string{"access-1"},
+			expectErr:    ErrRecordNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pds := fakeSessionPDS(t)
+			defer pds.Close()
+
+			manager := NewSessionManager(NewATProtoService(pds.Client(), Config{BaseURL: pds.URL}), tt.session)
+
+			var tokens []string
+			err := manager.Do(context.Background(), func(accessJwt string) error {
+				tokens = append(tokens, accessJwt)
+				return tt.results[accessJwt]
+			})
+
+			assert.Equal(t, tt.expectTokens, tokens)
+			if tt.expectErr != nil {
+				assert.True(t, errors.Is(err, tt.expectErr))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Package syntax checks the string formats that AT Protocol identifiers
+// share, so AT-URI parsing and lexicon validation agree on them.
+package syntax
+
+import "regexp"
+
+var (
+	didPattern  = regexp.MustCompile(`^did:[a-z]+:[a-zA-Z0-9._:%-]*[a-zA-Z0-9._-]$`)
+	nsidPattern = regexp.MustCompile(`^[a-zA-Z]([a-zA-Z0-9-]{0,62})?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,62})?)+$`)
+	rkeyPattern = regexp.MustCompile(`^[a-zA-Z0-9._:~-]{1,512}$`)
+)
+
+func ValidDID(s string) bool {
+	return didPattern.MatchString(s)
+}
+
+func ValidNSID(s string) bool {
+	return nsidPattern.MatchString(s)
+}
+
+// ValidRecordKey rejects "." and "..", which match the character set but
+// are reserved.
+func ValidRecordKey(s string) bool {
+	return rkeyPattern.MatchString(s) && s != "." && s != ".."
+}
@@ -0,0 +1,37 @@
+package syntax
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		valid    func(string) bool
+		input    string
+		expected bool
+	}{
+		{name: "PLC DID", valid: ValidDID, input: "did:plc:abc123", expected: true},
+		{name: "Web DID", valid: ValidDID, input: "did:web:example.com", expected: true},
+		{name: "DID with trailing colon", valid: ValidDID, input: "did:plc:abc:", expected: false},
+		{name: "Uppercase DID method", valid: ValidDID, input: "did:PLC:abc", expected: false},
+		{name: "Handle as DID", valid: ValidDID, input: "alice.example.com", expected: false},
+		{name: "NSID", valid: ValidNSID, input: "social.shareframe.feed.post", expected: true},
+		{name: "Single segment NSID", valid: ValidNSID, input: "post", expected: false},
+		{name: "NSID starting with a digit", valid: ValidNSID, input: "1social.feed", expected: false},
+		{name: "TID record key", valid: ValidRecordKey, input: "3kq2ve7ruvk2a", expected: true},
+		{name: "Literal record key", valid: ValidRecordKey, input: "self", expected: true},
+		{name: "Dot record key", valid: ValidRecordKey, input: ".", expected: false},
+		{name: "Dot-dot record key", valid: ValidRecordKey, input: "..", expected: false},
+		{name: "Record key with a slash", valid: ValidRecordKey, input: "a/b", expected: false},
+		{name: "Empty record key", valid: ValidRecordKey, input: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.valid(tt.input))
+		})
+	}
+}
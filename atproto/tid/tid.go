@@ -0,0 +1,96 @@
+package tid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	alphabet = "234567abcdefghijklmnopqrstuvwxyz"
+
+	timestampBits = 53
+	clockIDBits   = 10
+)
+
+var tidPattern = regexp.MustCompile(`^[234567abcdefghij][234567abcdefghijklmnopqrstuvwxyz]{12}$`)
+
+var defaultClock = NewClock(randomClockID())
+
+func New(t time.Time, clockID uint) string {
+	return encode(pack(uint64(t.UnixMicro()), clockID))
+}
+
+func Now() string {
+	return defaultClock.Next()
+}
+
+func Valid(s string) bool {
+	return tidPattern.MatchString(s)
+}
+
+func Parse(s string) (time.Time, uint, error) {
+	if !Valid(s) {
+		return time.Time{}, 0, fmt.Errorf("invalid TID %q", s)
+	}
+
+	var v uint64
+	for i := 0; i < len(s); i++ {
+		v = v<<5 | uint64(indexOf(s[i]))
+	}
+	return time.UnixMicro(int64(v >> clockIDBits)).UTC(), uint(v & (1<<clockIDBits - 1)), nil
+}
+
+// Clock hands out strictly increasing TIDs even when the wall clock stalls or
+// steps backwards, so keys generated by one process never collide.
+type Clock struct {
+	mu      sync.Mutex
+	clockID uint
+	last    uint64
+	now     func() time.Time
+}
+
+func NewClock(clockID uint) *Clock {
+	return &Clock{clockID: clockID & (1<<clockIDBits - 1), now: time.Now}
+}
+
+func (c *Clock) Next() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	micros := uint64(c.now().UnixMicro())
+	if micros <= c.last {
+		micros = c.last + 1
+	}
+	c.last = micros
+	return encode(pack(micros, c.clockID))
+}
+
+func pack(micros uint64, clockID uint) uint64 {
+	return micros&(1<<timestampBits-1)<<clockIDBits | uint64(clockID&(1<<clockIDBits-1))
+}
+
+func encode(v uint64) string {
+	var buf [13]byte
+	for i := len(buf) - 1; i >= 0; i-- {
+		buf[i] = alphabet[v&0x1f]
+		v >>= 5
+	}
+	return string(buf[:])
+}
+
+func indexOf(c byte) int {
+	if c <= '7' {
+		return int(c - '2')
+	}
+	return int(c-'a') + 6
+}
+
+func randomClockID() uint {
+	var b [2]byte
+	rand.Read(b[:])
+	return uint(binary.BigEndian.Uint16(b[:]))
+}
@@ -0,0 +1,149 @@
+package tid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		time    time.Time
+		clockID uint
+		expect  string
+	}{
+		{
+			name:   "Unix epoch",
+			time:   time.Unix(0, 0),
+			expect: "2222222222222",
+		},
+		{
+			name:    "Clock ID fills the low bits",
+			time:    time.Unix(0, 0),
+			clockID: 31,
+			expect:  "222222222222z",
+		},
+		{
+			name:    "Clock ID is truncated to 10 bits",
+			time:    time.Unix(0, 0),
+			clockID: 1<<10 | 1,
+			expect:  "2222222222223",
+		},
+		{
+			name:   "Known timestamp",
+			time:   time.UnixMicro(1 << 20),
+			expect: "2222223222222",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, New(tt.time, tt.clockID))
+		})
+	}
+}
+
+func TestNewSortsByTime(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	earlier := New(base, 1023)
+	later := New(base.Add(time.Microsecond), 0)
+
+	assert.Len(t, earlier, 13)
+	assert.Less(t, earlier, later)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		tid           string
+		expectTime    time.Time
+		expectClockID uint
+		expectErr     bool
+	}{
+		{
+			name:          "Round trip",
+			tid:           New(time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC), 42),
+			expectTime:    time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC),
+			expectClockID: 42,
+		},
+		{
+			name:          "Unix epoch with maximum clock ID",
+			tid:           "22222222222zz",
+			expectTime:    time.Unix(0, 0).UTC(),
+			expectClockID: 1023,
+		},
+		{
+			name:      "Too short",
+			tid:       "3jzfcijpj2z2",
+			expectErr: true,
+		},
+		{
+			name:      "High bit set",
+			tid:       "zzzzzzzzzzzzz",
+			expectErr: true,
+		},
+		{
+			name:      "Uppercase",
+			tid:       "3JZFCIJPJ2Z2A",
+			expectErr: true,
+		},
+		{
+			name:      "Outside the alphabet",
+			tid:       "3jzfcijpj2z21",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, clockID, err := Parse(tt.tid)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.False(t, Valid(tt.tid))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, Valid(tt.tid))
+			assert.Equal(t, tt.expectTime, ts)
+			assert.Equal(t, tt.expectClockID, clockID)
+		})
+	}
+}
+
+func TestClockIsMonotonic(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewClock(7)
+	clock.now = func() time.Time { return fixed }
+
+	first := clock.Next()
+	second := clock.Next()
+
+	clock.now = func() time.Time { return fixed.Add(-time.Second) }
+	third := clock.Next()
+
+	assert.Less(t, first, second)
+	assert.Less(t, second, third)
+
+	ts, clockID, err := Parse(third)
+	assert.NoError(t, err)
+	assert.Equal(t, fixed.Add(2*time.Microsecond), ts)
+	assert.Equal(t, uint(7), clockID)
+}
+
+func TestNow(t *testing.T) {
+	seen := make(map[string]bool)
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		next := Now()
+		assert.True(t, Valid(next))
+		assert.Less(t, prev, next)
+		assert.False(t, seen[next])
+		seen[next] = true
+		prev = next
+	}
+}
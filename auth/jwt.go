@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const DefaultLeeway = 30 * time.Second
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+
+	ErrMalformedToken    = fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	ErrUnsupportedAlg    = fmt.Errorf("%w: unsupported signing algorithm", ErrInvalidToken)
+	ErrUnknownKey        = fmt.Errorf("%w: unknown signing key", ErrInvalidToken)
+	ErrInvalidSignature  = fmt.Errorf("%w: signature verification failed", ErrInvalidToken)
+	ErrTokenExpired      = fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	ErrTokenNotYetValid  = fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	ErrInvalidIssuer     = fmt.Errorf("%w: untrusted issuer", ErrInvalidToken)
+	ErrInvalidAudience   = fmt.Errorf("%w: token is not intended for this service", ErrInvalidToken)
+	ErrSubjectMismatch   = fmt.Errorf("%w: token subject does not match DID", ErrInvalidToken)
+	ErrMissingExpiration = fmt.Errorf("%w: token has no expiry", ErrInvalidToken)
+	ErrInvalidScope      = fmt.Errorf("%w: token does not grant access", ErrInvalidToken)
+
+	ErrMissingAudience = errors.New("an audience is required to verify tokens")
+)
+
+// DefaultScopes are the scopes a PDS puts in access tokens. Refresh and
+// service-auth tokens carry other scopes and are rejected.
+var DefaultScopes = []string{"com.atproto.access", "com.atproto.appPass", "com.atproto.appPassPrivileged"}
+
+// AppPasswordScopes are the access scopes of sessions created with an app
+// password rather than the account password.
+var AppPasswordScopes = []string{"com.atproto.appPass", "com.atproto.appPassPrivileged"}
+
+type Header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+}
+
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a Audience) Contains(aud string) bool {
+	return contains(a, aud)
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type Config struct {
+	Keys     KeySet
+	Issuers  []string
+	Audience string
+	// Scopes lists the accepted scopes; a token must carry at least one.
+	// Defaults to DefaultScopes.
+	Scopes []string
+	Leeway time.Duration
+}
+
+type Verifier struct {
+	keys     KeySet
+	issuers  []string
+	audience string
+	scopes   []string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewVerifier requires an audience: without one, a token the PDS signed for
+// any other service would be accepted here.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.Audience == "" {
+		return nil, ErrMissingAudience
+	}
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = DefaultLeeway
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	return &Verifier{
+		keys:     cfg.Keys,
+		issuers:  cfg.Issuers,
+		audience: cfg.Audience,
+		scopes:   cfg.Scopes,
+		leeway:   cfg.Leeway,
+		now:      time.Now,
+	}, nil
+}
+
+func (v *Verifier) Verify(ctx context.Context, token, did string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header Header
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	if err := v.validateClaims(&claims, did); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *Verifier) validateClaims(claims *Claims, did string) error {
+	now := v.now()
+
+	if claims.ExpiresAt == 0 {
+		return ErrMissingExpiration
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(v.leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+
+	if len(v.issuers) > 0 && !contains(v.issuers, claims.Issuer) {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, claims.Issuer)
+	}
+	if !claims.Audience.Contains(v.audience) {
+		return ErrInvalidAudience
+	}
+	if !claims.HasScope(v.scopes) {
+		return fmt.Errorf("%w: %q", ErrInvalidScope, claims.Scope)
+	}
+	if claims.Subject != did {
+		return ErrSubjectMismatch
+	}
+	return nil
+}
+
+// HasScope reports whether the space-separated scope claim grants any of the
+// accepted scopes.
+func (c *Claims) HasScope(accepted []string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if contains(accepted, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseUnverified decodes a token's claims without checking its signature or
+// validity. It is only for tokens the service received from the PDS itself.
+func ParseUnverified(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+func verifySignature(alg string, key interface{}, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("%w: %s key is not a shared secret", ErrUnsupportedAlg, alg)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().Name != "P-256" {
+			return fmt.Errorf("%w: %s key is not a P-256 public key", ErrUnsupportedAlg, alg)
+		}
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %s key is not an RSA public key", ErrUnsupportedAlg, alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupportedAlg, alg)
+}
@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testNow    = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	testSecret = []byte("pds-jwt-secret")
+)
+
+func signToken(t *testing.T, header Header, claims interface{}, key interface{}) string {
+	t.Helper()
+
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() Claims {
+	return Claims{
+		Issuer:    "did:web:pds.shareframe.social",
+		Subject:   "did:plc:alice",
+		Audience:  Audience{"did:web:pds.shareframe.social"},
+		ExpiresAt: testNow.Add(time.Hour).Unix(),
+		IssuedAt:  testNow.Add(-time.Minute).Unix(),
+		Scope:     "com.atproto.access",
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherEC, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := StaticKeys{
+		"hmac": testSecret,
+		"ec":   &ecKey.PublicKey,
+		"rsa":  &rsaKey.PublicKey,
+	}
+
+	with := func(mutate func(*Claims)) Claims {
+		c := validClaims()
+		mutate(&c)
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		token       func(t *testing.T) string
+		did         string
+		expectedErr error
+	}{
+		{
+			name: "HS256",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, validClaims(), testSecret)
+			},
+		},
+		{
+			name:  "ES256",
+			token: func(t *testing.T) string { return signToken(t, Header{Alg: "ES256", Kid: "ec"}, validClaims(), ecKey) },
+		},
+		{
+			name: "RS256",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "RS256", Kid: "rsa"}, validClaims(), rsaKey)
+			},
+		},
+		{
+			name: "Audience as a string within leeway of expiry",
+			token: func(t *testing.T) string {
+				claims := map[string]interface{}{
+					"sub":   "did:plc:alice",
+					"iss":   "did:web:pds.shareframe.social",
+					"aud":   "did:web:pds.shareframe.social",
+					"exp":   testNow.Add(-10 * time.Second).Unix(),
+					"scope": "com.atproto.appPass",
+				}
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, claims, testSecret)
+			},
+		},
+		{
+			name: "Scope among several",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, with(func(c *Claims) { c.Scope = "atproto com.atproto.access" }), testSecret)
+			},
+		},
+		{
+			name: "Refresh token",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, with(func(c *Claims) { c.Scope = "com.atproto.refresh" }), testSecret)
+			},
+			expectedErr: ErrInvalidScope,
+		},
+		{
+			name: "No scope",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, with(func(c *Claims) { c.Scope = "" }), testSecret)
+			},
+			expectedErr: ErrInvalidScope,
+		},
+		{
+			name: "Service-auth token",
+			token: func(t *testing.T) string {
+				claims := map[string]interface{}{
+					"sub": "did:plc:alice",
+					"iss": "did:web:pds.shareframe.social",
+					"aud": "did:web:pds.shareframe.social",
+					"exp": testNow.Add(time.Minute).Unix(),
+					"lxm": "com.atproto.repo.createRecord",
+				}
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, claims, testSecret)
+			},
+			expectedErr: ErrInvalidScope,
+		},
+		{
+			name: "Subject does not match DID",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, validClaims(), testSecret)
+			},
+			did:         "did:plc:mallory",
+			expectedErr: ErrSubjectMismatch,
+		},
+		{
+			name: "Expired",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "ES256", Kid: "ec"}, with(func(c *Claims) { c.ExpiresAt = testNow.Add(-time.Hour).Unix() }), ecKey)
+			},
+			expectedErr: ErrTokenExpired,
+		},
+		{
+			name: "No expiry",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, with(func(c *Claims) { c.ExpiresAt = 0 }), testSecret)
+			},
+			expectedErr: ErrMissingExpiration,
+		},
+		{
+			name: "Not valid yet",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, with(func(c *Claims) { c.NotBefore = testNow.Add(time.Hour).Unix() }), testSecret)
+			},
+			expectedErr: ErrTokenNotYetValid,
+		},
+		{
+			name: "Untrusted issuer",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, with(func(c *Claims) { c.Issuer = "did:web:evil.example" }), testSecret)
+			},
+			expectedErr: ErrInvalidIssuer,
+		},
+		{
+			name: "Wrong audience",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, with(func(c *Claims) { c.Audience = Audience{"did:web:other.example"} }), testSecret)
+			},
+			expectedErr: ErrInvalidAudience,
+		},
+		{
+			name: "Signed by another key",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "ES256", Kid: "ec"}, validClaims(), otherEC)
+			},
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name: "Wrong shared secret",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "hmac"}, validClaims(), []byte("guess"))
+			},
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name: "HS256 signed with an RSA public key",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "rsa"}, validClaims(), []byte("not-the-secret"))
+			},
+			expectedErr: ErrUnsupportedAlg,
+		},
+		{
+			name: "Alg none",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "none", Kid: "hmac"}, validClaims(), nil)
+			},
+			expectedErr: ErrUnsupportedAlg,
+		},
+		{
+			name: "Unknown kid",
+			token: func(t *testing.T) string {
+				return signToken(t, Header{Alg: "HS256", Kid: "rotated"}, validClaims(), testSecret)
+			},
+			expectedErr: ErrUnknownKey,
+		},
+		{
+			name:        "Not a JWT",
+			token:       func(t *testing.T) string { return "opaque-session-token" },
+			expectedErr: ErrMalformedToken,
+		},
+		{
+			name:        "Garbage segments",
+			token:       func(t *testing.T) string { return "a.b.c" },
+			expectedErr: ErrMalformedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVerifier(Config{
+				Keys:     keys,
+				Issuers:  []string{"did:web:pds.shareframe.social"},
+				Audience: "did:web:pds.shareframe.social",
+			})
+			require.NoError(t, err)
+			v.now = func() time.Time { return testNow }
+
+			did := tt.did
+			if did == "" {
+				did = "did:plc:alice"
+			}
+
+			claims, err := v.Verify(context.Background(), tt.token(t), did)
+			if tt.expectedErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, "did:plc:alice", claims.Subject)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			assert.Nil(t, claims)
+		})
+	}
+}
+
+func TestVerifyWithoutIssuer(t *testing.T) {
+	v, err := NewVerifier(Config{Keys: StaticKeys{"": testSecret}, Audience: "did:web:pds.shareframe.social"})
+	require.NoError(t, err)
+	v.now = func() time.Time { return testNow }
+
+	claims := validClaims()
+	claims.Issuer = ""
+	token := signToken(t, Header{Alg: "HS256"}, claims, testSecret)
+
+	_, err = v.Verify(context.Background(), token, "did:plc:alice")
+	assert.NoError(t, err)
+}
+
+func TestNewVerifierRequiresAudience(t *testing.T) {
+	v, err := NewVerifier(Config{Keys: StaticKeys{"": testSecret}})
+
+	assert.ErrorIs(t, err, ErrMissingAudience)
+	assert.Nil(t, v)
+}
+
+func TestParseUnverified(t *testing.T) {
+	claims := validClaims()
+	claims.Scope = "com.atproto.appPass"
+	token := signToken(t, Header{Alg: "HS256"}, claims, []byte("someone else's secret"))
+
+	got, err := ParseUnverified(token)
+	require.NoError(t, err)
+	assert.Equal(t, "did:plc:alice", got.Subject)
+	assert.True(t, got.HasScope(AppPasswordScopes))
+
+	got.Scope = "com.atproto.access"
+	assert.False(t, got.HasScope(AppPasswordScopes))
+
+	_, err = ParseUnverified("not-a-token")
+	assert.ErrorIs(t, err, ErrMalformedToken)
+}
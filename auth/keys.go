@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultJWKSCacheTTL = 10 * time.Minute
+
+	minJWKSRefresh = 30 * time.Second
+)
+
+type KeySet interface {
+	Key(ctx context.Context, kid, alg string) (interface{}, error)
+}
+
+type StaticKeys map[string]interface{}
+
+func (k StaticKeys) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	if key, ok := k[kid]; ok {
+		return key, nil
+	}
+	if key, ok := k[""]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(StaticKeys, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (j JWK) PublicKey() (interface{}, error) {
+	switch j.Kty {
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, errX := decodeInt(j.X)
+		y, errY := decodeInt(j.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid EC coordinates")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !pub.Curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC point is not on curve")
+		}
+		return pub, nil
+	case "RSA":
+		n, errN := decodeInt(j.N)
+		e, errE := decodeInt(j.E)
+		if errN != nil || errE != nil || !e.IsInt64() || e.Int64() < 3 {
+			return nil, fmt.Errorf("invalid RSA parameters")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(j.K)
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("invalid symmetric key")
+		}
+		return secret, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) == 0 {
+		return nil, fmt.Errorf("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+type JWKS struct {
+	client *http.Client
+	url    string
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	keys      StaticKeys
+	fetchedAt time.Time
+	refresh   *jwksRefresh
+}
+
+// jwksRefresh is a fetch in progress. Callers wait on done rather than on
+// the lock, so a slow JWKS endpoint only delays requests that need new keys.
+type jwksRefresh struct {
+	done chan struct{}
+	keys StaticKeys
+	err  error
+}
+
+func NewJWKS(client *http.Client, url string, ttl time.Duration) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if ttl <= 0 {
+		ttl = DefaultJWKSCacheTTL
+	}
+	return &JWKS{client: client, url: url, ttl: ttl, now: time.Now}
+}
+
+func (j *JWKS) Key(ctx context.Context, kid, alg string) (interface{}, error) {
+	j.mu.Lock()
+	age := j.now().Sub(j.fetchedAt)
+	if j.keys != nil && age < j.ttl {
+		if key, ok := j.keys[kid]; ok {
+			j.mu.Unlock()
+			return key, nil
+		}
+		if age < minJWKSRefresh {
+			j.mu.Unlock()
+			return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+		}
+	}
+
+	refresh := j.refresh
+	if refresh == nil {
+		refresh = &jwksRefresh{done: make(chan struct{})}
+		j.refresh = refresh
+		// The fetch is shared, so it must outlive a caller that gives up.
+		go j.fetchKeys(context.WithoutCancel(ctx), refresh)
+	}
+	j.mu.Unlock()
+
+	select {
+	case <-refresh.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if refresh.err != nil {
+		return nil, refresh.err
+	}
+	return refresh.keys.Key(ctx, kid, alg)
+}
+
+func (j *JWKS) fetchKeys(ctx context.Context, refresh *jwksRefresh) {
+	refresh.keys, refresh.err = j.fetch(ctx)
+
+	j.mu.Lock()
+	if refresh.err == nil {
+		j.keys, j.fetchedAt = refresh.keys, j.now()
+	}
+	j.refresh = nil
+	j.mu.Unlock()
+
+	close(refresh.done)
+}
+
+func (j *JWKS) fetch(ctx context.Context) (StaticKeys, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		logrus.WithError(err).WithField("url", j.url).Error("JWKS request failed")
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		logrus.WithFields(logrus.Fields{
+			"url":    j.url,
+			"status": resp.StatusCode,
+		}).Error("Failed to fetch JWKS")
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	return ParseJWKS(body)
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func ecJWK(kid string, pub *ecdsa.PublicKey) JWK {
+	return JWK{Kty: "EC", Kid: kid, Crv: "P-256", X: b64(pub.X.FillBytes(make([]byte, 32))), Y: b64(pub.Y.FillBytes(make([]byte, 32)))}
+}
+
+func TestParseJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		keys      []JWK
+		expectErr string
+		check     func(*testing.T, StaticKeys)
+	}{
+		{
+			name: "EC, RSA and symmetric keys",
+			keys: []JWK{
+				ecJWK("ec", &ecKey.PublicKey),
+				{Kty: "RSA", Kid: "rsa", N: b64(rsaKey.N.Bytes()), E: b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+				{Kty: "oct", Kid: "hmac", K: b64(testSecret)},
+				{Kty: "RSA", Kid: "enc", Use: "enc", N: "x", E: "x"},
+			},
+			check: func(t *testing.T, keys StaticKeys) {
+				assert.True(t, ecKey.PublicKey.Equal(keys["ec"]))
+				assert.True(t, rsaKey.PublicKey.Equal(keys["rsa"]))
+				assert.Equal(t, testSecret, keys["hmac"])
+				assert.NotContains(t, keys, "enc")
+			},
+		},
+		{
+			name:      "Unsupported curve",
+			keys:      []JWK{{Kty: "EC", Kid: "k", Crv: "secp256k1", X: "AA", Y: "AA"}},
+			expectErr: `JWKS key "k": unsupported curve "secp256k1"`,
+		},
+		{
+			name:      "Point not on curve",
+			keys:      []JWK{{Kty: "EC", Kid: "k", Crv: "P-256", X: b64([]byte{1}), Y: b64([]byte{2})}},
+			expectErr: `JWKS key "k": EC point is not on curve`,
+		},
+		{
+			name:      "Unsupported key type",
+			keys:      []JWK{{Kty: "OKP", Kid: "k"}},
+			expectErr: `JWKS key "k": unsupported key type "OKP"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(map[string]interface{}{"keys": tt.keys})
+			require.NoError(t, err)
+
+			keys, err := ParseJWKS(raw)
+			if tt.expectErr != "" {
+				assert.EqualError(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			tt.check(t, keys)
+		})
+	}
+}
+
+func TestJWKSFetchAndRotate(t *testing.T) {
+	first, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	second, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var requests atomic.Int32
+	published := []JWK{ecJWK("k1", &first.PublicKey)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": published})
+	}))
+	defer srv.Close()
+
+	now := testNow
+	jwks := NewJWKS(srv.Client(), srv.URL, time.Hour)
+	jwks.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	key, err := jwks.Key(ctx, "k1", "ES256")
+	assert.NoError(t, err)
+	assert.True(t, first.PublicKey.Equal(key))
+
+	_, err = jwks.Key(ctx, "k1", "ES256")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load(), "cached key should not refetch")
+
+	published = append(published, ecJWK("k2", &second.PublicKey))
+
+	_, err = jwks.Key(ctx, "k2", "ES256")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, int32(1), requests.Load(), "unknown kid right after a fetch should not refetch")
+
+	now = now.Add(time.Minute)
+	key, err = jwks.Key(ctx, "k2", "ES256")
+	assert.NoError(t, err)
+	assert.True(t, second.PublicKey.Equal(key))
+	assert.Equal(t, int32(2), requests.Load())
+
+	now = now.Add(2 * time.Hour)
+	_, err = jwks.Key(ctx, "k1", "ES256")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load(), "expired cache should refetch")
+}
+
+func TestJWKSFetchDoesNotBlockCachedKeys(t *testing.T) {
+	first, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	second, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var requests atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		published := []JWK{ecJWK("k1", &first.PublicKey)}
+		if requests.Add(1) > 1 {
+			<-release
+			published = append(published, ecJWK("k2", &second.PublicKey))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": published})
+	}))
+	defer srv.Close()
+
+	now := testNow
+	jwks := NewJWKS(srv.Client(), srv.URL, time.Hour)
+	jwks.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err = jwks.Key(ctx, "k1", "ES256")
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+
+	fetched := make(chan interface{})
+	go func() {
+		key, _ := jwks.Key(ctx, "k2", "ES256")
+		fetched <- key
+	}()
+	require.Eventually(t, func() bool { return requests.Load() == 2 }, time.Second, time.Millisecond)
+
+	key, err := jwks.Key(ctx, "k1", "ES256")
+	assert.NoError(t, err)
+	assert.True(t, first.PublicKey.Equal(key))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = jwks.Key(canceled, "k3", "ES256")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	assert.True(t, second.PublicKey.Equal(<-fetched))
+	assert.Equal(t, int32(2), requests.Load(), "waiting callers share one fetch")
+}
+
+func TestJWKSFetchFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewJWKS(srv.Client(), srv.URL, 0).Key(context.Background(), "k1", "ES256")
+	assert.EqualError(t, err, "failed to fetch JWKS: status 503")
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+}
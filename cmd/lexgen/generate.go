@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/ShareFrame/posting-service/lexicon"
+)
+
+type Options struct {
+	Package string
+	Gen     map[string]string
+	Refs    map[string]string
+	Blob    string
+}
+
+type generator struct {
+	catalog *lexicon.Catalog
+	opts    Options
+	names   map[string]string
+	body    bytes.Buffer
+	unions  []union
+	imports map[string]bool
+	helper  bool
+}
+
+type union struct {
+	name     string
+	closed   bool
+	variants []variant
+}
+
+type variant struct {
+	typeID string
+	goType string
+}
+
+var initialisms = map[string]string{
+	"cid":  "CID",
+	"did":  "DID",
+	"id":   "ID",
+	"nsid": "NSID",
+	"uri":  "URI",
+	"url":  "URL",
+}
+
+func Generate(catalog *lexicon.Catalog, opts Options) ([]byte, error) {
+	if opts.Blob == "" {
+		opts.Blob = "Blob"
+	}
+	g := &generator{
+		catalog: catalog,
+		opts:    opts,
+		names:   make(map[string]string),
+		imports: make(map[string]bool),
+	}
+
+	for ref, name := range opts.Refs {
+		g.names[canonical(ref, "")] = name
+	}
+
+	targets := sortedKeys(opts.Gen)
+	for _, nsid := range targets {
+		schema, ok := catalog.Schema(nsid)
+		if !ok {
+			return nil, fmt.Errorf("unknown lexicon %s", nsid)
+		}
+		for name, def := range schema.Defs {
+			if !isObject(def) {
+				continue
+			}
+			if name == "main" {
+				g.names[nsid] = opts.Gen[nsid]
+			} else {
+				g.names[nsid+"#"+name] = exported(name)
+			}
+		}
+	}
+
+	for _, nsid := range targets {
+		schema, _ := catalog.Schema(nsid)
+		for _, name := range defOrder(schema) {
+			def := schema.Defs[name]
+			if !isObject(def) {
+				continue
+			}
+			if err := g.writeObject(g.names[canonical("#"+name, nsid)], def, nsid); err != nil {
+				return nil, fmt.Errorf("%s#%s: %w", nsid, name, err)
+			}
+		}
+	}
+
+	for _, u := range g.unions {
+		g.writeUnion(u)
+	}
+	if g.helper {
+		g.writeVariantHelper()
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by lexgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+	if len(g.imports) > 0 {
+		src.WriteString("import (\n")
+		for _, imp := range sortedKeys(g.imports) {
+			fmt.Fprintf(&src, "\t%q\n", imp)
+		}
+		src.WriteString(")\n\n")
+	}
+	src.Write(g.body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return formatted, nil
+}
+
+func (g *generator) writeObject(name string, def *lexicon.Def, nsid string) error {
+	if def.Type == "record" {
+		def = def.Record
+		defer g.writeRecordMarshaler(name, nsid)
+	}
+
+	fmt.Fprintf(&g.body, "type %s struct {\n", name)
+	for _, prop := range sortedKeys(def.Properties) {
+		required := contains(def.Required, prop)
+		goType, err := g.goType(def.Properties[prop], nsid, name+exported(prop), required)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prop, err)
+		}
+		tag := prop
+		if !required {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&g.body, "\t%s %s `json:%q`\n", exported(prop), goType, tag)
+	}
+	g.body.WriteString("}\n\n")
+	return nil
+}
+
+func (g *generator) goType(def *lexicon.Def, nsid, inlineName string, required bool) (string, error) {
+	switch def.Type {
+	case "string":
+		return "string", nil
+	case "integer":
+		return "int64", nil
+	case "boolean":
+		return "bool", nil
+	case "blob":
+		return g.opts.Blob, nil
+	case "bytes":
+		return "[]byte", nil
+	case "unknown":
+		g.imports["encoding/json"] = true
+		return "json.RawMessage", nil
+	case "array":
+		if def.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		item, err := g.goType(def.Items, nsid, inlineName, true)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + item, nil
+	case "ref":
+		name, err := g.refType(def.Ref, nsid)
+		if err != nil {
+			return "", err
+		}
+		return optional(name, required), nil
+	case "union":
+		u := union{name: inlineName, closed: def.Closed}
+		for _, ref := range def.Refs {
+			name, err := g.refType(ref, nsid)
+			if err != nil {
+				return "", err
+			}
+			u.variants = append(u.variants, variant{typeID: canonical(ref, nsid), goType: name})
+		}
+		g.unions = append(g.unions, u)
+		return optional(inlineName, required), nil
+	}
+	return "", fmt.Errorf("unsupported lexicon type %q", def.Type)
+}
+
+func (g *generator) refType(ref, nsid string) (string, error) {
+	id := canonical(ref, nsid)
+	if name, ok := g.names[id]; ok {
+		return name, nil
+	}
+
+	target, targetNSID, err := g.lookup(id)
+	if err != nil {
+		return "", err
+	}
+	if isObject(target) {
+		return "", fmt.Errorf("no Go type for %s; generate it or map it with -ref", id)
+	}
+	return g.goType(target, targetNSID, "", true)
+}
+
+func (g *generator) lookup(id string) (*lexicon.Def, string, error) {
+	nsid, name, ok := strings.Cut(id, "#")
+	if !ok {
+		name = "main"
+	}
+	schema, found := g.catalog.Schema(nsid)
+	if !found {
+		return nil, "", fmt.Errorf("unknown lexicon %s", nsid)
+	}
+	def, found := schema.Defs[name]
+	if !found {
+		return nil, "", fmt.Errorf("lexicon %s has no definition %q", nsid, name)
+	}
+	return def, nsid, nil
+}
+
+func (g *generator) writeRecordMarshaler(name, nsid string) {
+	g.imports["encoding/json"] = true
+	g.helper = true
+
+	fmt.Fprintf(&g.body, "func (r %s) MarshalJSON() ([]byte, error) {\n", name)
+	fmt.Fprintf(&g.body, "\ttype plain %s\n\treturn marshalVariant(%q, plain(r))\n}\n\n", name, nsid)
+}
+
+func (g *generator) writeUnion(u union) {
+	g.imports["encoding/json"] = true
+	g.imports["fmt"] = true
+	g.helper = true
+
+	fmt.Fprintf(&g.body, "type %s struct {\n", u.name)
+	for _, v := range u.variants {
+		fmt.Fprintf(&g.body, "\t%s *%s\n", v.goType, v.goType)
+	}
+	if !u.closed {
+		g.body.WriteString("\tUnknown json.RawMessage\n")
+	}
+	g.body.WriteString("}\n\n")
+
+	fmt.Fprintf(&g.body, "func (u %s) MarshalJSON() ([]byte, error) {\n\tswitch {\n", u.name)
+	for _, v := range u.variants {
+		fmt.Fprintf(&g.body, "\tcase u.%s != nil:\n\t\treturn marshalVariant(%q, u.%s)\n", v.goType, v.typeID, v.goType)
+	}
+	if !u.closed {
+		g.body.WriteString("\tcase u.Unknown != nil:\n\t\treturn u.Unknown, nil\n")
+	}
+	fmt.Fprintf(&g.body, "\t}\n\treturn nil, fmt.Errorf(\"%s: no variant set\")\n}\n\n", u.name)
+
+	fmt.Fprintf(&g.body, "func (u *%s) UnmarshalJSON(data []byte) error {\n", u.name)
+	g.body.WriteString("\tvar head struct {\n\t\tType string `json:\"$type\"`\n\t}\n")
+	g.body.WriteString("\tif err := json.Unmarshal(data, &head); err != nil {\n\t\treturn err\n\t}\n\n")
+	fmt.Fprintf(&g.body, "\t*u = %s{}\n\tswitch head.Type {\n", u.name)
+	for _, v := range u.variants {
+		fmt.Fprintf(&g.body, "\tcase %q:\n\t\tu.%s = new(%s)\n\t\treturn json.Unmarshal(data, u.%s)\n", v.typeID, v.goType, v.goType, v.goType)
+	}
+	fmt.Fprintf(&g.body, "\tcase \"\":\n\t\treturn fmt.Errorf(\"%s: missing $type\")\n\t}\n", u.name)
+	if u.closed {
+		fmt.Fprintf(&g.body, "\treturn fmt.Errorf(\"%s: unknown $type %%q\", head.Type)\n}\n\n", u.name)
+	} else {
+		g.body.WriteString("\tu.Unknown = append(json.RawMessage(nil), data...)\n\treturn nil\n}\n\n")
+	}
+}
+
+func (g *generator) writeVariantHelper() {
+	g.body.WriteString(`func marshalVariant(typeID string, v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	fields["$type"], _ = json.Marshal(typeID)
+	return json.Marshal(fields)
+}
+`)
+}
+
+func isObject(def *lexicon.Def) bool {
+	return def.Type == "object" || def.Type == "record"
+}
+
+func optional(name string, required bool) string {
+	if required {
+		return name
+	}
+	return "*" + name
+}
+
+func canonical(ref, context string) string {
+	nsid, name, _ := strings.Cut(ref, "#")
+	if nsid == "" {
+		nsid = context
+	}
+	if name == "" || name == "main" {
+		return nsid
+	}
+	return nsid + "#" + name
+}
+
+func exported(name string) string {
+	if upper, ok := initialisms[strings.ToLower(name)]; ok {
+		return upper
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func defOrder(schema *lexicon.Schema) []string {
+	names := sortedKeys(schema.Defs)
+	for i, name := range names {
+		if name == "main" {
+			return append([]string{"main"}, append(names[:i:i], names[i+1:]...)...)
+		}
+	}
+	return names
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ShareFrame/posting-service/lexicon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedModelsAreUpToDate(t *testing.T) {
+	catalog := lexicon.NewCatalog()
+	require.NoError(t, catalog.LoadFS(os.DirFS("../../lexicon")))
+
+	src, err := Generate(catalog, Options{
+		Package: "models",
+		Gen: map[string]string{
+			"social.shareframe.feed.post": "ShareFrameFeedPost",
+			"com.atproto.repo.strongRef":  "StrongRef",
+		},
+		Refs: map[string]string{"app.bsky.richtext.facet": "Facet"},
+	})
+	require.NoError(t, err)
+
+	existing, err := os.ReadFile("../../models/post_gen.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(existing), string(src), "models/post_gen.go is stale; run go generate ./models")
+}
+
+const fixture = `{
+	"lexicon": 1,
+	"id": "com.example.thing",
+	"defs": {
+		"main": {
+			"type": "record",
+			"record": {
+				"type": "object",
+				"required": ["name", "embed"],
+				"properties": {
+					"name": {"type": "string"},
+					"count": {"type": "integer"},
+					"embed": {"type": "union", "refs": ["#image", "#link"], "closed": true},
+					"labels": {"type": "array", "items": {"type": "union", "refs": ["#link"]}},
+					"extra": {"type": "unknown"},
+					"ownerDid": {"type": "ref", "ref": "#did"}
+				}
+			}
+		},
+		"did": {"type": "string", "format": "did"},
+		"image": {
+			"type": "object",
+			"required": ["blob"],
+			"properties": {"blob": {"type": "blob"}, "alt": {"type": "string"}}
+		},
+		"link": {
+			"type": "object",
+			"properties": {"uri": {"type": "string"}}
+		}
+	}
+}`
+
+func TestGenerateUnions(t *testing.T) {
+	catalog := lexicon.NewCatalog()
+	require.NoError(t, catalog.LoadFS(fstest.MapFS{"thing.json": {Data: []byte(fixture)}}))
+
+	src, err := Generate(catalog, Options{
+		Package: "example",
+		Gen:     map[string]string{"com.example.thing": "Thing"},
+	})
+	require.NoError(t, err)
+	code := strings.Join(strings.Fields(string(src)), " ")
+
+	assert.Contains(t, code, "type Thing struct {")
+	assert.Contains(t, code, "Embed ThingEmbed `json:\"embed\"`")
+	assert.Contains(t, code, "Labels []ThingLabels `json:\"labels,omitempty\"`")
+	assert.Contains(t, code, "OwnerDid *string `json:\"ownerDid,omitempty\"`")
+	assert.Contains(t, code, "Extra json.RawMessage `json:\"extra,omitempty\"`")
+	assert.Contains(t, code, "Blob Blob `json:\"blob\"`")
+	assert.Contains(t, code, `return marshalVariant("com.example.thing", plain(r))`)
+	assert.Contains(t, code, `case "com.example.thing#image":`)
+	assert.Contains(t, code, `return fmt.Errorf("ThingEmbed: unknown $type %q", head.Type)`)
+	assert.Contains(t, code, "Unknown json.RawMessage")
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "thing_gen.go", src, 0)
+	require.NoError(t, err)
+	blob, err := parser.ParseFile(fset, "blob.go", "package example\n\ntype Blob struct{}\n", 0)
+	require.NoError(t, err)
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("example", fset, []*ast.File{file, blob}, nil)
+	assert.NoError(t, err)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      string
+		expectedErr string
+	}{
+		{
+			name:        "Unmapped external ref",
+			schema:      `{"lexicon":1,"id":"com.example.a","defs":{"main":{"type":"object","properties":{"f":{"type":"ref","ref":"com.example.b"}}}}}`,
+			expectedErr: "com.example.a#main: f: unknown lexicon com.example.b",
+		},
+		{
+			name:        "Unsupported type",
+			schema:      `{"lexicon":1,"id":"com.example.a","defs":{"main":{"type":"object","properties":{"f":{"type":"cid-link"}}}}}`,
+			expectedErr: `com.example.a#main: f: unsupported lexicon type "cid-link"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := lexicon.NewCatalog()
+			require.NoError(t, catalog.LoadFS(fstest.MapFS{"a.json": {Data: []byte(tt.schema)}}))
+
+			_, err := Generate(catalog, Options{Package: "example", Gen: map[string]string{"com.example.a": "A"}})
+			assert.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ShareFrame/posting-service/lexicon"
+	"github.com/sirupsen/logrus"
+)
+
+type mappings map[string]string
+
+func (m mappings) String() string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m mappings) Set(value string) error {
+	nsid, name, ok := strings.Cut(value, "=")
+	if !ok || nsid == "" || name == "" {
+		return fmt.Errorf("expected <nsid>=<GoName>, got %q", value)
+	}
+	m[nsid] = name
+	return nil
+}
+
+func main() {
+	gen := mappings{}
+	refs := mappings{}
+
+	dir := flag.String("dir", "lexicon", "directory containing lexicon JSON files")
+	pkg := flag.String("package", "models", "Go package name of the generated file")
+	out := flag.String("out", "", "output file (stdout if empty)")
+	blob := flag.String("blob", "Blob", "Go type used for blob fields")
+	flag.Var(gen, "gen", "generate types for a lexicon as <nsid>=<GoName> (repeatable)")
+	flag.Var(refs, "ref", "map an existing Go type to a lexicon def as <nsid[#def]>=<GoName> (repeatable)")
+	flag.Parse()
+
+	catalog := lexicon.NewCatalog()
+	if err := catalog.LoadFS(os.DirFS(*dir)); err != nil {
+		logrus.WithError(err).Fatal("Failed to load lexicons")
+	}
+
+	src, err := Generate(catalog, Options{
+		Package: *pkg,
+		Gen:     gen,
+		Refs:    refs,
+		Blob:    *blob,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to generate types")
+	}
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		logrus.WithError(err).Fatal("Failed to write output")
+	}
+}
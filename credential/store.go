@@ -0,0 +1,115 @@
+// Package credential keeps the app passwords authors grant the service so it
+// can act for them while they are away: publishing scheduled posts and
+// deleting expired stories. Unlike a refresh token, an app password is not
+// used up when the author's own app refreshes its session, and the author can
+// revoke it from their account settings at any time.
+package credential
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/jsonstore"
+)
+
+// KeySize is the length of the key NewFileStore seals app passwords with.
+const KeySize = 32
+
+var (
+	ErrNotFound    = errors.New("credential not found")
+	ErrInvalidKey  = fmt.Errorf("credential key must be %d bytes", KeySize)
+	ErrDIDMismatch = errors.New("credential belongs to another DID")
+)
+
+type Credential struct {
+	DID         string    `json:"did"`
+	AppPassword string    `json:"appPassword"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Store holds one credential per DID. Put replaces any earlier one.
+type Store interface {
+	Put(ctx context.Context, c Credential) error
+	Get(ctx context.Context, did string) (*Credential, error)
+	Delete(ctx context.Context, did string) error
+}
+
+var config = jsonstore.Config{Name: "credential", ErrNotFound: ErrNotFound}
+
+func NewMemoryStore() Store {
+	return &store{credentials: jsonstore.NewMemory[Credential](config)}
+}
+
+// NewFileStore seals app passwords with AES-256-GCM under key before they
+// are written, so the files alone do not give away anyone's account.
+func NewFileStore(dir string, key []byte) (Store, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential cipher: %w", err)
+	}
+
+	credentials, err := jsonstore.NewFile[Credential](dir, config)
+	if err != nil {
+		return nil, err
+	}
+	return &store{credentials: credentials, aead: aead}, nil
+}
+
+type store struct {
+	credentials jsonstore.Store[Credential]
+	aead        cipher.AEAD
+}
+
+func (s *store) Put(ctx context.Context, c Credential) error {
+	if s.aead != nil {
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("failed to seal credential: %w", err)
+		}
+		// The DID is authenticated along with the password, so a sealed
+		// password cannot be copied into another DID's file.
+		sealed := s.aead.Seal(nonce, nonce, []byte(c.AppPassword), []byte(c.DID))
+		c.AppPassword = base64.StdEncoding.EncodeToString(sealed)
+	}
+	return s.credentials.Put(ctx, c.DID, c)
+}
+
+func (s *store) Get(ctx context.Context, did string) (*Credential, error) {
+	c, err := s.credentials.Get(ctx, did)
+	if err != nil || s.aead == nil {
+		return c, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(c.AppPassword)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return nil, fmt.Errorf("failed to open credential for %s: malformed", did)
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	if c.DID != did {
+		return nil, fmt.Errorf("failed to open credential for %s: %w", did, ErrDIDMismatch)
+	}
+	password, err := s.aead.Open(nil, nonce, ciphertext, []byte(c.DID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open credential for %s: %w", did, err)
+	}
+	c.AppPassword = string(password)
+	return c, nil
+}
+
+func (s *store) Delete(ctx context.Context, did string) error {
+	return s.credentials.Delete(ctx, did)
+}
@@ -0,0 +1,119 @@
+package credential
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{7}, KeySize)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	stores := map[string]func(t *testing.T) Store{
+		"Memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"File": func(t *testing.T) Store {
+			store, err := NewFileStore(t.TempDir(), key)
+			require.NoError(t, err)
+			return store
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+
+			_, err := store.Get(ctx, "did:plc:alice")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, store.Put(ctx, Credential{DID: "did:plc:alice", AppPassword: "aaaa-bbbb-cccc-dddd", CreatedAt: now, UpdatedAt: now}))
+			require.NoError(t, store.Put(ctx, Credential{DID: "did:plc:alice", AppPassword: "eeee-ffff-gggg-hhhh", CreatedAt: now, UpdatedAt: now}))
+
+			got, err := store.Get(ctx, "did:plc:alice")
+			require.NoError(t, err)
+			assert.Equal(t, "eeee-ffff-gggg-hhhh", got.AppPassword)
+
+			require.NoError(t, store.Delete(ctx, "did:plc:alice"))
+			_, err = store.Get(ctx, "did:plc:alice")
+			assert.ErrorIs(t, err, ErrNotFound)
+			assert.ErrorIs(t, store.Delete(ctx, "did:plc:alice"), ErrNotFound)
+		})
+	}
+}
+
+func TestFileStoreSealsPasswords(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, KeySize)
+
+	store, err := NewFileStore(dir, key)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, Credential{DID: "did:plc:alice", AppPassword: "aaaa-bbbb-cccc-dddd"}))
+	require.NoError(t, store.Put(ctx, Credential{DID: "did:plc:bob", AppPassword: "eeee-ffff-gggg-hhhh"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "bbbb")
+		assert.NotContains(t, string(data), "ffff")
+	}
+
+	t.Run("Another key cannot open them", func(t *testing.T) {
+		other, err := NewFileStore(dir, bytes.Repeat([]byte{8}, KeySize))
+		require.NoError(t, err)
+		_, err = other.Get(ctx, "did:plc:alice")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("A sealed password is bound to its DID", func(t *testing.T) {
+		alice, err := os.ReadFile(filepath.Join(dir, fileFor(t, dir, "did:plc:alice")))
+		require.NoError(t, err)
+		bob := fileFor(t, dir, "did:plc:bob")
+		swapped := bytes.Replace(alice, []byte("did:plc:alice"), []byte("did:plc:bob"), 1)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, bob), swapped, 0o600))
+
+		_, err = store.Get(ctx, "did:plc:bob")
+		assert.Error(t, err)
+	})
+
+	t.Run("A credential filed under another DID is refused", func(t *testing.T) {
+		alice, err := os.ReadFile(filepath.Join(dir, fileFor(t, dir, "did:plc:alice")))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fileFor(t, dir, "did:plc:bob")), alice, 0o600))
+
+		_, err = store.Get(ctx, "did:plc:bob")
+		assert.ErrorIs(t, err, ErrDIDMismatch)
+	})
+
+	t.Run("Key must be 32 bytes", func(t *testing.T) {
+		_, err := NewFileStore(t.TempDir(), key[:16])
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	})
+}
+
+// fileFor finds the file holding did's credential without depending on how
+// jsonstore names them.
+func fileFor(t *testing.T, dir, did string) string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		if bytes.Contains(data, []byte(`"`+did+`"`)) {
+			return filepath.Base(file)
+		}
+	}
+	t.Fatalf("no file for %s", did)
+	return ""
+}
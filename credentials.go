@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/sirupsen/logrus"
+)
+
+type CredentialInput struct {
+	AuthToken   string `json:"authToken"`
+	DID         string `json:"did"`
+	AppPassword string `json:"appPassword,omitempty"`
+}
+
+func isCredentialsPath(path string) bool {
+	return strings.HasSuffix(strings.TrimRight(path, "/"), "/credentials")
+}
+
+func (a *app) routeCredentials(ctx context.Context, req apiRequest) apiResponse {
+	var call func(context.Context, apiRequest) (interface{}, error)
+
+	switch req.Method {
+	case http.MethodPost, http.MethodPut:
+		call = a.registerCredential
+	case http.MethodDelete:
+		call = a.removeCredential
+	default:
+		return errorResponse(&handler.Error{
+			Code: handler.CodeMethodNotAllowed,
+			Err:  errors.New("method not allowed: " + req.Method),
+		})
+	}
+
+	result, err := call(ctx, req)
+	return respond(http.StatusOK, result, err)
+}
+
+func (a *app) registerCredential(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.credentialInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.RegisterCredential(ctx, a.client, a.credentials, a.schedules, a.expiries, input.DID, input.AppPassword)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("RegisterCredential failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (a *app) removeCredential(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.credentialInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handler.RemoveCredential(ctx, a.credentials, input.DID); err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("RemoveCredential failed")
+		return nil, err
+	}
+
+	return map[string]string{"did": input.DID}, nil
+}
+
+// Like schedules, credentials are managed without a call the PDS could
+// reject, so the caller's token must be verified here.
+func (a *app) credentialInput(ctx context.Context, req apiRequest) (*CredentialInput, error) {
+	if a.credentials == nil {
+		return nil, &handler.Error{Code: handler.CodeNotFound, Err: errors.New("credentials are not enabled")}
+	}
+
+	var input CredentialInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, err
+	}
+	if err := a.authorize(ctx, input.AuthToken, input.DID); err != nil {
+		return nil, err
+	}
+
+	return &input, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/auth"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func credentialEvent(method, body string) json.RawMessage {
+	return mustMarshal(map[string]interface{}{
+		"httpMethod": method,
+		"path":       "/credentials",
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       body,
+	})
+}
+
+func TestCredentials(t *testing.T) {
+	secret := []byte("test-secret")
+	alice := hs256Token(secret, "did:plc:alice", time.Now().Add(time.Hour))
+	mallory := hs256Token(secret, "did:plc:mallory", time.Now().Add(time.Hour))
+	appPassToken := hs256ScopedToken([]byte("pds-secret"), "did:plc:alice", "com.atproto.appPass", time.Now().Add(time.Hour))
+
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/xrpc/com.atproto.server.createSession", r.URL.Path)
+		w.Write([]byte(`{"did":"did:plc:alice","accessJwt":"` + appPassToken + `","refreshJwt":"refresh"}`))
+	})
+	a.credentials = credential.NewMemoryStore()
+	a.verifier = newTestVerifier(t, auth.Config{Keys: auth.StaticKeys{"": secret}, Audience: "did:web:pds.test"})
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{"Another user's token", http.MethodPut, `{"authToken":"` + mallory + `","did":"did:plc:alice","appPassword":"aaaa-bbbb-cccc-dddd"}`, http.StatusUnauthorized},
+		{"Registered", http.MethodPut, `{"authToken":"` + alice + `","did":"did:plc:alice","appPassword":"aaaa-bbbb-cccc-dddd"}`, http.StatusOK},
+		{"Removed", http.MethodDelete, `{"authToken":"` + alice + `","did":"did:plc:alice"}`, http.StatusOK},
+		{"Already removed", http.MethodDelete, `{"authToken":"` + alice + `","did":"did:plc:alice"}`, http.StatusNotFound},
+		{"Wrong method", http.MethodPatch, `{}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := a.handleEvent(context.Background(), credentialEvent(tt.method, tt.body))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode, resp.Body)
+		})
+	}
+}
+
+func TestCredentialsRequireVerification(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected PDS call to %s", r.URL.Path)
+	})
+	a.credentials = credential.NewMemoryStore()
+
+	resp, err := a.handleEvent(context.Background(), credentialEvent(http.MethodPut, `{"authToken":"token","did":"did:plc:alice","appPassword":"aaaa-bbbb-cccc-dddd"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,95 @@
+package draft
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ShareFrame/posting-service/jsonstore"
+	"github.com/ShareFrame/posting-service/models"
+)
+
+var (
+	ErrNotFound = errors.New("draft not found")
+	ErrExists   = errors.New("draft already exists")
+)
+
+// Draft is a post saved before it is ready to publish. The ID is a TID and
+// becomes the record key when the draft is published.
+type Draft struct {
+	ID        string                    `json:"id"`
+	DID       string                    `json:"did"`
+	Post      models.ShareFrameFeedPost `json:"post"`
+	ReplyTo   string                    `json:"replyTo,omitempty"`
+	QuoteOf   string                    `json:"quoteOf,omitempty"`
+	CreatedAt time.Time                 `json:"createdAt"`
+	UpdatedAt time.Time                 `json:"updatedAt"`
+}
+
+// Store persists drafts per DID. Update loads a draft, applies fn to it and
+// saves the result atomically; when fn returns an error nothing is saved and
+// that error is returned.
+type Store interface {
+	Create(ctx context.Context, d Draft) error
+	Get(ctx context.Context, did, id string) (*Draft, error)
+	List(ctx context.Context, did string) ([]Draft, error)
+	Update(ctx context.Context, did, id string, fn func(*Draft) error) (*Draft, error)
+	Delete(ctx context.Context, did, id string) error
+}
+
+var config = jsonstore.Config{Name: "draft", ErrNotFound: ErrNotFound, ErrExists: ErrExists}
+
+func NewMemoryStore() Store {
+	return &store{drafts: jsonstore.NewMemory[Draft](config)}
+}
+
+func NewFileStore(dir string) (Store, error) {
+	drafts, err := jsonstore.NewFile[Draft](dir, config)
+	if err != nil {
+		return nil, err
+	}
+	return &store{drafts: drafts}, nil
+}
+
+type store struct {
+	drafts jsonstore.Store[Draft]
+}
+
+func (s *store) Create(ctx context.Context, d Draft) error {
+	return s.drafts.Create(ctx, draftKey(d.DID, d.ID), d)
+}
+
+func (s *store) Get(ctx context.Context, did, id string) (*Draft, error) {
+	return s.drafts.Get(ctx, draftKey(did, id))
+}
+
+func (s *store) List(ctx context.Context, did string) ([]Draft, error) {
+	all, err := s.drafts.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var drafts []Draft
+	for _, d := range all {
+		if d.DID == did {
+			drafts = append(drafts, d)
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt)
+	})
+	return drafts, nil
+}
+
+func (s *store) Update(ctx context.Context, did, id string, fn func(*Draft) error) (*Draft, error) {
+	return s.drafts.Update(ctx, draftKey(did, id), fn)
+}
+
+func (s *store) Delete(ctx context.Context, did, id string) error {
+	return s.drafts.Delete(ctx, draftKey(did, id))
+}
+
+func draftKey(did, id string) string {
+	return did + "/" + id
+}
@@ -0,0 +1,53 @@
+package draft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	draft := func(did, id string, updatedAt time.Time) Draft {
+		return Draft{
+			ID:        id,
+			DID:       did,
+			Post:      models.ShareFrameFeedPost{Text: "Half a thought"},
+			CreatedAt: start,
+			UpdatedAt: updatedAt,
+		}
+	}
+
+	store := NewMemoryStore()
+	require.NoError(t, store.Create(ctx, draft("did:plc:alice", "a", start.Add(time.Hour))))
+	require.NoError(t, store.Create(ctx, draft("did:plc:alice", "b", start.Add(2*time.Hour))))
+	require.NoError(t, store.Create(ctx, draft("did:plc:bob", "c", start)))
+	assert.ErrorIs(t, store.Create(ctx, draft("did:plc:alice", "a", start)), ErrExists)
+
+	t.Run("Drafts are per DID", func(t *testing.T) {
+		_, err := store.Get(ctx, "did:plc:bob", "a")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, store.Delete(ctx, "did:plc:bob", "a"), ErrNotFound)
+
+		got, err := store.Get(ctx, "did:plc:alice", "a")
+		require.NoError(t, err)
+		assert.Equal(t, "Half a thought", got.Post.Text)
+	})
+
+	t.Run("List has the most recently updated first", func(t *testing.T) {
+		drafts, err := store.List(ctx, "did:plc:alice")
+		require.NoError(t, err)
+		require.Len(t, drafts, 2)
+		assert.Equal(t, "b", drafts[0].ID)
+		assert.Equal(t, "a", drafts[1].ID)
+
+		drafts, err = store.List(ctx, "did:plc:carol")
+		require.NoError(t, err)
+		assert.Empty(t, drafts)
+	})
+}
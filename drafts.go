@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ShareFrame/posting-service/draft"
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+type DraftInput struct {
+	AuthToken    string               `json:"authToken"`
+	RefreshToken string               `json:"refreshToken,omitempty"`
+	DID          string               `json:"did"`
+	ID           string               `json:"id,omitempty"`
+	ReplyTo      string               `json:"replyTo,omitempty"`
+	QuoteOf      string               `json:"quoteOf,omitempty"`
+	Media        []models.MediaUpload `json:"media,omitempty"`
+
+	models.PostInput
+}
+
+func (in DraftInput) draft() draft.Draft {
+	return draft.Draft{
+		ID:      in.ID,
+		DID:     in.DID,
+		Post:    in.PostInput.Record(),
+		ReplyTo: in.ReplyTo,
+		QuoteOf: in.QuoteOf,
+	}
+}
+
+func isDraftsPath(path string) bool {
+	_, ok := draftAction(path)
+	return ok
+}
+
+// draftAction splits /drafts, /drafts/list and /drafts/publish. Listing is a
+// POST because, like every other route, it takes the caller's tokens in the
+// body.
+func draftAction(path string) (string, bool) {
+	path = strings.TrimRight(path, "/")
+	for _, action := range []string{"list", "publish"} {
+		if strings.HasSuffix(path, "/drafts/"+action) {
+			return action, true
+		}
+	}
+	return "", strings.HasSuffix(path, "/drafts")
+}
+
+func (a *app) routeDrafts(ctx context.Context, req apiRequest) apiResponse {
+	var call func(context.Context, apiRequest) (interface{}, error)
+	status := http.StatusOK
+
+	action, _ := draftAction(req.Path)
+	switch action + " " + req.Method {
+	case "list " + http.MethodPost:
+		call = a.listDrafts
+	case "publish " + http.MethodPost:
+		call, status = a.publishDraft, http.StatusCreated
+	case " " + http.MethodPost:
+		call, status = a.createDraft, http.StatusCreated
+	case " " + http.MethodPut, " " + http.MethodPatch:
+		call = a.updateDraft
+	case " " + http.MethodDelete:
+		call = a.deleteDraft
+	}
+	if call == nil {
+		return errorResponse(&handler.Error{
+			Code: handler.CodeMethodNotAllowed,
+			Err:  errors.New("method not allowed: " + req.Method),
+		})
+	}
+
+	result, err := call(ctx, req)
+	return respond(status, result, err)
+}
+
+func (a *app) createDraft(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.draftInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.CreateDraft(ctx, a.drafts, input.draft())
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("CreateDraft failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (a *app) updateDraft(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.draftInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.UpdateDraft(ctx, a.drafts, input.draft())
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("UpdateDraft failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (a *app) listDrafts(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.draftInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.ListDrafts(ctx, a.drafts, input.DID)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("ListDrafts failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (a *app) deleteDraft(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.draftInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handler.DeleteDraft(ctx, a.drafts, input.DID, input.ID); err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("DeleteDraft failed")
+		return nil, err
+	}
+
+	return map[string]string{"id": input.ID}, nil
+}
+
+func (a *app) publishDraft(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.draftInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := models.RequestPayload{
+		AuthToken:    input.AuthToken,
+		RefreshToken: input.RefreshToken,
+		DID:          input.DID,
+		Media:        input.Media,
+	}
+
+	resp, err := handler.PublishDraft(ctx, a.client, a.policy, a.drafts, a.expiries, payload, input.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("PublishDraft failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Like schedules, drafts live only in this service, so the caller's token
+// has to be verified here rather than by the PDS.
+func (a *app) draftInput(ctx context.Context, req apiRequest) (*DraftInput, error) {
+	if a.drafts == nil {
+		return nil, &handler.Error{Code: handler.CodeNotFound, Err: errors.New("drafts are not enabled")}
+	}
+
+	var input DraftInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, err
+	}
+	if err := handler.RejectServerOwnedFields([]byte(req.Body)); err != nil {
+		return nil, err
+	}
+	if err := a.authorize(ctx, input.AuthToken, input.DID); err != nil {
+		return nil, err
+	}
+
+	return &input, nil
+}
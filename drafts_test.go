@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/auth"
+	"github.com/ShareFrame/posting-service/draft"
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func draftEvent(method, path, body string) json.RawMessage {
+	return mustMarshal(map[string]interface{}{
+		"httpMethod": method,
+		"path":       path,
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       body,
+	})
+}
+
+func TestDraftLifecycle(t *testing.T) {
+	secret := []byte("test-secret")
+	alice := hs256Token(secret, "did:plc:alice", time.Now().Add(time.Hour))
+	mallory := hs256Token(secret, "did:plc:mallory", time.Now().Add(time.Hour))
+
+	var published []string
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RKey   string                    `json:"rkey"`
+			Record models.ShareFrameFeedPost `json:"record"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		published = append(published, req.Record.Text)
+		w.Write([]byte(`{"uri":"at://did:plc:alice/social.shareframe.feed.post/` + req.RKey + `","cid":"bafyre123456"}`))
+	})
+	a.drafts = draft.NewMemoryStore()
+	a.verifier = newTestVerifier(t, auth.Config{Keys: auth.StaticKeys{"": secret}, Audience: "did:web:pds.test"})
+
+	call := func(method, path string, body map[string]interface{}) (int, string) {
+		resp, err := a.handleEvent(context.Background(), draftEvent(method, path, string(mustMarshal(body))))
+		require.NoError(t, err)
+		return resp.StatusCode, resp.Body
+	}
+
+	status, body := call(http.MethodPost, "/drafts", map[string]interface{}{"authToken": alice, "did": "did:plc:alice", "text": "Half a thought"})
+	require.Equal(t, http.StatusCreated, status, body)
+	var created draft.Draft
+	require.NoError(t, json.Unmarshal([]byte(body), &created))
+	assert.NotEmpty(t, created.ID)
+
+	status, _ = call(http.MethodPost, "/drafts/list", map[string]interface{}{"authToken": mallory, "did": "did:plc:alice"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, body = call(http.MethodPatch, "/drafts", map[string]interface{}{"authToken": alice, "did": "did:plc:alice", "id": created.ID, "text": "A whole thought"})
+	require.Equal(t, http.StatusOK, status, body)
+
+	status, body = call(http.MethodPost, "/drafts/list", map[string]interface{}{"authToken": alice, "did": "did:plc:alice"})
+	require.Equal(t, http.StatusOK, status, body)
+	var list handler.DraftList
+	require.NoError(t, json.Unmarshal([]byte(body), &list))
+	require.Len(t, list.Drafts, 1)
+	assert.Equal(t, "A whole thought", list.Drafts[0].Post.Text)
+	assert.Empty(t, published)
+
+	status, body = call(http.MethodPost, "/drafts/publish", map[string]interface{}{"authToken": alice, "did": "did:plc:alice", "id": created.ID})
+	require.Equal(t, http.StatusCreated, status, body)
+	var resp models.PostResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+	assert.Equal(t, "at://did:plc:alice/social.shareframe.feed.post/"+created.ID, resp.URI)
+	assert.Equal(t, []string{"A whole thought"}, published)
+
+	status, _ = call(http.MethodDelete, "/drafts", map[string]interface{}{"authToken": alice, "did": "did:plc:alice", "id": created.ID})
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = call(http.MethodGet, "/drafts/publish", map[string]interface{}{"authToken": alice, "did": "did:plc:alice"})
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+}
+
+func TestDraftsRequireVerification(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected PDS call to %s", r.URL.Path)
+	})
+	a.drafts = draft.NewMemoryStore()
+
+	resp, err := a.handleEvent(context.Background(), draftEvent(http.MethodPost, "/drafts", `{"authToken":"token","did":"did:plc:alice","text":"Hi"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,108 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ShareFrame/posting-service/jsonstore"
+	"github.com/ShareFrame/posting-service/lease"
+)
+
+var ErrNotFound = errors.New("story expiry not found")
+
+type Status = lease.Status
+
+const (
+	StatusPending = lease.StatusPending
+	StatusDeleted = Status("deleted")
+	StatusFailed  = lease.StatusFailed
+)
+
+// Event is one entry in a story's audit trail.
+type Event struct {
+	At     time.Time `json:"at"`
+	Action string    `json:"action"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Entry tracks a story that must be deleted from its author's repo once
+// ExpiresAt passes. It holds no tokens: the sweeper deletes the story with
+// the app password its author registered.
+type Entry struct {
+	URI       string    `json:"uri"`
+	DID       string    `json:"did"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	History   []Event   `json:"history,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	lease.State
+}
+
+// Store persists story expiries keyed by AT-URI. Put replaces any entry for
+// the same URI. Update loads an entry, applies fn to it and saves the result
+// atomically; when fn returns an error nothing is saved and that error is
+// returned.
+type Store interface {
+	Put(ctx context.Context, entry Entry) error
+	Get(ctx context.Context, uri string) (*Entry, error)
+	List(ctx context.Context, did string) ([]Entry, error)
+	Due(ctx context.Context, now time.Time, limit int) ([]Entry, error)
+	Update(ctx context.Context, uri string, fn func(*Entry) error) (*Entry, error)
+}
+
+var config = jsonstore.Config{Name: "story expiry", ErrNotFound: ErrNotFound}
+
+func NewMemoryStore() Store {
+	return &store{entries: jsonstore.NewMemory[Entry](config)}
+}
+
+func NewFileStore(dir string) (Store, error) {
+	entries, err := jsonstore.NewFile[Entry](dir, config)
+	if err != nil {
+		return nil, err
+	}
+	return &store{entries: entries}, nil
+}
+
+type store struct {
+	entries jsonstore.Store[Entry]
+}
+
+func (s *store) Put(ctx context.Context, entry Entry) error {
+	return s.entries.Put(ctx, entry.URI, entry)
+}
+
+func (s *store) Get(ctx context.Context, uri string) (*Entry, error) {
+	return s.entries.Get(ctx, uri)
+}
+
+func (s *store) List(ctx context.Context, did string) ([]Entry, error) {
+	all, err := s.entries.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, e := range all {
+		if e.DID == did {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ExpiresAt.Before(entries[j].ExpiresAt)
+	})
+	return entries, nil
+}
+
+func (s *store) Due(ctx context.Context, now time.Time, limit int) ([]Entry, error) {
+	all, err := s.entries.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lease.Due(all, now, limit), nil
+}
+
+func (s *store) Update(ctx context.Context, uri string, fn func(*Entry) error) (*Entry, error) {
+	return s.entries.Update(ctx, uri, fn)
+}
@@ -0,0 +1,63 @@
+package expiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(did, rkey string, expiresAt time.Time) Entry {
+		return Entry{
+			URI:       "at://" + did + "/social.shareframe.feed.post/" + rkey,
+			DID:       did,
+			ExpiresAt: expiresAt,
+			History:   []Event{{At: now, Action: "tracked"}},
+			State:     lease.State{Status: StatusPending, NextAttemptAt: expiresAt},
+		}
+	}
+
+	store := NewMemoryStore()
+	require.NoError(t, store.Put(ctx, entry("did:plc:alice", "later", now.Add(time.Hour))))
+	require.NoError(t, store.Put(ctx, entry("did:plc:alice", "second", now.Add(-time.Minute))))
+	require.NoError(t, store.Put(ctx, entry("did:plc:bob", "first", now.Add(-time.Hour))))
+	deleted := entry("did:plc:bob", "deleted", now.Add(-time.Hour))
+	deleted.Status = StatusDeleted
+	require.NoError(t, store.Put(ctx, deleted))
+
+	t.Run("Put replaces", func(t *testing.T) {
+		e := entry("did:plc:carol", "a", now.Add(time.Hour))
+		require.NoError(t, store.Put(ctx, e))
+		e.History = append(e.History, Event{At: now, Action: "retracked"})
+		require.NoError(t, store.Put(ctx, e))
+
+		got, err := store.Get(ctx, e.URI)
+		require.NoError(t, err)
+		assert.Len(t, got.History, 2)
+
+		_, err = store.Get(ctx, "at://did:plc:alice/social.shareframe.feed.post/missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("List is per DID and ordered by expiry", func(t *testing.T) {
+		alice, err := store.List(ctx, "did:plc:alice")
+		require.NoError(t, err)
+		require.Len(t, alice, 2)
+		assert.Equal(t, "at://did:plc:alice/social.shareframe.feed.post/second", alice[0].URI)
+		assert.Equal(t, "at://did:plc:alice/social.shareframe.feed.post/later", alice[1].URI)
+	})
+
+	t.Run("Due skips deleted and unexpired stories", func(t *testing.T) {
+		due, err := store.Due(ctx, now, 0)
+		require.NoError(t, err)
+		require.Len(t, due, 2)
+		assert.Equal(t, "at://did:plc:bob/social.shareframe.feed.post/first", due[0].URI)
+		assert.Equal(t, "at://did:plc:alice/social.shareframe.feed.post/second", due[1].URI)
+	})
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/auth"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/schedule"
+	"github.com/sirupsen/logrus"
+)
+
+var errNotParked = errors.New("entry is not waiting for authorization")
+
+// RegisterCredential saves the app password the service publishes scheduled
+// posts and deletes expired stories with. The password is checked against the
+// PDS first, and the account password is refused: it cannot be revoked
+// without changing the password.
+func RegisterCredential(ctx context.Context, client atproto.ATProtoClient, credentials credential.Store, schedules schedule.Store, expiries expiry.Store, did, appPassword string) (*models.CredentialResponse, error) {
+	if did == "" || appPassword == "" {
+		return nil, newError(CodeInvalidRequest, errors.New("invalid request: missing 'did' or 'appPassword'"))
+	}
+
+	session, err := client.CreateSession(ctx, did, appPassword)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Failed to create session with app password")
+		return nil, upstreamError("checking app password failed", err)
+	}
+	if session.DID != did {
+		return nil, newError(CodeForbidden, fmt.Errorf("app password belongs to %s, not %s", session.DID, did))
+	}
+	if claims, err := auth.ParseUnverified(session.AccessJwt); err != nil || !claims.HasScope(auth.AppPasswordScopes) {
+		return nil, newError(CodeInvalidRequest, errors.New("invalid request: 'appPassword' must be an app password, not the account password"))
+	}
+
+	now := time.Now()
+	c := credential.Credential{DID: did, AppPassword: appPassword, CreatedAt: now, UpdatedAt: now}
+	if existing, err := credentials.Get(ctx, did); err == nil {
+		c.CreatedAt = existing.CreatedAt
+	}
+	if err := credentials.Put(ctx, c); err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Failed to save credential")
+		return nil, newError(CodeInternal, fmt.Errorf("saving credential failed: %w", err))
+	}
+
+	resumed := 0
+	if schedules != nil {
+		resumed = resumeEntries(ctx, schedules.List, func(ctx context.Context, e schedule.Entry, fn func(*schedule.Entry) error) error {
+			_, err := schedules.Update(ctx, e.DID, e.ID, fn)
+			return err
+		}, did, now)
+	}
+	if expiries != nil {
+		resumed += resumeEntries(ctx, expiries.List, func(ctx context.Context, e expiry.Entry, fn func(*expiry.Entry) error) error {
+			_, err := expiries.Update(ctx, e.URI, fn)
+			return err
+		}, did, now)
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": did, "resumed": resumed}).Info("Registered credential")
+	return credentialResponse(c), nil
+}
+
+func RemoveCredential(ctx context.Context, credentials credential.Store, did string) error {
+	if did == "" {
+		return newError(CodeInvalidRequest, errors.New("invalid request: missing 'did'"))
+	}
+
+	err := credentials.Delete(ctx, did)
+	if errors.Is(err, credential.ErrNotFound) {
+		return newError(CodeNotFound, fmt.Errorf("no credential registered for %s", did))
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Failed to delete credential")
+		return newError(CodeInternal, fmt.Errorf("deleting credential failed: %w", err))
+	}
+
+	logrus.WithField("DID", did).Info("Removed credential")
+	return nil
+}
+
+// resumeEntries makes the DID's entries that were parked for want of
+// authorization due again, and returns how many it resumed.
+func resumeEntries[T any, P lease.Entry[T]](ctx context.Context, list func(context.Context, string) ([]T, error), update func(context.Context, T, func(*T) error) error, did string, now time.Time) int {
+	entries, err := list(ctx, did)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Failed to list entries to resume")
+		return 0
+	}
+
+	resumed := 0
+	for _, e := range entries {
+		if P(&e).Lease().Status != lease.StatusNeedsAuth {
+			continue
+		}
+		err := update(ctx, e, func(e *T) error {
+			if !P(e).Lease().Resume(now) {
+				return errNotParked
+			}
+			return nil
+		})
+		switch {
+		case err == nil:
+			resumed++
+		case !errors.Is(err, errNotParked):
+			logrus.WithError(err).WithField("DID", did).Error("Failed to resume entry")
+		}
+	}
+	return resumed
+}
+
+// serviceSessions logs in with each author's registered app password at most
+// once per run. The sessions are never saved.
+type serviceSessions struct {
+	client      atproto.ATProtoClient
+	credentials credential.Store
+	sessions    map[string]*models.Session
+}
+
+func newServiceSessions(client atproto.ATProtoClient, credentials credential.Store) *serviceSessions {
+	return &serviceSessions{client: client, credentials: credentials, sessions: map[string]*models.Session{}}
+}
+
+// get returns a CodeUnauthorized error when the author has no usable app
+// password, so the entry waits for them to register one.
+func (s *serviceSessions) get(ctx context.Context, did string) (*models.Session, error) {
+	if session, ok := s.sessions[did]; ok {
+		return session, nil
+	}
+
+	c, err := s.credentials.Get(ctx, did)
+	if errors.Is(err, credential.ErrNotFound) {
+		return nil, newError(CodeUnauthorized, fmt.Errorf("no app password registered for %s", did))
+	}
+	if err != nil {
+		return nil, newError(CodeInternal, fmt.Errorf("loading credential failed: %w", err))
+	}
+
+	session, err := s.client.CreateSession(ctx, did, c.AppPassword)
+	if err != nil {
+		return nil, upstreamError("creating session with app password failed", err)
+	}
+	s.sessions[did] = session
+	return session, nil
+}
+
+func credentialResponse(c credential.Credential) *models.CredentialResponse {
+	return &models.CredentialResponse{
+		DID:       c.DID,
+		CreatedAt: c.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: c.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
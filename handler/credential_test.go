@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// registeredCredentials has an app password registered for did:plc:alice.
+func registeredCredentials(t *testing.T) credential.Store {
+	store := credential.NewMemoryStore()
+	require.NoError(t, store.Put(context.Background(), credential.Credential{DID: "did:plc:alice", AppPassword: "app-password"}))
+	return store
+}
+
+func accessJwt(scope string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"ES256K"}`)) + "." + encode([]byte(`{"sub":"did:plc:alice","scope":"`+scope+`"}`)) + ".c2ln"
+}
+
+func TestRegisterCredential(t *testing.T) {
+	tests := []struct {
+		name         string
+		did          string
+		appPassword  string
+		setupMock    func(m *MockATProtoClient)
+		expectedCode ErrorCode
+		expectedErr  string
+	}{
+		{
+			name:        "Registered",
+			did:         "did:plc:alice",
+			appPassword: "aaaa-bbbb-cccc-dddd",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "aaaa-bbbb-cccc-dddd").
+					Return(&models.Session{DID: "did:plc:alice", AccessJwt: accessJwt("com.atproto.appPass")}, nil).Once()
+			},
+		},
+		{
+			name:        "Account password is refused",
+			did:         "did:plc:alice",
+			appPassword: "hunter2",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "hunter2").
+					Return(&models.Session{DID: "did:plc:alice", AccessJwt: accessJwt("com.atproto.access")}, nil).Once()
+			},
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "must be an app password",
+		},
+		{
+			name:        "Wrong password",
+			did:         "did:plc:alice",
+			appPassword: "aaaa-bbbb-cccc-dddd",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "aaaa-bbbb-cccc-dddd").
+					Return(nil, &atproto.XRPCError{StatusCode: 401, ErrorName: "AuthenticationRequired"}).Once()
+			},
+			expectedCode: CodeUnauthorized,
+			expectedErr:  "checking app password failed",
+		},
+		{
+			name:        "Another account's password",
+			did:         "did:plc:alice",
+			appPassword: "aaaa-bbbb-cccc-dddd",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "aaaa-bbbb-cccc-dddd").
+					Return(&models.Session{DID: "did:plc:mallory", AccessJwt: accessJwt("com.atproto.appPass")}, nil).Once()
+			},
+			expectedCode: CodeForbidden,
+			expectedErr:  "belongs to did:plc:mallory",
+		},
+		{
+			name:         "Missing password",
+			did:          "did:plc:alice",
+			setupMock:    func(m *MockATProtoClient) {},
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "missing 'did' or 'appPassword'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			credentials := credential.NewMemoryStore()
+			mockClient := new(MockATProtoClient)
+			tt.setupMock(mockClient)
+
+			resp, err := RegisterCredential(ctx, mockClient, credentials, nil, nil, tt.did, tt.appPassword)
+
+			mockClient.AssertExpectations(t)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+				_, err := credentials.Get(ctx, tt.did)
+				assert.ErrorIs(t, err, credential.ErrNotFound)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.did, resp.DID)
+			saved, err := credentials.Get(ctx, tt.did)
+			require.NoError(t, err)
+			assert.Equal(t, tt.appPassword, saved.AppPassword)
+		})
+	}
+}
+
+func TestRegisterCredentialResumesParkedEntries(t *testing.T) {
+	ctx := context.Background()
+	schedules := schedule.NewMemoryStore()
+	add := func(did, id string, status schedule.Status) {
+		require.NoError(t, schedules.Create(ctx, schedule.Entry{
+			ID:    id,
+			DID:   did,
+			State: lease.State{Status: status, NextAttemptAt: time.Now().Add(-time.Hour), Attempts: 1},
+		}))
+	}
+	add("did:plc:alice", "parked", lease.StatusNeedsAuth)
+	add("did:plc:alice", "failed", schedule.StatusFailed)
+	add("did:plc:bob", "parked", lease.StatusNeedsAuth)
+	expiries := expiry.NewMemoryStore()
+	require.NoError(t, expiries.Put(ctx, expiry.Entry{
+		URI:   "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a",
+		DID:   "did:plc:alice",
+		State: lease.State{Status: lease.StatusNeedsAuth, Attempts: 1},
+	}))
+
+	mockClient := new(MockATProtoClient)
+	mockClient.On("CreateSession", mock.Anything, "did:plc:alice", "aaaa-bbbb-cccc-dddd").
+		Return(&models.Session{DID: "did:plc:alice", AccessJwt: accessJwt("com.atproto.appPass")}, nil).Once()
+
+	_, err := RegisterCredential(ctx, mockClient, credential.NewMemoryStore(), schedules, expiries, "did:plc:alice", "aaaa-bbbb-cccc-dddd")
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		did, id string
+		status  schedule.Status
+	}{
+		{"did:plc:alice", "parked", schedule.StatusPending},
+		{"did:plc:alice", "failed", schedule.StatusFailed},
+		{"did:plc:bob", "parked", lease.StatusNeedsAuth},
+	} {
+		entry, err := schedules.Get(ctx, tt.did, tt.id)
+		require.NoError(t, err)
+		assert.Equal(t, tt.status, entry.Status, tt.did+"/"+tt.id)
+	}
+
+	story, err := expiries.Get(ctx, "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a")
+	require.NoError(t, err)
+	assert.Equal(t, expiry.StatusPending, story.Status)
+}
+
+func TestRemoveCredential(t *testing.T) {
+	ctx := context.Background()
+	credentials := registeredCredentials(t)
+
+	require.NoError(t, RemoveCredential(ctx, credentials, "did:plc:alice"))
+	_, err := credentials.Get(ctx, "did:plc:alice")
+	assert.ErrorIs(t, err, credential.ErrNotFound)
+
+	err = RemoveCredential(ctx, credentials, "did:plc:alice")
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+}
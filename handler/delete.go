@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+func DeleteHandler(ctx context.Context, client atproto.ATProtoClient, request models.DeleteRequestPayload) (*models.DeleteResponse, error) {
+	if request.AuthToken == "" || request.DID == "" || request.URI == "" {
+		err := errors.New("invalid request: missing 'authToken', 'did' or 'uri'")
+		logrus.Error(err)
+		return nil, newError(CodeInvalidRequest, err)
+	}
+
+	uri, err := parseOwnedPostURI(request.DID, request.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	session := newSession(client, request.DID, request.AuthToken, request.RefreshToken)
+
+	err = session.Do(ctx, func(accessJwt string) error {
+		return client.DeletePost(ctx, accessJwt, request.DID, uri.RKey)
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("URI", request.URI).Error("Failed to delete post")
+		return nil, upstreamError("deleting post failed", err)
+	}
+
+	return &models.DeleteResponse{URI: uri.String(), Session: refreshedSession(session)}, nil
+}
+
+func parseOwnedPostURI(did, rawURI string) (atproto.ATURI, error) {
+	uri, err := atproto.ParseATURI(rawURI)
+	if err != nil {
+		logrus.WithError(err).Error("Invalid post URI")
+		return atproto.ATURI{}, newError(CodeInvalidRequest, fmt.Errorf("invalid request: %w", err))
+	}
+
+	if uri.DID != did {
+		err := errors.New("forbidden: post does not belong to the caller")
+		logrus.WithFields(logrus.Fields{
+			"DID": did,
+			"URI": rawURI,
+		}).Error(err)
+		return atproto.ATURI{}, newError(CodeForbidden, err)
+	}
+
+	if uri.Collection != models.FeedPostNSID {
+		err := fmt.Errorf("invalid request: %s is not a ShareFrame post", rawURI)
+		logrus.Error(err)
+		return atproto.ATURI{}, newError(CodeInvalidRequest, err)
+	}
+
+	return uri, nil
+}
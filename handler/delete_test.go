@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockATProtoClient) DeletePost(ctx context.Context, authToken, did, rkey string) error {
+	args := m.Called(ctx, authToken, did, rkey)
+	return args.Error(0)
+}
+
+func TestDeleteHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    models.DeleteRequestPayload
+		mockErr    error
+		mockCalled bool
+		expectErr  bool
+	}{
+		{
+			name: "Owner deletes their post",
+			request: models.DeleteRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a",
+			},
+			mockCalled: true,
+		},
+		{
+			name: "Post owned by another DID",
+			request: models.DeleteRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:mallory",
+				URI:       "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a",
+			},
+			expectErr: true,
+		},
+		{
+			name: "Record from another collection",
+			request: models.DeleteRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       "at://did:plc:alice/app.bsky.actor.profile/self",
+			},
+			expectErr: true,
+		},
+		{
+			name: "Malformed URI",
+			request: models.DeleteRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       "https://shareframe.social/post/3jzfcijpj2z2a",
+			},
+			expectErr: true,
+		},
+		{
+			name: "Missing AuthToken",
+			request: models.DeleteRequestPayload{
+				DID: "did:plc:alice",
+				URI: "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a",
+			},
+			expectErr: true,
+		},
+		{
+			name: "PDS failure",
+			request: models.DeleteRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a",
+			},
+			mockErr:    errors.New("deleteRecord failed"),
+			mockCalled: true,
+			expectErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAtproto := new(MockATProtoClient)
+			if tt.mockCalled {
+				mockAtproto.On("DeletePost", mock.Anything, tt.request.AuthToken, tt.request.DID, "3jzfcijpj2z2a").
+					Return(tt.mockErr).Once()
+			}
+
+			resp, err := DeleteHandler(context.Background(), mockAtproto, tt.request)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.request.URI, resp.URI)
+			}
+			mockAtproto.AssertExpectations(t)
+		})
+	}
+}
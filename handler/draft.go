@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/atproto/tid"
+	"github.com/ShareFrame/posting-service/draft"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+type DraftList struct {
+	Drafts []draft.Draft `json:"drafts"`
+}
+
+func CreateDraft(ctx context.Context, store draft.Store, d draft.Draft) (*draft.Draft, error) {
+	if err := validateDraft(d); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	d.ID = tid.Now()
+	d.CreatedAt = now
+	d.UpdatedAt = now
+
+	if err := store.Create(ctx, d); err != nil {
+		logrus.WithError(err).WithField("DID", d.DID).Error("Failed to save draft")
+		return nil, newError(CodeInternal, fmt.Errorf("saving draft failed: %w", err))
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": d.DID, "id": d.ID}).Info("Saved draft")
+	return &d, nil
+}
+
+// UpdateDraft replaces the content of an existing draft with that of d.
+func UpdateDraft(ctx context.Context, store draft.Store, d draft.Draft) (*draft.Draft, error) {
+	if err := validateDraft(d); err != nil {
+		return nil, err
+	}
+	if d.ID == "" {
+		return nil, newError(CodeInvalidRequest, errors.New("invalid request: missing 'id'"))
+	}
+
+	updated, err := store.Update(ctx, d.DID, d.ID, func(existing *draft.Draft) error {
+		existing.Post = d.Post
+		existing.ReplyTo = d.ReplyTo
+		existing.QuoteOf = d.QuoteOf
+		existing.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		return nil, draftStoreError(err, d.DID, d.ID, "updating draft failed")
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": d.DID, "id": d.ID}).Info("Updated draft")
+	return updated, nil
+}
+
+func ListDrafts(ctx context.Context, store draft.Store, did string) (*DraftList, error) {
+	if did == "" {
+		return nil, newError(CodeInvalidRequest, errors.New("invalid request: missing 'did'"))
+	}
+
+	drafts, err := store.List(ctx, did)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Failed to list drafts")
+		return nil, newError(CodeInternal, fmt.Errorf("listing drafts failed: %w", err))
+	}
+	if drafts == nil {
+		drafts = []draft.Draft{}
+	}
+	return &DraftList{Drafts: drafts}, nil
+}
+
+func DeleteDraft(ctx context.Context, store draft.Store, did, id string) error {
+	if did == "" || id == "" {
+		return newError(CodeInvalidRequest, errors.New("invalid request: missing 'did' or 'id'"))
+	}
+
+	if err := store.Delete(ctx, did, id); err != nil {
+		return draftStoreError(err, did, id, "deleting draft failed")
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": did, "id": id}).Info("Deleted draft")
+	return nil
+}
+
+// PublishDraft posts a draft with full validation, using the tokens and media
+// from request, and deletes the draft once the post is written. The draft ID
+// is used as the record key, so publishing the same draft twice cannot
+// create two posts.
+func PublishDraft(ctx context.Context, client atproto.ATProtoClient, policy Policy, store draft.Store, expiries expiry.Store, request models.RequestPayload, id string) (*models.PostResponse, error) {
+	if request.DID == "" || id == "" {
+		return nil, newError(CodeInvalidRequest, errors.New("invalid request: missing 'did' or 'id'"))
+	}
+
+	d, err := store.Get(ctx, request.DID, id)
+	if err != nil {
+		return nil, draftStoreError(err, request.DID, id, "loading draft failed")
+	}
+
+	request.Post = d.Post
+	request.Post.NSID = models.FeedPostNSID
+	request.Post.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	request.ReplyTo = d.ReplyTo
+	request.QuoteOf = d.QuoteOf
+	request.RKey = d.ID
+
+	resp, err := PostHandler(ctx, client, policy, request)
+	if err != nil {
+		return nil, err
+	}
+	TrackStoryExpiry(ctx, expiries, request, resp)
+
+	if err := store.Delete(ctx, request.DID, id); err != nil && !errors.Is(err, draft.ErrNotFound) {
+		logrus.WithError(err).WithFields(logrus.Fields{"DID": request.DID, "id": id}).Warn("Failed to delete published draft")
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": request.DID, "id": id, "uri": resp.URI}).Info("Published draft")
+	return resp, nil
+}
+
+func validateDraft(d draft.Draft) error {
+	if d.DID == "" {
+		return newError(CodeInvalidRequest, errors.New("invalid request: missing 'did'"))
+	}
+
+	if err := validatePost(d.Post, lenientValidation); err != nil {
+		logrus.WithError(err).WithField("DID", d.DID).Error("Draft validation failed")
+		return newError(CodeInvalidRequest, fmt.Errorf("invalid draft: %w", err))
+	}
+
+	if d.ReplyTo != "" {
+		if _, err := atproto.ParseATURI(d.ReplyTo); err != nil {
+			return newError(CodeInvalidRequest, fmt.Errorf("invalid draft: replyTo: %w", err))
+		}
+	}
+	if d.QuoteOf != "" {
+		if _, err := atproto.ParseATURI(d.QuoteOf); err != nil {
+			return newError(CodeInvalidRequest, fmt.Errorf("invalid draft: quoteOf: %w", err))
+		}
+	}
+	return nil
+}
+
+func draftStoreError(err error, did, id, message string) error {
+	if errors.Is(err, draft.ErrNotFound) {
+		return newError(CodeNotFound, fmt.Errorf("draft %s not found", id))
+	}
+	logrus.WithError(err).WithFields(logrus.Fields{"DID": did, "id": id}).Error("Draft store failed")
+	return newError(CodeInternal, fmt.Errorf("%s: %w", message, err))
+}
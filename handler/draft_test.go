@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/atproto/tid"
+	"github.com/ShareFrame/posting-service/draft"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePostLenient(t *testing.T) {
+	tests := []struct {
+		name        string
+		post        models.ShareFrameFeedPost
+		expectedErr string
+	}{
+		{
+			name: "Empty draft",
+			post: models.ShareFrameFeedPost{},
+		},
+		{
+			name: "Text over the post limit",
+			post: models.ShareFrameFeedPost{Text: strings.Repeat("a", 500)},
+		},
+		{
+			name:        "Text over the draft limit",
+			post:        models.ShareFrameFeedPost{Text: strings.Repeat("a", maxDraftTextLength+1)},
+			expectedErr: "text: must be at most 30000 bytes (got 30001)",
+		},
+		{
+			name:        "Invalid image format",
+			post:        models.ShareFrameFeedPost{ImageUris: []string{"https://example.com/doc.pdf"}},
+			expectedErr: "invalid image format: .pdf",
+		},
+		{
+			name:        "Invalid language",
+			post:        models.ShareFrameFeedPost{Language: "not a language"},
+			expectedErr: "language",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePost(tt.post, lenientValidation)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDraftLifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := draft.NewMemoryStore()
+
+	created, err := CreateDraft(ctx, store, draft.Draft{DID: "did:plc:alice", Post: models.ShareFrameFeedPost{Text: strings.Repeat("a", 500)}})
+	require.NoError(t, err)
+	assert.True(t, tid.Valid(created.ID))
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+
+	_, err = CreateDraft(ctx, store, draft.Draft{DID: "did:plc:alice", ReplyTo: "https://example.com/post"})
+	assert.Equal(t, CodeInvalidRequest, ErrorCodeOf(err))
+	assert.ErrorContains(t, err, "replyTo")
+
+	updated, err := UpdateDraft(ctx, store, draft.Draft{ID: created.ID, DID: "did:plc:alice", Post: models.ShareFrameFeedPost{Text: "Shorter"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Shorter", updated.Post.Text)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+
+	_, err = UpdateDraft(ctx, store, draft.Draft{ID: created.ID, DID: "did:plc:bob"})
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+
+	list, err := ListDrafts(ctx, store, "did:plc:alice")
+	require.NoError(t, err)
+	require.Len(t, list.Drafts, 1)
+	assert.Equal(t, "Shorter", list.Drafts[0].Post.Text)
+
+	list, err = ListDrafts(ctx, store, "did:plc:bob")
+	require.NoError(t, err)
+	assert.NotNil(t, list.Drafts)
+	assert.Empty(t, list.Drafts)
+
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(DeleteDraft(ctx, store, "did:plc:bob", created.ID)))
+	require.NoError(t, DeleteDraft(ctx, store, "did:plc:alice", created.ID))
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(DeleteDraft(ctx, store, "did:plc:alice", created.ID)))
+}
+
+func TestPublishDraft(t *testing.T) {
+	tests := []struct {
+		name          string
+		post          models.ShareFrameFeedPost
+		id            string
+		setupMock     func(m *MockATProtoClient, id string)
+		expectedCode  ErrorCode
+		expectedErr   string
+		expectDeleted bool
+		expectTracked bool
+	}{
+		{
+			name: "Published",
+			post: models.ShareFrameFeedPost{Text: "Ready now"},
+			setupMock: func(m *MockATProtoClient, id string) {
+				m.On("PostToFeed", mock.Anything, mock.MatchedBy(func(p models.ShareFrameFeedPost) bool {
+					return p.Text == "Ready now" && p.NSID == models.FeedPostNSID && p.CreatedAt != ""
+				}), "access", "did:plc:alice", id).
+					Return(&models.PostResponse{URI: "at://did:plc:alice/social.shareframe.feed.post/" + id, CID: "bafyre123456"}, nil).Once()
+			},
+			expectDeleted: true,
+		},
+		{
+			name: "Story is tracked for expiry",
+			post: models.ShareFrameFeedPost{Text: "Gone soon", IsStory: true},
+			setupMock: func(m *MockATProtoClient, id string) {
+				m.On("PostToFeed", mock.Anything, mock.Anything, "access", "did:plc:alice", id).
+					Return(&models.PostResponse{URI: "at://did:plc:alice/social.shareframe.feed.post/" + id, CID: "bafyre123456"}, nil).Once()
+			},
+			expectDeleted: true,
+			expectTracked: true,
+		},
+		{
+			name:         "Fails full validation",
+			post:         models.ShareFrameFeedPost{Text: strings.Repeat("a", 301)},
+			setupMock:    func(m *MockATProtoClient, id string) {},
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "must be at most 300 characters",
+		},
+		{
+			name: "Upstream failure keeps the draft",
+			post: models.ShareFrameFeedPost{Text: "Ready now"},
+			setupMock: func(m *MockATProtoClient, id string) {
+				m.On("PostToFeed", mock.Anything, mock.Anything, "access", "did:plc:alice", id).
+					Return(nil, &atproto.XRPCError{StatusCode: 502}).Once()
+			},
+			expectedCode: CodeUpstreamUnavailable,
+			expectedErr:  "posting to feed failed",
+		},
+		{
+			name:         "Unknown draft",
+			post:         models.ShareFrameFeedPost{Text: "Ready now"},
+			id:           "3kq2ve7ruvk2z",
+			setupMock:    func(m *MockATProtoClient, id string) {},
+			expectedCode: CodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := draft.NewMemoryStore()
+			expiries := expiry.NewMemoryStore()
+			created, err := CreateDraft(ctx, store, draft.Draft{DID: "did:plc:alice", Post: tt.post})
+			require.NoError(t, err)
+
+			id := created.ID
+			if tt.id != "" {
+				id = tt.id
+			}
+
+			mockClient := new(MockATProtoClient)
+			tt.setupMock(mockClient, id)
+
+			request := models.RequestPayload{AuthToken: "access", DID: "did:plc:alice"}
+			resp, err := PublishDraft(ctx, mockClient, Policy{}, store, expiries, request, id)
+
+			mockClient.AssertExpectations(t)
+			if tt.expectedCode != "" {
+				assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+				assert.ErrorContains(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "bafyre123456", resp.CID)
+			}
+
+			_, err = store.Get(ctx, "did:plc:alice", created.ID)
+			if tt.expectDeleted {
+				assert.ErrorIs(t, err, draft.ErrNotFound)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			tracked, err := expiries.List(ctx, "did:plc:alice")
+			require.NoError(t, err)
+			if tt.expectTracked {
+				require.Len(t, tracked, 1)
+				assert.WithinDuration(t, time.Now().Add(24*time.Hour), tracked[0].ExpiresAt, time.Minute)
+			} else {
+				assert.Empty(t, tracked)
+			}
+		})
+	}
+}
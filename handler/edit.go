@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/richtext"
+	"github.com/sirupsen/logrus"
+)
+
+func EditHandler(ctx context.Context, client atproto.ATProtoClient, policy Policy, request models.EditRequestPayload) (*models.PostResponse, error) {
+	if request.AuthToken == "" || request.DID == "" || request.URI == "" {
+		err := errors.New("invalid request: missing 'authToken', 'did' or 'uri'")
+		logrus.Error(err)
+		return nil, newError(CodeInvalidRequest, err)
+	}
+
+	uri, err := parseOwnedPostURI(request.DID, request.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Media != nil {
+		if err := validateMedia(*request.Media); err != nil {
+			logrus.WithError(err).WithField("DID", request.DID).Error("Media validation failed")
+			return nil, newError(CodeInvalidRequest, fmt.Errorf("invalid media: %w", err))
+		}
+	}
+
+	session := newSession(client, request.DID, request.AuthToken, request.RefreshToken)
+
+	var current *models.GetRecordResponse
+	err = session.Do(ctx, func(accessJwt string) error {
+		var err error
+		current, err = client.GetRecord(ctx, accessJwt, request.DID, uri.RKey)
+		return err
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("URI", request.URI).Error("Failed to fetch post")
+		return nil, upstreamError("fetching post failed", err)
+	}
+
+	post := current.Value
+	post.EditHistory = append(post.EditHistory, models.EditHistoryEntry{
+		Text:      post.Text,
+		ImageUris: post.ImageUris,
+		VideoUris: post.VideoUris,
+		Images:    post.Images,
+		Videos:    post.Videos,
+		EditedAt:  time.Now().UTC().Format(time.RFC3339),
+	})
+
+	if request.Text != nil {
+		post.Text = *request.Text
+		post.Facets = richtext.DetectFacets(ctx, post.Text, client)
+	}
+	if request.ImageUris != nil {
+		post.ImageUris = *request.ImageUris
+	}
+	if request.VideoUris != nil {
+		post.VideoUris = *request.VideoUris
+	}
+
+	if err := validatePost(post, strictValidation); err != nil {
+		logrus.WithError(err).WithField("URI", request.URI).Error("Validation failed")
+		return nil, newError(CodeInvalidRequest, fmt.Errorf("invalid post: %w", err))
+	}
+	if request.ImageUris != nil || request.VideoUris != nil {
+		if err := verifyMediaURIs(ctx, policy.MediaVerifier, post); err != nil {
+			return nil, err
+		}
+	}
+
+	if request.Media != nil {
+		post.Images, post.Videos = nil, nil
+		if err := uploadMedia(ctx, client, session, request.DID, *request.Media, &post); err != nil {
+			logrus.WithError(err).WithField("DID", request.DID).Error("Failed to upload media")
+			return nil, upstreamError("uploading media failed", err)
+		}
+	}
+
+	var postResponse *models.PostResponse
+	err = session.Do(ctx, func(accessJwt string) error {
+		var err error
+		postResponse, err = client.PutRecord(ctx, accessJwt, request.DID, uri.RKey, post, current.CID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, atproto.ErrInvalidSwap) {
+			logrus.WithField("URI", request.URI).Warn("Post was edited concurrently")
+			return nil, newError(CodeConflict, fmt.Errorf("conflict: post changed since it was read: %w", err))
+		}
+		logrus.WithError(err).WithField("URI", request.URI).Error("Failed to update post")
+		return nil, upstreamError("updating post failed", err)
+	}
+
+	if postResponse == nil {
+		logrus.Error("EditHandler returned nil postResponse with no error")
+		return nil, newError(CodeUpstreamError, errors.New("no response returned from ATProto"))
+	}
+
+	postResponse.Session = refreshedSession(session)
+	return postResponse, nil
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockATProtoClient) GetRecord(ctx context.Context, authToken, did, rkey string) (*models.GetRecordResponse, error) {
+	args := m.Called(ctx, authToken, did, rkey)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.GetRecordResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockATProtoClient) PutRecord(ctx context.Context, authToken, did, rkey string, post models.ShareFrameFeedPost, swapRecord string) (*models.PostResponse, error) {
+	args := m.Called(ctx, authToken, did, rkey, post, swapRecord)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.PostResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestEditHandler(t *testing.T) {
+	const uri = "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a"
+	createdAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	newText := "Hello, edited world!"
+	tooLong := string(make([]byte, 301))
+
+	current := &models.GetRecordResponse{
+		URI: uri,
+		CID: "bafyreiold",
+		Value: models.ShareFrameFeedPost{
+			NSID:      "social.shareframe.feed.post",
+			Text:      "Hello, world!",
+			ImageUris: []string{"https://example.com/photo.jpg"},
+			CreatedAt: createdAt,
+			SourceApp: "ShareFrame",
+			EditHistory: []models.EditHistoryEntry{
+				{Text: "Helo, world!", EditedAt: createdAt},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		request     models.EditRequestPayload
+		getErr      error
+		putErr      error
+		expectGet   bool
+		expectPut   bool
+		expectErr   string
+		checkPostFn func(*testing.T, models.ShareFrameFeedPost)
+	}{
+		{
+			name: "Text edit appends previous version to history",
+			request: models.EditRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       uri,
+				Text:      &newText,
+			},
+			expectGet: true,
+			expectPut: true,
+			checkPostFn: func(t *testing.T, p models.ShareFrameFeedPost) {
+				assert.Equal(t, newText, p.Text)
+				assert.Equal(t, []string{"https://example.com/photo.jpg"}, p.ImageUris)
+				assert.Equal(t, createdAt, p.CreatedAt)
+				if assert.Len(t, p.EditHistory, 2) {
+					entry := p.EditHistory[1]
+					assert.Equal(t, "Hello, world!", entry.Text)
+					assert.Equal(t, []string{"https://example.com/photo.jpg"}, entry.ImageUris)
+					editedAt, err := time.Parse(time.RFC3339, entry.EditedAt)
+					assert.NoError(t, err)
+					assert.WithinDuration(t, time.Now(), editedAt, time.Minute)
+				}
+			},
+		},
+		{
+			name: "Concurrent edit fails on swap",
+			request: models.EditRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       uri,
+				Text:      &newText,
+			},
+			putErr:    &atproto.XRPCError{NSID: "com.atproto.repo.putRecord", StatusCode: 400, ErrorName: "InvalidSwap"},
+			expectGet: true,
+			expectPut: true,
+			expectErr: "conflict",
+		},
+		{
+			name: "Post not found",
+			request: models.EditRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       uri,
+				Text:      &newText,
+			},
+			getErr:    &atproto.XRPCError{NSID: "com.atproto.repo.getRecord", StatusCode: 400, ErrorName: "RecordNotFound"},
+			expectGet: true,
+			expectErr: "fetching post failed",
+		},
+		{
+			name: "Edited text fails validation",
+			request: models.EditRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				URI:       uri,
+				Text:      &tooLong,
+			},
+			expectGet: true,
+			expectErr: "invalid post",
+		},
+		{
+			name: "Editing another user's post",
+			request: models.EditRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:mallory",
+				URI:       uri,
+				Text:      &newText,
+			},
+			expectErr: "forbidden",
+		},
+		{
+			name: "Missing URI",
+			request: models.EditRequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				Text:      &newText,
+			},
+			expectErr: "invalid request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAtproto := new(MockATProtoClient)
+			if tt.expectGet {
+				var record *models.GetRecordResponse
+				if tt.getErr == nil {
+					copied := *current
+					record = &copied
+				}
+				mockAtproto.On("GetRecord", mock.Anything, "valid_token", "did:plc:alice", "3jzfcijpj2z2a").
+					Return(record, tt.getErr).Once()
+			}
+
+			var capturedPost models.ShareFrameFeedPost
+			if tt.expectPut {
+				var resp *models.PostResponse
+				if tt.putErr == nil {
+					resp = &models.PostResponse{URI: uri, CID: "bafyreinew"}
+				}
+				mockAtproto.On("PutRecord", mock.Anything, "valid_token", "did:plc:alice", "3jzfcijpj2z2a", mock.Anything, "bafyreiold").
+					Run(func(args mock.Arguments) {
+						capturedPost = args.Get(4).(models.ShareFrameFeedPost)
+					}).
+					Return(resp, tt.putErr).Once()
+			}
+
+			resp, err := EditHandler(context.Background(), mockAtproto, Policy{}, tt.request)
+
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "bafyreinew", resp.CID)
+				tt.checkPostFn(t, capturedPost)
+			}
+			mockAtproto.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEditHandlerReplacesMedia(t *testing.T) {
+	const uri = "at://did:plc:alice/social.shareframe.feed.post/3jzfcijpj2z2a"
+	oldBlob := models.Blob{Type: "blob", Ref: models.BlobLink{Link: "bafkreiold"}, MimeType: "image/png", Size: 10}
+	newBlob := &models.Blob{Type: "blob", Ref: models.BlobLink{Link: "bafkreinew"}, MimeType: "image/jpeg", Size: 20}
+	media := []models.MediaUpload{{Data: []byte("jpeg"), MimeType: "image/jpeg"}}
+
+	mockAtproto := new(MockATProtoClient)
+	mockAtproto.On("GetRecord", mock.Anything, "valid_token", "did:plc:alice", "3jzfcijpj2z2a").
+		Return(&models.GetRecordResponse{
+			URI: uri,
+			CID: "bafyreiold",
+			Value: models.ShareFrameFeedPost{
+				NSID:      "social.shareframe.feed.post",
+				Images:    []models.Blob{oldBlob},
+				CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			},
+		}, nil).Once()
+	mockAtproto.On("UploadBlob", mock.Anything, "valid_token", "did:plc:alice", media[0]).
+		Return(newBlob, nil).Once()
+
+	var capturedPost models.ShareFrameFeedPost
+	mockAtproto.On("PutRecord", mock.Anything, "valid_token", "did:plc:alice", "3jzfcijpj2z2a", mock.Anything, "bafyreiold").
+		Run(func(args mock.Arguments) {
+			capturedPost = args.Get(4).(models.ShareFrameFeedPost)
+		}).
+		Return(&models.PostResponse{URI: uri, CID: "bafyreinew"}, nil).Once()
+
+	_, err := EditHandler(context.Background(), mockAtproto, Policy{}, models.EditRequestPayload{
+		AuthToken: "valid_token",
+		DID:       "did:plc:alice",
+		URI:       uri,
+		Media:     &media,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Blob{*newBlob}, capturedPost.Images)
+	if assert.Len(t, capturedPost.EditHistory, 1) {
+		assert.Equal(t, []models.Blob{oldBlob}, capturedPost.EditHistory[0].Images)
+	}
+	mockAtproto.AssertExpectations(t)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/media"
+)
+
+type ErrorCode string
+
+const (
+	CodeInvalidRequest      ErrorCode = "invalid_request"
+	CodeUnauthorized        ErrorCode = "unauthorized"
+	CodeForbidden           ErrorCode = "forbidden"
+	CodeNotFound            ErrorCode = "not_found"
+	CodeMethodNotAllowed    ErrorCode = "method_not_allowed"
+	CodeConflict            ErrorCode = "conflict"
+	CodeRateLimited         ErrorCode = "rate_limited"
+	CodeUpstreamUnavailable ErrorCode = "upstream_unavailable"
+	CodeUpstreamError       ErrorCode = "upstream_error"
+	CodeTimeout             ErrorCode = "timeout"
+	CodeCanceled            ErrorCode = "canceled"
+	CodeInternal            ErrorCode = "internal_error"
+)
+
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func ErrorCodeOf(err error) ErrorCode {
+	var handlerErr *Error
+	if errors.As(err, &handlerErr) {
+		return handlerErr.Code
+	}
+	return CodeInternal
+}
+
+func newError(code ErrorCode, err error) error {
+	return &Error{Code: code, Err: err}
+}
+
+func upstreamError(message string, err error) error {
+	return &Error{Code: upstreamErrorCode(err), Err: fmt.Errorf("%s: %w", message, err)}
+}
+
+func upstreamErrorCode(err error) ErrorCode {
+	var handlerErr *Error
+	if errors.As(err, &handlerErr) {
+		return handlerErr.Code
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CodeTimeout
+	}
+	if errors.Is(err, atproto.ErrCanceled) || errors.Is(err, context.Canceled) {
+		return CodeCanceled
+	}
+	if isRejectedMediaSource(err) {
+		return CodeInvalidRequest
+	}
+
+	var xrpcErr *atproto.XRPCError
+	if !errors.As(err, &xrpcErr) {
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return CodeUpstreamUnavailable
+		}
+		return CodeUpstreamError
+	}
+
+	switch {
+	case xrpcErr.IsAuthError():
+		return CodeUnauthorized
+	case xrpcErr.IsRateLimited():
+		return CodeRateLimited
+	case xrpcErr.ErrorName == "InvalidSwap":
+		return CodeConflict
+	case xrpcErr.ErrorName == "RecordNotFound" || xrpcErr.StatusCode == http.StatusNotFound:
+		return CodeNotFound
+	case xrpcErr.StatusCode == http.StatusForbidden:
+		return CodeForbidden
+	case xrpcErr.StatusCode == http.StatusBadGateway,
+		xrpcErr.StatusCode == http.StatusServiceUnavailable,
+		xrpcErr.StatusCode == http.StatusGatewayTimeout:
+		return CodeUpstreamUnavailable
+	case xrpcErr.IsServerError():
+		return CodeUpstreamError
+	case xrpcErr.StatusCode == http.StatusBadRequest:
+		return CodeInvalidRequest
+	}
+	return CodeUpstreamError
+}
+
+// isRejectedMediaSource reports whether a media source URL was refused for
+// what it is or serves, rather than because it could not be reached.
+func isRejectedMediaSource(err error) bool {
+	for _, target := range []error{media.ErrInvalidURL, media.ErrBlockedAddress, media.ErrUnsupportedType, atproto.ErrBlobTooLarge} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
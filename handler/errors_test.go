@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpstreamErrorCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorCode
+	}{
+		{"Invalid token", &atproto.XRPCError{StatusCode: http.StatusBadRequest, ErrorName: "InvalidToken"}, CodeUnauthorized},
+		{"Expired token", &atproto.XRPCError{StatusCode: http.StatusBadRequest, ErrorName: "ExpiredToken"}, CodeUnauthorized},
+		{"Unauthorized status", &atproto.XRPCError{StatusCode: http.StatusUnauthorized}, CodeUnauthorized},
+		{"Rate limit", &atproto.XRPCError{StatusCode: http.StatusTooManyRequests, ErrorName: "RateLimitExceeded"}, CodeRateLimited},
+		{"Invalid swap", &atproto.XRPCError{StatusCode: http.StatusBadRequest, ErrorName: "InvalidSwap"}, CodeConflict},
+		{"Record not found", &atproto.XRPCError{StatusCode: http.StatusBadRequest, ErrorName: "RecordNotFound"}, CodeNotFound},
+		{"Forbidden", &atproto.XRPCError{StatusCode: http.StatusForbidden}, CodeForbidden},
+		{"Invalid request", &atproto.XRPCError{StatusCode: http.StatusBadRequest, ErrorName: "InvalidRequest"}, CodeInvalidRequest},
+		{"Bad gateway", &atproto.XRPCError{StatusCode: http.StatusBadGateway}, CodeUpstreamUnavailable},
+		{"Internal server error", &atproto.XRPCError{StatusCode: http.StatusInternalServerError, ErrorName: "InternalServerError"}, CodeUpstreamError},
+		{"Wrapped XRPC error", fmt.Errorf("media 0: %w", &atproto.XRPCError{StatusCode: http.StatusTooManyRequests}), CodeRateLimited},
+		{"Deadline exceeded", fmt.Errorf("createRecord: %w: %w", atproto.ErrCanceled, context.DeadlineExceeded), CodeTimeout},
+		{"Canceled", fmt.Errorf("createRecord: %w: %w", atproto.ErrCanceled, context.Canceled), CodeCanceled},
+		{"Network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, CodeUpstreamUnavailable},
+		{"Private media source", fmt.Errorf("failed to fetch media: %w", media.ErrBlockedAddress), CodeInvalidRequest},
+		{"Media source is not media", fmt.Errorf("failed to fetch media: %w", media.ErrUnsupportedType), CodeInvalidRequest},
+		{"Media source too large", atproto.ErrBlobTooLarge, CodeInvalidRequest},
+		{"Media source unavailable", fmt.Errorf("failed to fetch media: %w", media.ErrUnavailable), CodeUpstreamError},
+		{"Handler error keeps its code", newError(CodeInvalidRequest, errors.New("bad media")), CodeInvalidRequest},
+		{"Unknown error", errors.New("failed to parse response"), CodeUpstreamError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, upstreamErrorCode(tt.err))
+		})
+	}
+}
+
+func TestPostHandlerErrorCodes(t *testing.T) {
+	validPost := models.ShareFrameFeedPost{
+		NSID:      "social.shareframe.feed.post",
+		Text:      "Hello",
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	tests := []struct {
+		name     string
+		request  models.RequestPayload
+		mockErr  error
+		expected ErrorCode
+	}{
+		{
+			name:     "Missing DID",
+			request:  models.RequestPayload{AuthToken: "valid_token", Post: validPost},
+			expected: CodeInvalidRequest,
+		},
+		{
+			name:     "Invalid post",
+			request:  models.RequestPayload{AuthToken: "valid_token", DID: "did:example:123", Post: models.ShareFrameFeedPost{NSID: "wrong"}},
+			expected: CodeInvalidRequest,
+		},
+		{
+			name:     "PDS rejects token",
+			request:  models.RequestPayload{AuthToken: "valid_token", DID: "did:example:123", Post: validPost},
+			mockErr:  &atproto.XRPCError{StatusCode: http.StatusUnauthorized, ErrorName: "InvalidToken"},
+			expected: CodeUnauthorized,
+		},
+		{
+			name:     "PDS rate limits",
+			request:  models.RequestPayload{AuthToken: "valid_token", DID: "did:example:123", Post: validPost},
+			mockErr:  &atproto.XRPCError{StatusCode: http.StatusTooManyRequests, ErrorName: "RateLimitExceeded"},
+			expected: CodeRateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAtproto := new(MockATProtoClient)
+			if tt.mockErr != nil {
+				mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:example:123", mock.Anything).Return(nil, tt.mockErr).Once()
+			}
+
+			_, err := PostHandler(context.Background(), mockAtproto, Policy{}, tt.request)
+
+			assert.Equal(t, tt.expected, ErrorCodeOf(err))
+			if tt.mockErr != nil {
+				var xrpcErr *atproto.XRPCError
+				assert.True(t, errors.As(err, &xrpcErr))
+			}
+			mockAtproto.AssertExpectations(t)
+		})
+	}
+}
+
+func TestErrorCodeOfPlainError(t *testing.T) {
+	assert.Equal(t, CodeInternal, ErrorCodeOf(errors.New("boom")))
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultMaxStoryLifetime = 7 * 24 * time.Hour
+
+	sweepBatchSize   = 100
+	maxSweepAttempts = 10
+	sweepRetryDelay  = time.Minute
+	sweepLease       = 5 * time.Minute
+)
+
+type SweepResult struct {
+	Deleted   int `json:"deleted"`
+	Retrying  int `json:"retrying"`
+	NeedsAuth int `json:"needsAuth"`
+	Failed    int `json:"failed"`
+}
+
+func validateExpiresAt(value string, now time.Time, maxLifetime time.Duration) error {
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return errors.New("expiresAt: must be an RFC 3339 datetime")
+	}
+	if !expiresAt.After(now) {
+		return errors.New("expiresAt: must be in the future")
+	}
+	if expiresAt.After(now.Add(maxLifetime)) {
+		return fmt.Errorf("expiresAt: must be no more than %s from now", maxLifetime)
+	}
+	return nil
+}
+
+func TrackStoryExpiry(ctx context.Context, store expiry.Store, request models.RequestPayload, resp *models.PostResponse) {
+	if store == nil || !request.Post.IsStory || resp.ExpiresAt == "" {
+		return
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, resp.ExpiresAt)
+	if err != nil {
+		logrus.WithError(err).WithField("uri", resp.URI).Error("Failed to parse story expiry")
+		return
+	}
+
+	now := time.Now()
+	err = store.Put(ctx, expiry.Entry{
+		URI:       resp.URI,
+		DID:       request.DID,
+		ExpiresAt: expiresAt,
+		History:   []expiry.Event{{At: now, Action: "tracked"}},
+		CreatedAt: now,
+		State:     lease.State{Status: expiry.StatusPending, NextAttemptAt: expiresAt, UpdatedAt: now},
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("uri", resp.URI).Error("Failed to track story expiry")
+	}
+}
+
+func SweepExpiredStories(ctx context.Context, client atproto.ATProtoClient, store expiry.Store, credentials credential.Store, now time.Time) (*SweepResult, error) {
+	sessions := newServiceSessions(client, credentials)
+	result, err := lease.Run(ctx, lease.Job[expiry.Entry]{
+		Name:        "expired story",
+		BatchSize:   sweepBatchSize,
+		MaxAttempts: maxSweepAttempts,
+		Lease:       sweepLease,
+		RetryDelay:  sweepRetryDelay,
+		Done:        expiry.StatusDeleted,
+		Due:         store.Due,
+		Update: func(ctx context.Context, e expiry.Entry, fn func(*expiry.Entry) error) (*expiry.Entry, error) {
+			return store.Update(ctx, e.URI, fn)
+		},
+		Attempt: func(ctx context.Context, e *expiry.Entry) (func(*expiry.Entry), error) {
+			err := deleteExpiredStory(ctx, client, sessions, e)
+			event := expiry.Event{At: now, Action: "deleted"}
+			if err != nil {
+				event = expiry.Event{At: now, Action: "delete_failed", Error: err.Error()}
+			}
+			return func(e *expiry.Entry) { e.History = append(e.History, event) }, err
+		},
+		Retryable: retryableDispatchError,
+		NeedsAuth: needsAuth,
+		Fields:    expiredStoryFields,
+		Finished: func(e *expiry.Entry, status lease.Status, err error) {
+			log := logrus.WithFields(expiredStoryFields(*e)).WithFields(logrus.Fields{"audit": true, "attempt": e.Attempts, "status": status})
+			if err != nil {
+				log.WithError(err).WithField("code", ErrorCodeOf(err)).Warn("Expired story was not deleted")
+				return
+			}
+			log.Info("Deleted expired story")
+		},
+	}, now)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list expired stories")
+		return nil, fmt.Errorf("listing expired stories failed: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"deleted":   result.Done,
+		"retrying":  result.Retrying,
+		"needsAuth": result.NeedsAuth,
+		"failed":    result.Failed,
+	}).Info("Swept expired stories")
+	return &SweepResult{Deleted: result.Done, Retrying: result.Retrying, NeedsAuth: result.NeedsAuth, Failed: result.Failed}, nil
+}
+
+func expiredStoryFields(e expiry.Entry) logrus.Fields {
+	return logrus.Fields{"DID": e.DID, "uri": e.URI}
+}
+
+// deleteExpiredStory treats a story that is already gone as deleted.
+func deleteExpiredStory(ctx context.Context, client atproto.ATProtoClient, sessions *serviceSessions, entry *expiry.Entry) error {
+	session, err := sessions.get(ctx, entry.DID)
+	if err != nil {
+		return err
+	}
+
+	_, err = DeleteHandler(ctx, client, models.DeleteRequestPayload{
+		AuthToken:    session.AccessJwt,
+		RefreshToken: session.RefreshJwt,
+		DID:          entry.DID,
+		URI:          entry.URI,
+	})
+	if ErrorCodeOf(err) == CodeNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateExpiresAt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		value       string
+		expectedErr string
+	}{
+		{name: "Within the window", value: "2024-05-02T12:00:00Z"},
+		{name: "At the maximum", value: "2024-05-08T12:00:00Z"},
+		{name: "Not a datetime", value: "tomorrow", expectedErr: "must be an RFC 3339 datetime"},
+		{name: "In the past", value: "2024-05-01T11:00:00Z", expectedErr: "must be in the future"},
+		{name: "Now", value: "2024-05-01T12:00:00Z", expectedErr: "must be in the future"},
+		{name: "Too far ahead", value: "2024-05-08T12:00:01Z", expectedErr: "must be no more than 168h0m0s from now"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExpiresAt(tt.value, now, DefaultMaxStoryLifetime)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPostHandlerRejectsInvalidExpiresAt(t *testing.T) {
+	request := scheduledRequest("Gone soon")
+	request.Post.IsStory = true
+	request.Post.ExpiresAt = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	mockClient := new(MockATProtoClient)
+	_, err := PostHandler(context.Background(), mockClient, Policy{}, request)
+
+	assert.ErrorContains(t, err, "expiresAt: must be in the future")
+	assert.Equal(t, CodeInvalidRequest, ErrorCodeOf(err))
+	mockClient.AssertNotCalled(t, "PostToFeed", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPostHandlerUsesPolicyStoryLifetime(t *testing.T) {
+	request := scheduledRequest("Gone soon")
+	request.Post.IsStory = true
+	request.Post.ExpiresAt = time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+
+	mockClient := new(MockATProtoClient)
+	_, err := PostHandler(context.Background(), mockClient, Policy{MaxStoryLifetime: time.Hour}, request)
+
+	assert.ErrorContains(t, err, "expiresAt: must be no more than 1h0m0s from now")
+	assert.Equal(t, CodeInvalidRequest, ErrorCodeOf(err))
+}
+
+func TestTrackStoryExpiry(t *testing.T) {
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name          string
+		isStory       bool
+		expectTracked bool
+	}{
+		{name: "Story", isStory: true, expectTracked: true},
+		{name: "Regular post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := expiry.NewMemoryStore()
+			request := scheduledRequest("Gone soon")
+			request.Post.IsStory = tt.isStory
+			resp := &models.PostResponse{
+				URI:       "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a",
+				ExpiresAt: expiresAt.Format(time.RFC3339),
+				Session:   &models.Session{AccessJwt: "new_access", RefreshJwt: "new_refresh"},
+			}
+
+			TrackStoryExpiry(ctx, store, request, resp)
+
+			entry, err := store.Get(ctx, resp.URI)
+			if !tt.expectTracked {
+				assert.ErrorIs(t, err, expiry.ErrNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, expiry.StatusPending, entry.Status)
+			assert.Equal(t, "did:plc:alice", entry.DID)
+			assert.True(t, expiresAt.Equal(entry.ExpiresAt))
+			assert.True(t, expiresAt.Equal(entry.NextAttemptAt))
+			require.Len(t, entry.History, 1)
+			assert.Equal(t, "tracked", entry.History[0].Action)
+		})
+	}
+}
+
+func TestSweepExpiredStories(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	session := &models.Session{DID: "did:plc:alice", AccessJwt: "service_access", RefreshJwt: "service_refresh"}
+	expiredURI := "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a"
+	laterURI := "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2b"
+
+	tests := []struct {
+		name           string
+		attempts       int
+		unregistered   bool
+		setupMock      func(m *MockATProtoClient)
+		expectedResult SweepResult
+		expectedStatus expiry.Status
+		expectedAction string
+		expectedNext   time.Time
+	}{
+		{
+			name: "Deleted",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").Return(session, nil).Once()
+				m.On("DeletePost", mock.Anything, "service_access", "did:plc:alice", "3kq2ve7ruvk2a").Return(nil).Once()
+			},
+			expectedResult: SweepResult{Deleted: 1},
+			expectedStatus: expiry.StatusDeleted,
+			expectedAction: "deleted",
+		},
+		{
+			name: "Already gone",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").Return(session, nil).Once()
+				m.On("DeletePost", mock.Anything, "service_access", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(&atproto.XRPCError{StatusCode: 400, ErrorName: "RecordNotFound"}).Once()
+			},
+			expectedResult: SweepResult{Deleted: 1},
+			expectedStatus: expiry.StatusDeleted,
+			expectedAction: "deleted",
+		},
+		{
+			name: "Transient failure is retried later",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").Return(session, nil).Once()
+				m.On("DeletePost", mock.Anything, "service_access", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(&atproto.XRPCError{StatusCode: 502}).Once()
+			},
+			expectedResult: SweepResult{Retrying: 1},
+			expectedStatus: expiry.StatusPending,
+			expectedAction: "delete_failed",
+			expectedNext:   now.Add(time.Minute),
+		},
+		{
+			name:     "Giving up after the last attempt",
+			attempts: maxSweepAttempts - 1,
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").Return(session, nil).Once()
+				m.On("DeletePost", mock.Anything, "service_access", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(&atproto.XRPCError{StatusCode: 502}).Once()
+			},
+			expectedResult: SweepResult{Failed: 1},
+			expectedStatus: expiry.StatusFailed,
+			expectedAction: "delete_failed",
+		},
+		{
+			name: "Revoked app password waits for authorization",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").
+					Return(nil, &atproto.XRPCError{StatusCode: 401, ErrorName: "AuthenticationRequired"}).Once()
+			},
+			expectedResult: SweepResult{NeedsAuth: 1},
+			expectedStatus: lease.StatusNeedsAuth,
+			expectedAction: "delete_failed",
+		},
+		{
+			name:           "No app password waits for authorization",
+			unregistered:   true,
+			setupMock:      func(m *MockATProtoClient) {},
+			expectedResult: SweepResult{NeedsAuth: 1},
+			expectedStatus: lease.StatusNeedsAuth,
+			expectedAction: "delete_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := expiry.NewMemoryStore()
+			add := func(uri string, at time.Time) {
+				require.NoError(t, store.Put(ctx, expiry.Entry{
+					URI:       uri,
+					DID:       "did:plc:alice",
+					ExpiresAt: at,
+					History:   []expiry.Event{{At: at.Add(-24 * time.Hour), Action: "tracked"}},
+					State:     lease.State{Status: expiry.StatusPending, NextAttemptAt: at, Attempts: tt.attempts},
+				}))
+			}
+			add(expiredURI, now.Add(-time.Minute))
+			add(laterURI, now.Add(time.Hour))
+
+			credentials := registeredCredentials(t)
+			if tt.unregistered {
+				require.NoError(t, credentials.Delete(ctx, "did:plc:alice"))
+			}
+			mockClient := new(MockATProtoClient)
+			tt.setupMock(mockClient)
+
+			result, err := SweepExpiredStories(ctx, mockClient, store, credentials, now)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, *result)
+			mockClient.AssertExpectations(t)
+
+			entry, err := store.Get(ctx, expiredURI)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, entry.Status)
+			assert.Equal(t, tt.attempts+1, entry.Attempts)
+			require.Len(t, entry.History, 2)
+			assert.Equal(t, tt.expectedAction, entry.History[1].Action)
+			assert.Equal(t, now, entry.History[1].At)
+			if !tt.expectedNext.IsZero() {
+				assert.Equal(t, tt.expectedNext, entry.NextAttemptAt)
+			}
+
+			later, err := store.Get(ctx, laterURI)
+			require.NoError(t, err)
+			assert.Equal(t, expiry.StatusPending, later.Status)
+			assert.Equal(t, tt.attempts, later.Attempts)
+		})
+	}
+}
@@ -9,7 +9,11 @@ import (
 	"time"
 
 	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/atproto/tid"
+	"github.com/ShareFrame/posting-service/lexicon"
+	"github.com/ShareFrame/posting-service/media"
 	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/richtext"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,13 +24,91 @@ var (
 	allowedVideoExts = map[string]struct{}{
 		".mp4": {}, ".mov": {}, ".webm": {},
 	}
+	allowedImageTypes = map[string]struct{}{
+		"image/jpeg": {}, "image/png": {}, "image/gif": {}, "image/heic": {}, "image/heif": {},
+	}
+	allowedVideoTypes = map[string]struct{}{
+		"video/mp4": {}, "video/quicktime": {}, "video/webm": {},
+	}
 )
 
-func PostHandler(ctx context.Context, client atproto.ATProtoClient, request models.RequestPayload) (*models.PostResponse, error) {
+// Policy holds the checks a post must pass beyond its lexicon, which the
+// service configures once at startup.
+type Policy struct {
+	// MediaVerifier, when set, fetches every imageUris and videoUris entry to
+	// check it serves media of that kind.
+	MediaVerifier *media.Verifier
+	// MaxStoryLifetime bounds how far ahead expiresAt may be. Defaults to
+	// DefaultMaxStoryLifetime.
+	MaxStoryLifetime time.Duration
+}
+
+func (p Policy) maxStoryLifetime() time.Duration {
+	if p.MaxStoryLifetime <= 0 {
+		return DefaultMaxStoryLifetime
+	}
+	return p.MaxStoryLifetime
+}
+
+func PostHandler(ctx context.Context, client atproto.ATProtoClient, policy Policy, request models.RequestPayload) (*models.PostResponse, error) {
+	if err := preparePost(&request, policy); err != nil {
+		return nil, err
+	}
+	if err := verifyMediaURIs(ctx, policy.MediaVerifier, request.Post); err != nil {
+		return nil, err
+	}
+
+	if request.ReplyTo != "" {
+		reply, err := resolveReply(ctx, client, request.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+		request.Post.Reply = reply
+	}
+
+	if request.QuoteOf != "" {
+		quote, err := resolveQuote(ctx, client, request.QuoteOf)
+		if err != nil {
+			return nil, err
+		}
+		request.Post.QuoteOf = quote
+	}
+
+	request.Post.Facets = richtext.DetectFacets(ctx, request.Post.Text, client)
+
+	session := newSession(client, request.DID, request.AuthToken, request.RefreshToken)
+
+	if err := uploadMedia(ctx, client, session, request.DID, request.Media, &request.Post); err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Failed to upload media")
+		return nil, upstreamError("uploading media failed", err)
+	}
+
+	var postResponse *models.PostResponse
+	err := session.Do(ctx, func(accessJwt string) error {
+		var err error
+		postResponse, err = client.PostToFeed(ctx, request.Post, accessJwt, request.DID, request.RKey)
+		return err
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Failed to post to feed")
+		return nil, upstreamError("posting to feed failed", err)
+	}
+
+	if postResponse == nil {
+		logrus.Error("PostHandler returned nil postResponse with no error")
+		return nil, newError(CodeUpstreamError, errors.New("no response returned from ATProto"))
+	}
+
+	postResponse.ExpiresAt = request.Post.ExpiresAt
+	postResponse.Session = refreshedSession(session)
+	return postResponse, nil
+}
+
+func preparePost(request *models.RequestPayload, policy Policy) error {
 	if request.AuthToken == "" || request.DID == "" {
 		err := errors.New("invalid request: missing 'authToken' or 'did'")
 		logrus.Error(err)
-		return nil, err
+		return newError(CodeInvalidRequest, err)
 	}
 
 	request.Post.SourceApp = "ShareFrame"
@@ -35,32 +117,105 @@ func PostHandler(ctx context.Context, client atproto.ATProtoClient, request mode
 		request.Post.ExpiresAt = time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
 	}
 
-	if err := validatePost(request.Post); err != nil {
+	if err := validatePost(request.Post, strictValidation); err != nil {
 		logrus.WithError(err).WithField("NSID", request.Post.NSID).Error("Validation failed")
-		return nil, fmt.Errorf("invalid post: %w", err)
+		return newError(CodeInvalidRequest, fmt.Errorf("invalid post: %w", err))
 	}
 
-	postResponse, err := client.PostToFeed(request.Post, request.AuthToken, request.DID)
-	if err != nil {
-		logrus.WithError(err).WithField("DID", request.DID).Error("Failed to post to feed")
-		return nil, fmt.Errorf("posting to feed failed: %w", err)
+	if request.Post.ExpiresAt != "" {
+		if err := validateExpiresAt(request.Post.ExpiresAt, time.Now(), policy.maxStoryLifetime()); err != nil {
+			logrus.WithError(err).WithField("DID", request.DID).Error("Validation failed")
+			return newError(CodeInvalidRequest, fmt.Errorf("invalid post: %w", err))
+		}
 	}
 
-	if postResponse == nil {
-		logrus.Error("PostHandler returned nil postResponse with no error")
-		return nil, fmt.Errorf("no response returned from ATProto")
-	}	
+	if err := validateMedia(request.Media); err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Media validation failed")
+		return newError(CodeInvalidRequest, fmt.Errorf("invalid media: %w", err))
+	}
 
-	return postResponse, nil
+	if request.RKey == "" {
+		request.RKey = tid.Now()
+	} else if !tid.Valid(request.RKey) {
+		err := fmt.Errorf("invalid request: rkey %q is not a TID", request.RKey)
+		logrus.WithField("DID", request.DID).Error(err)
+		return newError(CodeInvalidRequest, err)
+	}
+
+	return nil
+}
+
+func newSession(client atproto.ATProtoClient, did, authToken, refreshToken string) *atproto.SessionManager {
+	return atproto.NewSessionManager(client, models.Session{
+		DID:        did,
+		AccessJwt:  authToken,
+		RefreshJwt: refreshToken,
+	})
 }
 
-func validatePost(post models.ShareFrameFeedPost) error {
-	if post.NSID != "social.shareframe.feed.post" {
-		return errors.New("invalid NSID: only social.shareframe.feed.post is allowed")
+func refreshedSession(session *atproto.SessionManager) *models.Session {
+	if !session.Refreshed() {
+		return nil
 	}
+	refreshed := session.Session()
+	return &refreshed
+}
 
-	if len(post.Text) > 300 {
-		return errors.New("post text must be 300 characters or fewer")
+func uploadMedia(ctx context.Context, client atproto.ATProtoClient, session *atproto.SessionManager, did string, media []models.MediaUpload, post *models.ShareFrameFeedPost) error {
+	for i, m := range media {
+		var blob *models.Blob
+		err := session.Do(ctx, func(accessJwt string) error {
+			var err error
+			blob, err = client.UploadBlob(ctx, accessJwt, did, m)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("media %d: %w", i, err)
+		}
+
+		switch {
+		case isAllowedType(blob.MimeType, allowedImageTypes):
+			post.Images = append(post.Images, *blob)
+		case isAllowedType(blob.MimeType, allowedVideoTypes):
+			post.Videos = append(post.Videos, *blob)
+		default:
+			return newError(CodeInvalidRequest, fmt.Errorf("media %d: unsupported media type: %s", i, blob.MimeType))
+		}
+	}
+	return nil
+}
+
+type validationMode int
+
+const (
+	strictValidation validationMode = iota
+
+	// lenientValidation is for drafts: the text may be empty or over the
+	// length limit and createdAt/nsid may be missing, since all of that is
+	// fixed or checked again at publish time.
+	lenientValidation
+)
+
+// maxDraftTextLength bounds what a draft can store when the lexicon limit
+// does not apply.
+const maxDraftTextLength = 30000
+
+func validatePost(post models.ShareFrameFeedPost, mode validationMode) error {
+	if mode == lenientValidation {
+		if len(post.Text) > maxDraftTextLength {
+			return fmt.Errorf("text: must be at most %d bytes (got %d)", maxDraftTextLength, len(post.Text))
+		}
+		post.Text = ""
+		if post.NSID == "" {
+			post.NSID = models.FeedPostNSID
+		}
+		if post.CreatedAt == "" {
+			post.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		}
+	}
+
+	if err := lexicon.ValidateRecord(models.FeedPostNSID, post); err != nil {
+		return err
 	}
 
 	for _, uri := range post.ImageUris {
@@ -75,24 +230,28 @@ func validatePost(post models.ShareFrameFeedPost) error {
 		}
 	}
 
-	if !isRFC3339(post.CreatedAt) {
-		return errors.New("invalid datetime format for createdAt")
-	}
+	return nil
+}
 
-	if post.ExpiresAt != "" && !isRFC3339(post.ExpiresAt) {
-		return errors.New("invalid datetime format for expiresAt")
+func validateMedia(media []models.MediaUpload) error {
+	for i, m := range media {
+		if len(m.Data) == 0 && m.SourceURL == "" {
+			return fmt.Errorf("media %d: either data or sourceUrl is required", i)
+		}
+		if m.MimeType != "" && !isAllowedType(m.MimeType, allowedImageTypes) && !isAllowedType(m.MimeType, allowedVideoTypes) {
+			return fmt.Errorf("media %d: unsupported media type: %s", i, m.MimeType)
+		}
 	}
-
 	return nil
 }
 
+func isAllowedType(mimeType string, allowed map[string]struct{}) bool {
+	_, ok := allowed[strings.ToLower(mimeType)]
+	return ok
+}
+
 func isValidExtension(uri string, allowed map[string]struct{}) bool {
 	ext := strings.ToLower(filepath.Ext(uri))
 	_, ok := allowed[ext]
 	return ok
 }
-
-func isRFC3339(s string) bool {
-	_, err := time.Parse(time.RFC3339, s)
-	return err == nil
-}
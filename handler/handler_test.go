@@ -3,9 +3,13 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/atproto/tid"
 	"github.com/ShareFrame/posting-service/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -15,14 +19,43 @@ type MockATProtoClient struct {
 	mock.Mock
 }
 
-func (m *MockATProtoClient) PostToFeed(post models.ShareFrameFeedPost, authToken, did string) (*models.PostResponse, error) {
-	args := m.Called(post, authToken, did)
+func (m *MockATProtoClient) PostToFeed(ctx context.Context, post models.ShareFrameFeedPost, authToken, did, rkey string) (*models.PostResponse, error) {
+	args := m.Called(ctx, post, authToken, did, rkey)
 	if args.Get(0) != nil {
 		return args.Get(0).(*models.PostResponse), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
+func (m *MockATProtoClient) UploadBlob(ctx context.Context, authToken, did string, media models.MediaUpload) (*models.Blob, error) {
+	args := m.Called(ctx, authToken, did, media)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Blob), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockATProtoClient) CreateSession(ctx context.Context, identifier, password string) (*models.Session, error) {
+	args := m.Called(ctx, identifier, password)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Session), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockATProtoClient) RefreshSession(ctx context.Context, did, refreshJwt string) (*models.Session, error) {
+	args := m.Called(ctx, did, refreshJwt)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Session), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockATProtoClient) ResolveHandle(ctx context.Context, handle string) (string, error) {
+	args := m.Called(ctx, handle)
+	return args.String(0), args.Error(1)
+}
+
 func TestPostHandler(t *testing.T) {
 	mockAtproto := new(MockATProtoClient)
 
@@ -37,6 +70,7 @@ func TestPostHandler(t *testing.T) {
 		expectResp  *models.PostResponse
 		mockCalled  bool
 		checkPostFn func(models.ShareFrameFeedPost)
+		checkRespFn func(*models.PostResponse)
 	}{
 		{
 			name: "Valid post request",
@@ -88,6 +122,11 @@ func TestPostHandler(t *testing.T) {
 			expectErr:  false,
 			expectResp: &models.PostResponse{URI: "dummy", CID: "c", Commit: models.Commit{}, ValidationStatus: "ok"},
 			mockCalled: true,
+			checkRespFn: func(resp *models.PostResponse) {
+				expiresAt, err := time.Parse(time.RFC3339, resp.ExpiresAt)
+				assert.NoError(t, err)
+				assert.WithinDuration(t, now.Add(24*time.Hour), expiresAt, time.Minute)
+			},
 			checkPostFn: func(p models.ShareFrameFeedPost) {
 				assert.Equal(t, "ShareFrame", p.SourceApp)
 				assert.NotEmpty(t, p.ExpiresAt)
@@ -149,9 +188,9 @@ func TestPostHandler(t *testing.T) {
 
 			if tt.mockCalled {
 				var capturedPost models.ShareFrameFeedPost
-				mockAtproto.On("PostToFeed", mock.Anything, tt.request.AuthToken, tt.request.DID).
+				mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, tt.request.AuthToken, tt.request.DID, mock.Anything).
 					Run(func(args mock.Arguments) {
-						capturedPost = args.Get(0).(models.ShareFrameFeedPost)
+						capturedPost = args.Get(1).(models.ShareFrameFeedPost)
 					}).
 					Return(tt.mockResp, tt.mockErr).Once()
 
@@ -162,13 +201,17 @@ func TestPostHandler(t *testing.T) {
 				}()
 			}
 
-			resp, err := PostHandler(ctx, mockAtproto, tt.request)
+			resp, err := PostHandler(ctx, mockAtproto, Policy{}, tt.request)
 
 			if tt.expectErr {
 				assert.Error(t, err)
 				assert.Nil(t, resp)
 			} else {
 				assert.NoError(t, err)
+				if tt.checkRespFn != nil {
+					tt.checkRespFn(resp)
+					resp.ExpiresAt = ""
+				}
 				assert.Equal(t, tt.expectResp, resp)
 			}
 
@@ -179,6 +222,271 @@ func TestPostHandler(t *testing.T) {
 	}
 }
 
+func TestPostHandlerUploadsMedia(t *testing.T) {
+	now := time.Now().UTC()
+	imageBlob := &models.Blob{
+		Type:     "blob",
+		Ref:      models.BlobLink{Link: "bafkreiimage"},
+		MimeType: "image/jpeg",
+		Size:     1024,
+	}
+	videoBlob := &models.Blob{
+		Type:     "blob",
+		Ref:      models.BlobLink{Link: "bafkreivideo"},
+		MimeType: "video/mp4",
+		Size:     4096,
+	}
+
+	tests := []struct {
+		name        string
+		media       []models.MediaUpload
+		blobs       []*models.Blob
+		uploadErr   error
+		expectErr   bool
+		expectPost  bool
+		checkPostFn func(*testing.T, models.ShareFrameFeedPost)
+	}{
+		{
+			name: "Image and video are embedded as blobs",
+			media: []models.MediaUpload{
+				{Data: []byte("jpeg"), MimeType: "image/jpeg"},
+				{SourceURL: "https://cdn.example.com/clip.mp4"},
+			},
+			blobs:      []*models.Blob{imageBlob, videoBlob},
+			expectPost: true,
+			checkPostFn: func(t *testing.T, p models.ShareFrameFeedPost) {
+				assert.Equal(t, []models.Blob{*imageBlob}, p.Images)
+				assert.Equal(t, []models.Blob{*videoBlob}, p.Videos)
+			},
+		},
+		{
+			name:      "Declared media type not allowed",
+			media:     []models.MediaUpload{{Data: []byte("%PDF"), MimeType: "application/pdf"}},
+			expectErr: true,
+		},
+		{
+			name:      "Media without data or source",
+			media:     []models.MediaUpload{{MimeType: "image/png"}},
+			expectErr: true,
+		},
+		{
+			name:      "Uploaded blob has unsupported type",
+			media:     []models.MediaUpload{{Data: []byte("<html>")}},
+			blobs:     []*models.Blob{{Type: "blob", Ref: models.BlobLink{Link: "bafkreihtml"}, MimeType: "text/html"}},
+			expectErr: true,
+		},
+		{
+			name:      "Upload failure",
+			media:     []models.MediaUpload{{Data: []byte("jpeg"), MimeType: "image/jpeg"}},
+			blobs:     []*models.Blob{nil},
+			uploadErr: errors.New("upload failed"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAtproto := new(MockATProtoClient)
+			request := models.RequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:example:123",
+				Post: models.ShareFrameFeedPost{
+					NSID:      "social.shareframe.feed.post",
+					Text:      "Look at this",
+					CreatedAt: now.Format(time.RFC3339),
+				},
+				Media: tt.media,
+			}
+
+			for i, blob := range tt.blobs {
+				mockAtproto.On("UploadBlob", mock.Anything, "valid_token", "did:example:123", tt.media[i]).
+					Return(blob, tt.uploadErr).Once()
+			}
+
+			var capturedPost models.ShareFrameFeedPost
+			if tt.expectPost {
+				mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:example:123", mock.Anything).
+					Run(func(args mock.Arguments) {
+						capturedPost = args.Get(1).(models.ShareFrameFeedPost)
+					}).
+					Return(&models.PostResponse{URI: "at://did:example:123/social.shareframe.feed.post/xyz"}, nil).Once()
+			}
+
+			resp, err := PostHandler(context.Background(), mockAtproto, Policy{}, request)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				tt.checkPostFn(t, capturedPost)
+			}
+			mockAtproto.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostHandlerAttachesFacets(t *testing.T) {
+	mockAtproto := new(MockATProtoClient)
+	mockAtproto.On("ResolveHandle", mock.Anything, "alice.shareframe.social").Return("did:plc:alice", nil).Once()
+
+	var capturedPost models.ShareFrameFeedPost
+	mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:example:123", mock.Anything).
+		Run(func(args mock.Arguments) {
+			capturedPost = args.Get(1).(models.ShareFrameFeedPost)
+		}).
+		Return(&models.PostResponse{URI: "at://did:example:123/social.shareframe.feed.post/xyz"}, nil).Once()
+
+	_, err := PostHandler(context.Background(), mockAtproto, Policy{}, models.RequestPayload{
+		AuthToken: "valid_token",
+		DID:       "did:example:123",
+		Post: models.ShareFrameFeedPost{
+			NSID:      "social.shareframe.feed.post",
+			Text:      "📸 with @alice.shareframe.social #sunset https://shareframe.social",
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, capturedPost.Facets, 3) {
+		assert.Equal(t, "did:plc:alice", capturedPost.Facets[0].Features[0].Mention.DID)
+		assert.Equal(t, "sunset", capturedPost.Facets[1].Features[0].Tag.Tag)
+		assert.Equal(t, "https://shareframe.social", capturedPost.Facets[2].Features[0].Link.URI)
+	}
+	mockAtproto.AssertExpectations(t)
+}
+
+func TestPostHandlerRecordKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		rkey        string
+		expectRKey  string
+		expectedErr string
+	}{
+		{
+			name: "Generated when the client sends none",
+		},
+		{
+			name:       "Client-generated TID",
+			rkey:       "3jzfcijpj2z2a",
+			expectRKey: "3jzfcijpj2z2a",
+		},
+		{
+			name:        "Client key that is not a TID",
+			rkey:        "my-post",
+			expectedErr: `rkey "my-post" is not a TID`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAtproto := new(MockATProtoClient)
+			var rkey string
+			mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:example:123", mock.Anything).
+				Run(func(args mock.Arguments) {
+					rkey = args.String(4)
+				}).
+				Return(&models.PostResponse{URI: "at://did:example:123/social.shareframe.feed.post/xyz"}, nil).Maybe()
+
+			_, err := PostHandler(context.Background(), mockAtproto, Policy{}, models.RequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:example:123",
+				Post: models.ShareFrameFeedPost{
+					NSID:      "social.shareframe.feed.post",
+					Text:      "Hello",
+					CreatedAt: time.Now().UTC().Format(time.RFC3339),
+				},
+				RKey: tt.rkey,
+			})
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, CodeInvalidRequest, ErrorCodeOf(err))
+				mockAtproto.AssertNotCalled(t, "PostToFeed", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, tid.Valid(rkey))
+			if tt.expectRKey != "" {
+				assert.Equal(t, tt.expectRKey, rkey)
+			}
+		})
+	}
+}
+
+func TestPostHandlerRefreshesExpiredToken(t *testing.T) {
+	expired := fmt.Errorf("com.atproto.repo.createRecord failed: %w", atproto.ErrExpiredToken)
+	refreshed := &models.Session{DID: "did:example:123", AccessJwt: "new_access", RefreshJwt: "new_refresh"}
+
+	tests := []struct {
+		name          string
+		refreshToken  string
+		refreshErr    error
+		expectRefresh bool
+		expectRetry   bool
+		expectErr     bool
+	}{
+		{
+			name:          "Expired token is refreshed and the post retried",
+			refreshToken:  "refresh_token",
+			expectRefresh: true,
+			expectRetry:   true,
+		},
+		{
+			name:      "No refresh token surfaces the expiry",
+			expectErr: true,
+		},
+		{
+			name:          "Refresh failure surfaces the error",
+			refreshToken:  "refresh_token",
+			refreshErr:    errors.New("refresh token revoked"),
+			expectRefresh: true,
+			expectErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAtproto := new(MockATProtoClient)
+			mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, "expired_token", "did:example:123", mock.Anything).
+				Return(nil, expired).Once()
+			if tt.expectRefresh {
+				var session *models.Session
+				if tt.refreshErr == nil {
+					session = refreshed
+				}
+				mockAtproto.On("RefreshSession", mock.Anything, "did:example:123", tt.refreshToken).
+					Return(session, tt.refreshErr).Once()
+			}
+			if tt.expectRetry {
+				mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, "new_access", "did:example:123", mock.Anything).
+					Return(&models.PostResponse{URI: "at://did:example:123/social.shareframe.feed.post/xyz"}, nil).Once()
+			}
+
+			resp, err := PostHandler(context.Background(), mockAtproto, Policy{}, models.RequestPayload{
+				AuthToken:    "expired_token",
+				RefreshToken: tt.refreshToken,
+				DID:          "did:example:123",
+				Post: models.ShareFrameFeedPost{
+					NSID:      "social.shareframe.feed.post",
+					Text:      "Hello again",
+					CreatedAt: time.Now().UTC().Format(time.RFC3339),
+				},
+			})
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, refreshed, resp.Session)
+			}
+			mockAtproto.AssertExpectations(t)
+		})
+	}
+}
+
 func TestValidatePost(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -256,7 +564,7 @@ func TestValidatePost(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validatePost(tt.post)
+			err := validatePost(tt.post, strictValidation)
 			if tt.expectErr {
 				assert.Error(t, err)
 			} else {
@@ -266,6 +574,83 @@ func TestValidatePost(t *testing.T) {
 	}
 }
 
+func TestValidatePostTextLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		expectedErr string
+	}{
+		{
+			name: "300 ASCII characters",
+			text: strings.Repeat("a", 300),
+		},
+		{
+			name:        "301 ASCII characters",
+			text:        strings.Repeat("a", 301),
+			expectedErr: "text: must be at most 300 characters (got 301)",
+		},
+		{
+			name: "300 Japanese characters",
+			text: strings.Repeat("あ", 300),
+		},
+		{
+			name:        "301 Japanese characters",
+			text:        strings.Repeat("あ", 301),
+			expectedErr: "text: must be at most 300 characters (got 301)",
+		},
+		{
+			name: "Korean syllables",
+			text: strings.Repeat("한", 300),
+		},
+		{
+			name: "Hindi with viramas",
+			text: strings.Repeat("नमस्ते ", 60),
+		},
+		{
+			name:        "Too many Hindi graphemes",
+			text:        strings.Repeat("नमस्ते ", 61),
+			expectedErr: "text: must be at most 300 characters (got 305)",
+		},
+		{
+			name: "Combining accents",
+			text: strings.Repeat("e\u0301", 300),
+		},
+		{
+			name: "Skin tone emoji",
+			text: strings.Repeat("👍🏽", 300),
+		},
+		{
+			name: "Flags",
+			text: strings.Repeat("🇯🇵", 300),
+		},
+		{
+			name:        "301 emoji",
+			text:        strings.Repeat("😀", 301),
+			expectedErr: "text: must be at most 300 characters (got 301)",
+		},
+		{
+			name:        "ZWJ families within grapheme limit exceed byte ceiling",
+			text:        strings.Repeat("👨‍👩‍👧‍👦", 150),
+			expectedErr: "text: must be at most 3000 bytes (got 3750)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePost(models.ShareFrameFeedPost{
+				NSID:      "social.shareframe.feed.post",
+				Text:      tt.text,
+				CreatedAt: time.Now().Format(time.RFC3339),
+			}, strictValidation)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestIsValidExtension(t *testing.T) {
 	tests := []struct {
 		name      string
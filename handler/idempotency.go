@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/atproto/tid"
+	"github.com/ShareFrame/posting-service/idempotency"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// A pending key younger than this is assumed to belong to a request that
+	// is still running; older ones are resumed with the reserved rkey.
+	idempotencyInFlight = 30 * time.Second
+)
+
+func IdempotentPostHandler(ctx context.Context, client atproto.ATProtoClient, policy Policy, store idempotency.Store, key string, request models.RequestPayload) (*models.PostResponse, error) {
+	if store == nil || key == "" || request.AuthToken == "" || request.DID == "" {
+		return PostHandler(ctx, client, policy, request)
+	}
+
+	if err := validateIdempotencyKey(key); err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Invalid idempotency key")
+		return nil, newError(CodeInvalidRequest, fmt.Errorf("invalid request: %w", err))
+	}
+
+	fingerprint, err := requestFingerprint(request)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to fingerprint request")
+		return nil, newError(CodeInternal, err)
+	}
+
+	rkey := request.RKey
+	if rkey == "" {
+		rkey = tid.Now()
+	}
+
+	now := time.Now()
+	rec := idempotency.Record{
+		Key:         request.DID + "/" + key,
+		Fingerprint: fingerprint,
+		RKey:        rkey,
+		Status:      idempotency.StatusPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(idempotency.DefaultTTL),
+	}
+
+	existing, err := store.Reserve(ctx, rec)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Failed to reserve idempotency key")
+		return nil, newError(CodeInternal, fmt.Errorf("reserving idempotency key failed: %w", err))
+	}
+
+	request.RKey = rec.RKey
+	if existing != nil {
+		resp, err := replayPost(ctx, client, store, request.DID, rec, existing)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+		request.RKey = existing.RKey
+	}
+
+	resp, err := PostHandler(ctx, client, policy, request)
+	if err != nil {
+		if existing == nil && !mayHaveWritten(err) {
+			if releaseErr := store.Release(ctx, rec.Key); releaseErr != nil {
+				logrus.WithError(releaseErr).WithField("DID", request.DID).Warn("Failed to release idempotency key")
+			}
+		}
+		return nil, err
+	}
+
+	completePost(ctx, store, rec.Key, resp)
+	return resp, nil
+}
+
+func replayPost(ctx context.Context, client atproto.ATProtoClient, store idempotency.Store, did string, rec idempotency.Record, existing *idempotency.Record) (*models.PostResponse, error) {
+	if existing.Fingerprint != rec.Fingerprint {
+		err := errors.New("invalid request: idempotency key was already used for a different post")
+		logrus.WithField("DID", did).Error(err)
+		return nil, newError(CodeInvalidRequest, err)
+	}
+
+	if existing.Status == idempotency.StatusComplete {
+		var resp models.PostResponse
+		if err := json.Unmarshal(existing.Response, &resp); err != nil {
+			logrus.WithError(err).WithField("DID", did).Error("Failed to parse stored response")
+			return nil, newError(CodeInternal, fmt.Errorf("failed to parse stored response: %w", err))
+		}
+		logrus.WithFields(logrus.Fields{"DID": did, "uri": resp.URI}).Info("Replayed idempotent post")
+		return &resp, nil
+	}
+
+	if time.Since(existing.CreatedAt) < idempotencyInFlight {
+		return nil, newError(CodeConflict, errors.New("a request with this idempotency key is still in progress"))
+	}
+
+	record, err := client.GetRecord(ctx, "", did, existing.RKey)
+	if err != nil {
+		if isRecordNotFound(err) {
+			return nil, nil
+		}
+		logrus.WithError(err).WithField("DID", did).Error("Failed to look up pending idempotent post")
+		return nil, upstreamError("checking for an earlier post failed", err)
+	}
+
+	resp := &models.PostResponse{URI: record.URI, CID: record.CID}
+	completePost(ctx, store, existing.Key, resp)
+	return resp, nil
+}
+
+func completePost(ctx context.Context, store idempotency.Store, key string, resp *models.PostResponse) {
+	stored := *resp
+	stored.Session = nil
+
+	data, err := json.Marshal(stored)
+	if err == nil {
+		err = store.Complete(ctx, key, data)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("uri", resp.URI).Warn("Failed to save idempotent response")
+	}
+}
+
+func mayHaveWritten(err error) bool {
+	switch ErrorCodeOf(err) {
+	case CodeInvalidRequest, CodeUnauthorized, CodeForbidden, CodeRateLimited:
+		return false
+	}
+	return true
+}
+
+func validateIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key must be at most %d characters", maxIdempotencyKeyLength)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return errors.New("idempotency key must be printable ASCII")
+		}
+	}
+	return nil
+}
+
+func requestFingerprint(request models.RequestPayload) (string, error) {
+	post := request.Post
+	post.CreatedAt = ""
+
+	data, err := json.Marshal(struct {
+		DID     string                    `json:"did"`
+		Post    models.ShareFrameFeedPost `json:"post"`
+		Media   []models.MediaUpload      `json:"media,omitempty"`
+		ReplyTo string                    `json:"replyTo,omitempty"`
+		QuoteOf string                    `json:"quoteOf,omitempty"`
+		RKey    string                    `json:"rkey,omitempty"`
+	}{request.DID, post, request.Media, request.ReplyTo, request.QuoteOf, request.RKey})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/idempotency"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func idempotentRequest(text string) models.RequestPayload {
+	return models.RequestPayload{
+		AuthToken: "valid_token",
+		DID:       "did:plc:alice",
+		Post: models.ShareFrameFeedPost{
+			NSID:      "social.shareframe.feed.post",
+			Text:      text,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+func TestIdempotentPostHandlerReplaysResponse(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	mockClient := new(MockATProtoClient)
+
+	var rkeys []string
+	mockClient.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:plc:alice", mock.Anything).
+		Run(func(args mock.Arguments) {
+			rkeys = append(rkeys, args.String(4))
+		}).
+		Return(&models.PostResponse{URI: "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a", CID: "bafyre123456"}, nil)
+
+	first, err := IdempotentPostHandler(context.Background(), mockClient, Policy{}, store, "retry-1", idempotentRequest("Hello"))
+	require.NoError(t, err)
+
+	replay := idempotentRequest("Hello")
+	replay.Post.CreatedAt = time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	second, err := IdempotentPostHandler(context.Background(), mockClient, Policy{}, store, "retry-1", replay)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	mockClient.AssertNumberOfCalls(t, "PostToFeed", 1)
+	require.Len(t, rkeys, 1)
+	assert.Regexp(t, `^[234567a-j][234567a-z]{12}$`, rkeys[0])
+
+	_, err = IdempotentPostHandler(context.Background(), mockClient, Policy{}, store, "retry-2", idempotentRequest("Hello"))
+	require.NoError(t, err)
+	mockClient.AssertNumberOfCalls(t, "PostToFeed", 2)
+	assert.NotEqual(t, rkeys[0], rkeys[1])
+}
+
+func TestIdempotentPostHandler(t *testing.T) {
+	notFound := &atproto.XRPCError{NSID: "com.atproto.repo.getRecord", StatusCode: 400, ErrorName: "RecordNotFound"}
+	created := &models.PostResponse{URI: "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a", CID: "bafyre123456"}
+	fingerprint, err := requestFingerprint(idempotentRequest("Hello"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		key          string
+		existing     *idempotency.Record
+		request      models.RequestPayload
+		setupMock    func(m *MockATProtoClient)
+		expectedCode ErrorCode
+		expectedErr  string
+		expectedResp *models.PostResponse
+		expectedRKey string
+	}{
+		{
+			name: "Same key with a different post",
+			key:  "k",
+			existing: &idempotency.Record{
+				Fingerprint: "other",
+				Status:      idempotency.StatusComplete,
+				Response:    []byte(`{}`),
+			},
+			request:      idempotentRequest("Hello"),
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "already used for a different post",
+		},
+		{
+			name: "Original request still in flight",
+			key:  "k",
+			existing: &idempotency.Record{
+				Fingerprint: fingerprint,
+				RKey:        "3kq2ve7ruvk2a",
+				Status:      idempotency.StatusPending,
+				CreatedAt:   time.Now(),
+			},
+			request:      idempotentRequest("Hello"),
+			expectedCode: CodeConflict,
+			expectedErr:  "still in progress",
+		},
+		{
+			name: "Stale pending key whose post was written",
+			key:  "k",
+			existing: &idempotency.Record{
+				Fingerprint: fingerprint,
+				RKey:        "3kq2ve7ruvk2a",
+				Status:      idempotency.StatusPending,
+				CreatedAt:   time.Now().Add(-time.Hour),
+			},
+			request: idempotentRequest("Hello"),
+			setupMock: func(m *MockATProtoClient) {
+				m.On("GetRecord", mock.Anything, "", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(&models.GetRecordResponse{URI: created.URI, CID: created.CID}, nil).Once()
+			},
+			expectedResp: created,
+		},
+		{
+			name: "Stale pending key whose post was never written",
+			key:  "k",
+			existing: &idempotency.Record{
+				Fingerprint: fingerprint,
+				RKey:        "3kq2ve7ruvk2a",
+				Status:      idempotency.StatusPending,
+				CreatedAt:   time.Now().Add(-time.Hour),
+			},
+			request: idempotentRequest("Hello"),
+			setupMock: func(m *MockATProtoClient) {
+				m.On("GetRecord", mock.Anything, "", "did:plc:alice", "3kq2ve7ruvk2a").Return(nil, notFound).Once()
+				m.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(created, nil).Once()
+			},
+			expectedResp: created,
+			expectedRKey: "3kq2ve7ruvk2a",
+		},
+		{
+			name:         "Key too long",
+			key:          strings.Repeat("k", 256),
+			request:      idempotentRequest("Hello"),
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "at most 255 characters",
+		},
+		{
+			name:         "Key with control characters",
+			key:          "k\n",
+			request:      idempotentRequest("Hello"),
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "printable ASCII",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := idempotency.NewMemoryStore()
+			if tt.existing != nil {
+				rec := *tt.existing
+				rec.Key = "did:plc:alice/" + tt.key
+				rec.ExpiresAt = time.Now().Add(time.Hour)
+				_, err := store.Reserve(context.Background(), rec)
+				require.NoError(t, err)
+			}
+
+			mockClient := new(MockATProtoClient)
+			if tt.setupMock != nil {
+				tt.setupMock(mockClient)
+			}
+
+			resp, err := IdempotentPostHandler(context.Background(), mockClient, Policy{}, store, tt.key, tt.request)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+				mockClient.AssertNotCalled(t, "PostToFeed", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedResp, resp)
+			mockClient.AssertExpectations(t)
+
+			existing, err := store.Reserve(context.Background(), idempotency.Record{Key: "did:plc:alice/" + tt.key})
+			require.NoError(t, err)
+			require.NotNil(t, existing)
+			assert.Equal(t, idempotency.StatusComplete, existing.Status)
+		})
+	}
+}
+
+func TestIdempotentPostHandlerFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		postErr       error
+		expectPending bool
+	}{
+		{
+			name:    "Rejected request releases the key",
+			postErr: &atproto.XRPCError{NSID: "com.atproto.repo.createRecord", StatusCode: 400, ErrorName: "InvalidRequest"},
+		},
+		{
+			name:          "Ambiguous failure keeps the key",
+			postErr:       &atproto.XRPCError{NSID: "com.atproto.repo.createRecord", StatusCode: 504},
+			expectPending: true,
+		},
+		{
+			name:          "Timeout keeps the key",
+			postErr:       context.DeadlineExceeded,
+			expectPending: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := idempotency.NewMemoryStore()
+			mockClient := new(MockATProtoClient)
+			mockClient.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:plc:alice", mock.Anything).
+				Return(nil, tt.postErr).Once()
+
+			_, err := IdempotentPostHandler(context.Background(), mockClient, Policy{}, store, "k", idempotentRequest("Hello"))
+			assert.True(t, errors.Is(err, tt.postErr))
+
+			existing, err := store.Reserve(context.Background(), idempotency.Record{Key: "did:plc:alice/k"})
+			require.NoError(t, err)
+			if tt.expectPending {
+				require.NotNil(t, existing)
+				assert.Equal(t, idempotency.StatusPending, existing.Status)
+			} else {
+				assert.Nil(t, existing)
+			}
+		})
+	}
+}
+
+func TestIdempotentPostHandlerDoesNotStoreSession(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	mockClient := new(MockATProtoClient)
+	mockClient.On("PostToFeed", mock.Anything, mock.Anything, "expired_token", "did:plc:alice", mock.Anything).
+		Return(nil, &atproto.XRPCError{StatusCode: 400, ErrorName: "ExpiredToken"}).Once()
+	mockClient.On("RefreshSession", mock.Anything, "did:plc:alice", "refresh_token").
+		Return(&models.Session{DID: "did:plc:alice", AccessJwt: "new_access", RefreshJwt: "new_refresh"}, nil).Once()
+	mockClient.On("PostToFeed", mock.Anything, mock.Anything, "new_access", "did:plc:alice", mock.Anything).
+		Return(&models.PostResponse{URI: "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a"}, nil).Once()
+
+	request := idempotentRequest("Hello")
+	request.AuthToken = "expired_token"
+	request.RefreshToken = "refresh_token"
+
+	first, err := IdempotentPostHandler(context.Background(), mockClient, Policy{}, store, "k", request)
+	require.NoError(t, err)
+	require.NotNil(t, first.Session)
+
+	second, err := IdempotentPostHandler(context.Background(), mockClient, Policy{}, store, "k", request)
+	require.NoError(t, err)
+	assert.Nil(t, second.Session)
+	assert.Equal(t, first.URI, second.URI)
+}
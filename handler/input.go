@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+func RejectServerOwnedFields(body []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return newError(CodeInvalidRequest, fmt.Errorf("invalid request: %w", err))
+	}
+
+	for _, name := range models.ServerOwnedFields {
+		if _, ok := fields[name]; ok {
+			err := fmt.Errorf("invalid request: %q is computed by the server and cannot be set", name)
+			logrus.Error(err)
+			return newError(CodeInvalidRequest, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectServerOwnedFields(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectedErr string
+	}{
+		{
+			name: "Client fields only",
+			body: `{"text":"hello","imageUris":["https://example.com/a.jpg"],"isStory":true}`,
+		},
+		{
+			name:        "Likes",
+			body:        `{"text":"hello","likes":1000000}`,
+			expectedErr: `invalid request: "likes" is computed by the server and cannot be set`,
+		},
+		{
+			name:        "Zero-valued counter is still rejected",
+			body:        `{"text":"hello","shares":0}`,
+			expectedErr: `invalid request: "shares" is computed by the server and cannot be set`,
+		},
+		{
+			name:        "Trending score",
+			body:        `{"trendingScore":99.9}`,
+			expectedErr: `invalid request: "trendingScore" is computed by the server and cannot be set`,
+		},
+		{
+			name:        "Not an object",
+			body:        `["likes"]`,
+			expectedErr: "invalid request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RejectServerOwnedFields([]byte(tt.body))
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.expectedErr)
+			assert.Equal(t, CodeInvalidRequest, ErrorCodeOf(err))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+// mediaVerifyTimeout bounds the checks for one post as a whole. The lexicon
+// caps how many URIs a post may carry, so this is rarely the limit.
+const mediaVerifyTimeout = 20 * time.Second
+
+// verifyMediaURIs checks that every imageUris and videoUris entry serves media
+// of that kind. Without a verifier only the file extension is checked.
+func verifyMediaURIs(ctx context.Context, verifier *media.Verifier, post models.ShareFrameFeedPost) error {
+	if verifier == nil || len(post.ImageUris)+len(post.VideoUris) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mediaVerifyTimeout)
+	defer cancel()
+
+	check := func(uri string, kind media.Kind) error {
+		err := verifier.Verify(ctx, uri, kind)
+		if err == nil {
+			return nil
+		}
+		logrus.WithError(err).WithField("uri", uri).Error("Media verification failed")
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return upstreamError("verifying media failed", ctxErr)
+		}
+		return newError(CodeInvalidRequest, fmt.Errorf("invalid post: %w", err))
+	}
+
+	for _, uri := range post.ImageUris {
+		if err := check(uri, media.Image); err != nil {
+			return err
+		}
+	}
+	for _, uri := range post.VideoUris {
+		if err := check(uri, media.Video); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostHandlerVerifiesMedia(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'})
+		case "/page.jpg":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	policy := Policy{MediaVerifier: media.NewVerifier(media.Config{Client: server.Client(), AllowPrivateNetworks: true})}
+
+	tests := []struct {
+		name         string
+		imageURI     string
+		expectedCode ErrorCode
+		expectedErr  string
+	}{
+		{name: "Real image", imageURI: server.URL + "/photo.jpg"},
+		{name: "HTML page", imageURI: server.URL + "/page.jpg", expectedCode: CodeInvalidRequest, expectedErr: "unsupported media type"},
+		{name: "Missing image", imageURI: server.URL + "/missing.jpg", expectedCode: CodeInvalidRequest, expectedErr: "media is not available"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAtproto := new(MockATProtoClient)
+			if tt.expectedErr == "" {
+				mockAtproto.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:example:123", mock.Anything).
+					Return(&models.PostResponse{URI: "at://did:example:123/social.shareframe.feed.post/xyz"}, nil).Once()
+			}
+
+			_, err := PostHandler(context.Background(), mockAtproto, policy, models.RequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:example:123",
+				Post: models.ShareFrameFeedPost{
+					NSID:      models.FeedPostNSID,
+					Text:      "Look at this",
+					CreatedAt: time.Now().UTC().Format(time.RFC3339),
+					ImageUris: []string{tt.imageURI},
+				},
+			})
+
+			mockAtproto.AssertExpectations(t)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPostHandlerCapsMediaURIs(t *testing.T) {
+	fetched := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched++
+	}))
+	defer server.Close()
+
+	policy := Policy{MediaVerifier: media.NewVerifier(media.Config{Client: server.Client(), AllowPrivateNetworks: true})}
+	uris := make([]string, 11)
+	for i := range uris {
+		uris[i] = server.URL + "/photo.jpg"
+	}
+
+	mockAtproto := new(MockATProtoClient)
+	_, err := PostHandler(context.Background(), mockAtproto, policy, models.RequestPayload{
+		AuthToken: "valid_token",
+		DID:       "did:example:123",
+		Post: models.ShareFrameFeedPost{
+			NSID:      models.FeedPostNSID,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			ImageUris: uris,
+		},
+	})
+
+	assert.ErrorContains(t, err, "imageUris: must have at most 10 items")
+	assert.Equal(t, CodeInvalidRequest, ErrorCodeOf(err))
+	assert.Zero(t, fetched)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+func resolveReply(ctx context.Context, client atproto.ATProtoClient, rawURI string) (*models.ReplyRef, error) {
+	parent, err := fetchPostRef(ctx, client, "replyTo", rawURI)
+	if err != nil {
+		return nil, err
+	}
+
+	root := parent.ref
+	if parent.reply != nil {
+		if _, err := atproto.ParseATURI(parent.reply.Root.URI); err != nil || parent.reply.Root.CID == "" {
+			err := fmt.Errorf("parent post %s has an invalid reply root", rawURI)
+			logrus.WithError(err).Error("Invalid reply root")
+			return nil, newError(CodeUpstreamError, err)
+		}
+		root = parent.reply.Root
+	}
+
+	return &models.ReplyRef{Root: root, Parent: parent.ref}, nil
+}
+
+func resolveQuote(ctx context.Context, client atproto.ATProtoClient, rawURI string) (*models.StrongRef, error) {
+	quoted, err := fetchPostRef(ctx, client, "quoteOf", rawURI)
+	if err != nil {
+		return nil, err
+	}
+	return &quoted.ref, nil
+}
+
+type postRef struct {
+	ref   models.StrongRef
+	reply *models.ReplyRef
+}
+
+func fetchPostRef(ctx context.Context, client atproto.ATProtoClient, field, rawURI string) (*postRef, error) {
+	uri, err := atproto.ParseATURI(rawURI)
+	if err != nil {
+		logrus.WithError(err).WithField("field", field).Error("Invalid post reference")
+		return nil, newError(CodeInvalidRequest, fmt.Errorf("invalid request: %s: %w", field, err))
+	}
+
+	if uri.Collection != models.FeedPostNSID {
+		err := fmt.Errorf("invalid request: %s: %s is not a ShareFrame post", field, rawURI)
+		logrus.Error(err)
+		return nil, newError(CodeInvalidRequest, err)
+	}
+
+	record, err := client.GetRecord(ctx, "", uri.DID, uri.RKey)
+	if err != nil {
+		if isRecordNotFound(err) {
+			err := fmt.Errorf("invalid request: %s: post %s does not exist", field, rawURI)
+			logrus.Error(err)
+			return nil, newError(CodeInvalidRequest, err)
+		}
+		logrus.WithError(err).WithField("URI", rawURI).Error("Failed to fetch referenced post")
+		return nil, upstreamError("fetching referenced post failed", err)
+	}
+
+	if record.CID == "" {
+		err := fmt.Errorf("referenced post %s has no CID", rawURI)
+		logrus.Error(err)
+		return nil, newError(CodeUpstreamError, err)
+	}
+
+	return &postRef{
+		ref:   models.StrongRef{URI: uri.String(), CID: record.CID},
+		reply: record.Value.Reply,
+	}, nil
+}
+
+func isRecordNotFound(err error) bool {
+	if errors.Is(err, atproto.ErrRecordNotFound) {
+		return true
+	}
+	var xrpcErr *atproto.XRPCError
+	return errors.As(err, &xrpcErr) && xrpcErr.StatusCode == http.StatusNotFound
+}
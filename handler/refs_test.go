@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostHandlerReferences(t *testing.T) {
+	const (
+		rootURI   = "at://did:plc:carol/social.shareframe.feed.post/3jzfcijpj2z2a"
+		parentURI = "at://did:plc:bob/social.shareframe.feed.post/3jzfcijpj2z2b"
+		quoteURI  = "at://did:plc:dave/social.shareframe.feed.post/3jzfcijpj2z2c"
+	)
+
+	rootRef := models.StrongRef{URI: rootURI, CID: "bafyreiroot"}
+	records := map[string]*models.GetRecordResponse{
+		"did:plc:carol/3jzfcijpj2z2a": {URI: rootURI, CID: "bafyreiroot"},
+		"did:plc:bob/3jzfcijpj2z2b": {
+			URI: parentURI,
+			CID: "bafyreiparent",
+			Value: models.ShareFrameFeedPost{
+				Reply: &models.ReplyRef{Root: rootRef, Parent: rootRef},
+			},
+		},
+		"did:plc:dave/3jzfcijpj2z2c": {URI: quoteURI, CID: "bafyreiquote"},
+	}
+	notFound := &atproto.XRPCError{NSID: "com.atproto.repo.getRecord", StatusCode: 400, ErrorName: "RecordNotFound"}
+
+	tests := []struct {
+		name          string
+		replyTo       string
+		quoteOf       string
+		expectedReply *models.ReplyRef
+		expectedQuote *models.StrongRef
+		expectedCode  ErrorCode
+		expectedErr   string
+	}{
+		{
+			name:    "Reply to a top-level post uses it as root",
+			replyTo: rootURI,
+			expectedReply: &models.ReplyRef{
+				Root:   rootRef,
+				Parent: rootRef,
+			},
+		},
+		{
+			name:    "Reply to a reply inherits its root",
+			replyTo: parentURI,
+			expectedReply: &models.ReplyRef{
+				Root:   rootRef,
+				Parent: models.StrongRef{URI: parentURI, CID: "bafyreiparent"},
+			},
+		},
+		{
+			name:          "Quote resolves to a strong ref",
+			quoteOf:       quoteURI,
+			expectedQuote: &models.StrongRef{URI: quoteURI, CID: "bafyreiquote"},
+		},
+		{
+			name:    "Reply and quote together",
+			replyTo: rootURI,
+			quoteOf: quoteURI,
+			expectedReply: &models.ReplyRef{
+				Root:   rootRef,
+				Parent: rootRef,
+			},
+			expectedQuote: &models.StrongRef{URI: quoteURI, CID: "bafyreiquote"},
+		},
+		{
+			name:         "Malformed reply URI",
+			replyTo:      "https://shareframe.social/post/123",
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "replyTo: invalid AT-URI",
+		},
+		{
+			name:         "Reply to another collection",
+			replyTo:      "at://did:plc:bob/app.bsky.feed.post/3jzfcijpj2z2b",
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "is not a ShareFrame post",
+		},
+		{
+			name:         "Reply to deleted post",
+			replyTo:      "at://did:plc:bob/social.shareframe.feed.post/deleted",
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "replyTo: post at://did:plc:bob/social.shareframe.feed.post/deleted does not exist",
+		},
+		{
+			name:         "Quote of nonexistent post",
+			quoteOf:      "at://did:plc:dave/social.shareframe.feed.post/missing",
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "quoteOf: post at://did:plc:dave/social.shareframe.feed.post/missing does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockATProtoClient)
+			for key, record := range records {
+				did, rkey, _ := strings.Cut(key, "/")
+				mockClient.On("GetRecord", mock.Anything, "", did, rkey).Return(record, nil).Maybe()
+			}
+			mockClient.On("GetRecord", mock.Anything, "", mock.Anything, mock.Anything).Return(nil, notFound).Maybe()
+
+			var posted models.ShareFrameFeedPost
+			mockClient.On("PostToFeed", mock.Anything, mock.Anything, "valid_token", "did:plc:alice", mock.Anything).
+				Run(func(args mock.Arguments) {
+					posted = args.Get(1).(models.ShareFrameFeedPost)
+				}).
+				Return(&models.PostResponse{URI: "at://did:plc:alice/social.shareframe.feed.post/new"}, nil).Maybe()
+
+			_, err := PostHandler(context.Background(), mockClient, Policy{}, models.RequestPayload{
+				AuthToken: "valid_token",
+				DID:       "did:plc:alice",
+				Post: models.ShareFrameFeedPost{
+					NSID:      "social.shareframe.feed.post",
+					Text:      "Replying",
+					CreatedAt: time.Now().UTC().Format(time.RFC3339),
+				},
+				ReplyTo: tt.replyTo,
+				QuoteOf: tt.quoteOf,
+			})
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+				mockClient.AssertNotCalled(t, "PostToFeed", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedReply, posted.Reply)
+			assert.Equal(t, tt.expectedQuote, posted.QuoteOf)
+		})
+	}
+}
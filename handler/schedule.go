@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/schedule"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxScheduleAhead = 30 * 24 * time.Hour
+
+	dispatchBatchSize   = 50
+	maxDispatchAttempts = 5
+	dispatchRetryDelay  = time.Minute
+
+	// A claimed entry is not handed out again until its lease runs out, so a
+	// dispatcher that dies mid-publish only delays the post.
+	dispatchLease = 5 * time.Minute
+)
+
+var errNotPending = errors.New("scheduled post is not pending")
+
+type DispatchResult struct {
+	Published int `json:"published"`
+	Retrying  int `json:"retrying"`
+	NeedsAuth int `json:"needsAuth"`
+	Failed    int `json:"failed"`
+}
+
+// SchedulePost saves the post without the author's tokens. It is published
+// later with the app password the author registered, so one is required.
+func SchedulePost(ctx context.Context, policy Policy, store schedule.Store, credentials credential.Store, request models.RequestPayload, scheduledAt string) (*models.ScheduledPostResponse, error) {
+	now := time.Now()
+	at, err := parseScheduledAt(scheduledAt, now)
+	if err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Invalid schedule time")
+		return nil, err
+	}
+
+	validated := request
+	if err := preparePost(&validated, policy); err != nil {
+		return nil, err
+	}
+	request.RKey = validated.RKey
+
+	if request.Post.ExpiresAt != "" {
+		if expiresAt, _ := time.Parse(time.RFC3339, request.Post.ExpiresAt); !expiresAt.After(at) {
+			return nil, newError(CodeInvalidRequest, errors.New("invalid post: expiresAt must be after scheduledAt"))
+		}
+	}
+
+	if _, err := credentials.Get(ctx, request.DID); err != nil {
+		if errors.Is(err, credential.ErrNotFound) {
+			return nil, newError(CodeForbidden, errors.New("scheduling posts requires a registered app password"))
+		}
+		logrus.WithError(err).WithField("DID", request.DID).Error("Failed to load credential")
+		return nil, newError(CodeInternal, fmt.Errorf("scheduling post failed: %w", err))
+	}
+	request.AuthToken, request.RefreshToken = "", ""
+
+	entry := schedule.Entry{
+		ID:          request.RKey,
+		DID:         request.DID,
+		URI:         atproto.ATURI{DID: request.DID, Collection: models.FeedPostNSID, RKey: request.RKey}.String(),
+		Request:     request,
+		ScheduledAt: at,
+		CreatedAt:   now,
+		State:       lease.State{Status: schedule.StatusPending, NextAttemptAt: at, UpdatedAt: now},
+	}
+	if err := store.Create(ctx, entry); err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Failed to save scheduled post")
+		if errors.Is(err, schedule.ErrExists) {
+			return nil, newError(CodeConflict, fmt.Errorf("scheduling post failed: %w", err))
+		}
+		return nil, newError(CodeInternal, fmt.Errorf("scheduling post failed: %w", err))
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": entry.DID, "id": entry.ID, "scheduledAt": at}).Info("Scheduled post")
+	return scheduledPostResponse(entry), nil
+}
+
+func CancelScheduledPost(ctx context.Context, store schedule.Store, did, id string) (*models.ScheduledPostResponse, error) {
+	entry, err := updatePendingEntry(ctx, store, did, id, func(e *schedule.Entry) {
+		e.Status = schedule.StatusCanceled
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": did, "id": id}).Info("Canceled scheduled post")
+	return scheduledPostResponse(*entry), nil
+}
+
+func ReschedulePost(ctx context.Context, store schedule.Store, did, id, scheduledAt string) (*models.ScheduledPostResponse, error) {
+	at, err := parseScheduledAt(scheduledAt, time.Now())
+	if err != nil {
+		logrus.WithError(err).WithField("DID", did).Error("Invalid schedule time")
+		return nil, err
+	}
+
+	entry, err := updatePendingEntry(ctx, store, did, id, func(e *schedule.Entry) {
+		e.ScheduledAt = at
+		e.NextAttemptAt = at
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{"DID": did, "id": id, "scheduledAt": at}).Info("Rescheduled post")
+	return scheduledPostResponse(*entry), nil
+}
+
+func updatePendingEntry(ctx context.Context, store schedule.Store, did, id string, fn func(*schedule.Entry)) (*schedule.Entry, error) {
+	if did == "" || id == "" {
+		return nil, newError(CodeInvalidRequest, errors.New("invalid request: missing 'did' or 'id'"))
+	}
+
+	var status schedule.Status
+	entry, err := store.Update(ctx, did, id, func(e *schedule.Entry) error {
+		if e.Status != schedule.StatusPending && e.Status != lease.StatusNeedsAuth {
+			status = e.Status
+			return errNotPending
+		}
+		fn(e)
+		e.UpdatedAt = time.Now()
+		return nil
+	})
+	switch {
+	case errors.Is(err, schedule.ErrNotFound):
+		return nil, newError(CodeNotFound, fmt.Errorf("scheduled post %s not found", id))
+	case errors.Is(err, errNotPending):
+		return nil, newError(CodeConflict, fmt.Errorf("scheduled post %s is already %s", id, status))
+	case err != nil:
+		logrus.WithError(err).WithFields(logrus.Fields{"DID": did, "id": id}).Error("Failed to update scheduled post")
+		return nil, newError(CodeInternal, fmt.Errorf("updating scheduled post failed: %w", err))
+	}
+	return entry, nil
+}
+
+func DispatchScheduledPosts(ctx context.Context, client atproto.ATProtoClient, policy Policy, store schedule.Store, credentials credential.Store, expiries expiry.Store, now time.Time) (*DispatchResult, error) {
+	sessions := newServiceSessions(client, credentials)
+	result, err := lease.Run(ctx, lease.Job[schedule.Entry]{
+		Name:        "scheduled post",
+		BatchSize:   dispatchBatchSize,
+		MaxAttempts: maxDispatchAttempts,
+		Lease:       dispatchLease,
+		RetryDelay:  dispatchRetryDelay,
+		Done:        schedule.StatusPublished,
+		Due:         store.Due,
+		Update: func(ctx context.Context, e schedule.Entry, fn func(*schedule.Entry) error) (*schedule.Entry, error) {
+			return store.Update(ctx, e.DID, e.ID, fn)
+		},
+		Attempt: func(ctx context.Context, e *schedule.Entry) (func(*schedule.Entry), error) {
+			return publishScheduledPost(ctx, client, policy, sessions, expiries, e, now)
+		},
+		Retryable: retryableDispatchError,
+		NeedsAuth: needsAuth,
+		Fields:    scheduledPostFields,
+		Finished: func(e *schedule.Entry, status lease.Status, err error) {
+			log := logrus.WithFields(scheduledPostFields(*e)).WithFields(logrus.Fields{"attempt": e.Attempts, "status": status})
+			if err != nil {
+				log.WithError(err).WithField("code", ErrorCodeOf(err)).Warn("Scheduled post was not published")
+				return
+			}
+			log.Info("Published scheduled post")
+		},
+	}, now)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list due scheduled posts")
+		return nil, fmt.Errorf("listing due scheduled posts failed: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"published": result.Done,
+		"retrying":  result.Retrying,
+		"needsAuth": result.NeedsAuth,
+		"failed":    result.Failed,
+	}).Info("Dispatched scheduled posts")
+	return &DispatchResult{Published: result.Done, Retrying: result.Retrying, NeedsAuth: result.NeedsAuth, Failed: result.Failed}, nil
+}
+
+func scheduledPostFields(e schedule.Entry) logrus.Fields {
+	return logrus.Fields{"DID": e.DID, "id": e.ID, "uri": e.URI}
+}
+
+// publishScheduledPost returns the CID to save once the post is published.
+func publishScheduledPost(ctx context.Context, client atproto.ATProtoClient, policy Policy, sessions *serviceSessions, expiries expiry.Store, entry *schedule.Entry, now time.Time) (func(*schedule.Entry), error) {
+	if entry.Attempts > 1 {
+		record, err := client.GetRecord(ctx, "", entry.DID, entry.ID)
+		if err == nil {
+			return setScheduledCID(record.CID), nil
+		}
+		if !isRecordNotFound(err) {
+			return nil, upstreamError("checking for an earlier attempt failed", err)
+		}
+	}
+
+	session, err := sessions.get(ctx, entry.DID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := entry.Request
+	request.AuthToken, request.RefreshToken = session.AccessJwt, session.RefreshJwt
+	request.Post.CreatedAt = now.UTC().Format(time.RFC3339)
+
+	resp, err := PostHandler(ctx, client, policy, request)
+	if err != nil {
+		return nil, err
+	}
+	TrackStoryExpiry(ctx, expiries, request, resp)
+	return setScheduledCID(resp.CID), nil
+}
+
+func setScheduledCID(cid string) func(*schedule.Entry) {
+	return func(e *schedule.Entry) {
+		e.CID = cid
+	}
+}
+
+// needsAuth reports whether an attempt failed because the service cannot act
+// for the author, most likely because they revoked their app password.
+func needsAuth(err error) bool {
+	return ErrorCodeOf(err) == CodeUnauthorized
+}
+
+func retryableDispatchError(err error) bool {
+	switch ErrorCodeOf(err) {
+	case CodeInvalidRequest, CodeUnauthorized, CodeForbidden, CodeNotFound:
+		return false
+	}
+	return true
+}
+
+func parseScheduledAt(value string, now time.Time) (time.Time, error) {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, newError(CodeInvalidRequest, errors.New("invalid request: scheduledAt must be an RFC 3339 datetime"))
+	}
+	if !at.After(now) {
+		return time.Time{}, newError(CodeInvalidRequest, errors.New("invalid request: scheduledAt must be in the future"))
+	}
+	if at.After(now.Add(maxScheduleAhead)) {
+		return time.Time{}, newError(CodeInvalidRequest, fmt.Errorf("invalid request: scheduledAt must be within %d days", int(maxScheduleAhead.Hours()/24)))
+	}
+	return at.UTC(), nil
+}
+
+func scheduledPostResponse(entry schedule.Entry) *models.ScheduledPostResponse {
+	return &models.ScheduledPostResponse{
+		ID:          entry.ID,
+		URI:         entry.URI,
+		Status:      string(entry.Status),
+		ScheduledAt: entry.ScheduledAt.UTC().Format(time.RFC3339),
+		Attempts:    entry.Attempts,
+		LastError:   entry.LastError,
+		CID:         entry.CID,
+	}
+}
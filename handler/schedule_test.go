@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduledRequest(text string) models.RequestPayload {
+	return models.RequestPayload{
+		AuthToken:    "access",
+		RefreshToken: "refresh",
+		DID:          "did:plc:alice",
+		Post: models.ShareFrameFeedPost{
+			NSID:      "social.shareframe.feed.post",
+			Text:      text,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+func TestSchedulePost(t *testing.T) {
+	inOneHour := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name         string
+		request      models.RequestPayload
+		did          string
+		scheduledAt  string
+		expectedCode ErrorCode
+		expectedErr  string
+	}{
+		{
+			name:        "Scheduled",
+			request:     scheduledRequest("Later"),
+			scheduledAt: inOneHour,
+		},
+		{
+			name:         "Not a datetime",
+			request:      scheduledRequest("Later"),
+			scheduledAt:  "tomorrow",
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "scheduledAt must be an RFC 3339 datetime",
+		},
+		{
+			name:         "In the past",
+			request:      scheduledRequest("Later"),
+			scheduledAt:  time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "scheduledAt must be in the future",
+		},
+		{
+			name:         "Too far ahead",
+			request:      scheduledRequest("Later"),
+			scheduledAt:  time.Now().Add(31 * 24 * time.Hour).UTC().Format(time.RFC3339),
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "scheduledAt must be within 30 days",
+		},
+		{
+			name: "Invalid post",
+			request: func() models.RequestPayload {
+				r := scheduledRequest("Later")
+				r.Post.ImageUris = []string{"https://example.com/doc.pdf"}
+				return r
+			}(),
+			scheduledAt:  inOneHour,
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "invalid image format",
+		},
+		{
+			name: "Story expires before it is published",
+			request: func() models.RequestPayload {
+				r := scheduledRequest("Later")
+				r.Post.IsStory = true
+				r.Post.ExpiresAt = time.Now().Add(30 * time.Minute).UTC().Format(time.RFC3339)
+				return r
+			}(),
+			scheduledAt:  inOneHour,
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "expiresAt must be after scheduledAt",
+		},
+		{
+			name:         "No registered app password",
+			request:      scheduledRequest("Later"),
+			did:          "did:plc:bob",
+			scheduledAt:  inOneHour,
+			expectedCode: CodeForbidden,
+			expectedErr:  "requires a registered app password",
+		},
+		{
+			name: "Missing auth token",
+			request: func() models.RequestPayload {
+				r := scheduledRequest("Later")
+				r.AuthToken = ""
+				return r
+			}(),
+			scheduledAt:  inOneHour,
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "missing 'authToken' or 'did'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := schedule.NewMemoryStore()
+			if tt.did != "" {
+				tt.request.DID = tt.did
+			}
+
+			resp, err := SchedulePost(context.Background(), Policy{}, store, registeredCredentials(t), tt.request, tt.scheduledAt)
+
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "pending", resp.Status)
+			assert.Equal(t, tt.scheduledAt, resp.ScheduledAt)
+			assert.Equal(t, "at://did:plc:alice/social.shareframe.feed.post/"+resp.ID, resp.URI)
+
+			entry, err := store.Get(context.Background(), "did:plc:alice", resp.ID)
+			require.NoError(t, err)
+			assert.Equal(t, resp.ID, entry.Request.RKey)
+			assert.Empty(t, entry.Request.AuthToken)
+			assert.Empty(t, entry.Request.RefreshToken)
+		})
+	}
+}
+
+func TestCancelAndReschedule(t *testing.T) {
+	ctx := context.Background()
+	store := schedule.NewMemoryStore()
+	scheduled, err := SchedulePost(ctx, Policy{}, store, registeredCredentials(t), scheduledRequest("Later"), time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	require.NoError(t, err)
+
+	newTime := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	resp, err := ReschedulePost(ctx, store, "did:plc:alice", scheduled.ID, newTime)
+	require.NoError(t, err)
+	assert.Equal(t, newTime, resp.ScheduledAt)
+
+	_, err = ReschedulePost(ctx, store, "did:plc:alice", scheduled.ID, "yesterday")
+	assert.Equal(t, CodeInvalidRequest, ErrorCodeOf(err))
+
+	_, err = CancelScheduledPost(ctx, store, "did:plc:bob", scheduled.ID)
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+
+	resp, err = CancelScheduledPost(ctx, store, "did:plc:alice", scheduled.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "canceled", resp.Status)
+
+	_, err = CancelScheduledPost(ctx, store, "did:plc:alice", scheduled.ID)
+	assert.Equal(t, CodeConflict, ErrorCodeOf(err))
+	assert.ErrorContains(t, err, "already canceled")
+
+	_, err = ReschedulePost(ctx, store, "did:plc:alice", scheduled.ID, newTime)
+	assert.Equal(t, CodeConflict, ErrorCodeOf(err))
+}
+
+func TestDispatchScheduledPosts(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	notFound := &atproto.XRPCError{NSID: "com.atproto.repo.getRecord", StatusCode: 400, ErrorName: "RecordNotFound"}
+	session := &models.Session{DID: "did:plc:alice", AccessJwt: "service_access", RefreshJwt: "service_refresh"}
+
+	tests := []struct {
+		name           string
+		attempts       int
+		unregistered   bool
+		setupMock      func(m *MockATProtoClient)
+		expectedResult DispatchResult
+		expectedStatus schedule.Status
+		expectedNext   time.Time
+		expectedErr    string
+	}{
+		{
+			name: "Published",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").Return(session, nil).Once()
+				m.On("PostToFeed", mock.Anything, mock.Anything, "service_access", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(&models.PostResponse{URI: "at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a", CID: "bafyre123456"}, nil).Once()
+			},
+			expectedResult: DispatchResult{Published: 1},
+			expectedStatus: schedule.StatusPublished,
+		},
+		{
+			name: "Transient failure is retried later",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").Return(session, nil).Once()
+				m.On("PostToFeed", mock.Anything, mock.Anything, "service_access", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(nil, &atproto.XRPCError{StatusCode: 502}).Once()
+			},
+			expectedResult: DispatchResult{Retrying: 1},
+			expectedStatus: schedule.StatusPending,
+			expectedNext:   now.Add(time.Minute),
+			expectedErr:    "posting to feed failed",
+		},
+		{
+			name:     "Giving up after the last attempt",
+			attempts: maxDispatchAttempts - 1,
+			setupMock: func(m *MockATProtoClient) {
+				m.On("GetRecord", mock.Anything, "", "did:plc:alice", "3kq2ve7ruvk2a").Return(nil, notFound).Once()
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").Return(session, nil).Once()
+				m.On("PostToFeed", mock.Anything, mock.Anything, "service_access", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(nil, &atproto.XRPCError{StatusCode: 502}).Once()
+			},
+			expectedResult: DispatchResult{Failed: 1},
+			expectedStatus: schedule.StatusFailed,
+			expectedErr:    "posting to feed failed",
+		},
+		{
+			name: "Revoked app password waits for authorization",
+			setupMock: func(m *MockATProtoClient) {
+				m.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").
+					Return(nil, &atproto.XRPCError{StatusCode: 401, ErrorName: "AuthenticationRequired"}).Once()
+			},
+			expectedResult: DispatchResult{NeedsAuth: 1},
+			expectedStatus: lease.StatusNeedsAuth,
+			expectedErr:    "creating session with app password failed",
+		},
+		{
+			name:           "Removed app password waits for authorization",
+			unregistered:   true,
+			setupMock:      func(m *MockATProtoClient) {},
+			expectedResult: DispatchResult{NeedsAuth: 1},
+			expectedStatus: lease.StatusNeedsAuth,
+			expectedErr:    "no app password registered",
+		},
+		{
+			name:     "Earlier attempt already wrote the post",
+			attempts: 1,
+			setupMock: func(m *MockATProtoClient) {
+				m.On("GetRecord", mock.Anything, "", "did:plc:alice", "3kq2ve7ruvk2a").
+					Return(&models.GetRecordResponse{CID: "bafyre123456"}, nil).Once()
+			},
+			expectedResult: DispatchResult{Published: 1},
+			expectedStatus: schedule.StatusPublished,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := schedule.NewMemoryStore()
+			add := func(id string, at time.Time) {
+				request := scheduledRequest("Later")
+				request.AuthToken, request.RefreshToken = "", ""
+				request.RKey = id
+				require.NoError(t, store.Create(ctx, schedule.Entry{
+					ID:          id,
+					DID:         "did:plc:alice",
+					Request:     request,
+					ScheduledAt: at,
+					State:       lease.State{Status: schedule.StatusPending, NextAttemptAt: at, Attempts: tt.attempts},
+				}))
+			}
+			add("3kq2ve7ruvk2a", now.Add(-time.Minute))
+			add("3kq2ve7ruvk2b", now.Add(time.Hour))
+
+			credentials := registeredCredentials(t)
+			if tt.unregistered {
+				require.NoError(t, credentials.Delete(ctx, "did:plc:alice"))
+			}
+			mockClient := new(MockATProtoClient)
+			tt.setupMock(mockClient)
+
+			result, err := DispatchScheduledPosts(ctx, mockClient, Policy{}, store, credentials, nil, now)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, *result)
+			mockClient.AssertExpectations(t)
+
+			entry, err := store.Get(ctx, "did:plc:alice", "3kq2ve7ruvk2a")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, entry.Status)
+			assert.Equal(t, tt.attempts+1, entry.Attempts)
+			assert.Empty(t, entry.Request.RefreshToken)
+			if tt.expectedErr != "" {
+				assert.Contains(t, entry.LastError, tt.expectedErr)
+			} else {
+				assert.Empty(t, entry.LastError)
+				assert.Equal(t, "bafyre123456", entry.CID)
+			}
+			if !tt.expectedNext.IsZero() {
+				assert.Equal(t, tt.expectedNext, entry.NextAttemptAt)
+			}
+
+			later, err := store.Get(ctx, "did:plc:alice", "3kq2ve7ruvk2b")
+			require.NoError(t, err)
+			assert.Equal(t, schedule.StatusPending, later.Status)
+			assert.Equal(t, tt.attempts, later.Attempts)
+		})
+	}
+}
+
+func TestDispatchLogsInOncePerAuthor(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := schedule.NewMemoryStore()
+	for _, id := range []string{"3kq2ve7ruvk2a", "3kq2ve7ruvk2b"} {
+		request := scheduledRequest("Later")
+		request.RKey = id
+		require.NoError(t, store.Create(ctx, schedule.Entry{
+			ID:      id,
+			DID:     "did:plc:alice",
+			Request: request,
+			State:   lease.State{Status: schedule.StatusPending, NextAttemptAt: now},
+		}))
+	}
+
+	mockClient := new(MockATProtoClient)
+	mockClient.On("CreateSession", mock.Anything, "did:plc:alice", "app-password").
+		Return(&models.Session{DID: "did:plc:alice", AccessJwt: "service_access"}, nil).Once()
+	mockClient.On("PostToFeed", mock.Anything, mock.Anything, "service_access", "did:plc:alice", mock.Anything).
+		Return(&models.PostResponse{CID: "bafyre123456"}, nil).Twice()
+
+	result, err := DispatchScheduledPosts(ctx, mockClient, Policy{}, store, registeredCredentials(t), nil, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, DispatchResult{Published: 2}, *result)
+	mockClient.AssertExpectations(t)
+}
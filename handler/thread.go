@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/atproto/dagcbor"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/richtext"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxThreadPosts = 25
+
+	maxPostGraphemes = 300
+	maxPostBytes     = 3000
+)
+
+// SplitThreadText breaks text that is too long for one post into post-sized
+// pieces, preferring sentence boundaries.
+func SplitThreadText(text string) []string {
+	return richtext.Split(text, maxPostGraphemes, maxPostBytes)
+}
+
+// PostThread writes every post of a thread in one applyWrites commit, so a
+// thread is never left half-published. Replies need the CID of their parent,
+// which is computed locally from the record since nothing has been written
+// yet.
+func PostThread(ctx context.Context, client atproto.ATProtoClient, policy Policy, request models.ThreadRequestPayload) (*models.ThreadResponse, error) {
+	if request.AuthToken == "" || request.DID == "" {
+		err := errors.New("invalid request: missing 'authToken' or 'did'")
+		logrus.Error(err)
+		return nil, newError(CodeInvalidRequest, err)
+	}
+	if len(request.Posts) == 0 || len(request.Posts) > maxThreadPosts {
+		err := fmt.Errorf("invalid request: a thread must have between 1 and %d posts", maxThreadPosts)
+		logrus.WithField("DID", request.DID).Error(err)
+		return nil, newError(CodeInvalidRequest, err)
+	}
+
+	// The default clock hands out increasing TIDs, so the posts sort in
+	// thread order.
+	posts := make([]models.RequestPayload, len(request.Posts))
+	for i, p := range request.Posts {
+		if p.Post.IsStory {
+			return nil, newError(CodeInvalidRequest, fmt.Errorf("invalid request: post %d: threads cannot contain stories", i))
+		}
+		posts[i] = models.RequestPayload{
+			AuthToken:    request.AuthToken,
+			RefreshToken: request.RefreshToken,
+			DID:          request.DID,
+			Post:         p.Post,
+			Media:        p.Media,
+		}
+		if err := preparePost(&posts[i], policy); err != nil {
+			return nil, fmt.Errorf("post %d: %w", i, err)
+		}
+		if err := verifyMediaURIs(ctx, policy.MediaVerifier, posts[i].Post); err != nil {
+			return nil, fmt.Errorf("post %d: %w", i, err)
+		}
+	}
+
+	var reply *models.ReplyRef
+	if request.ReplyTo != "" {
+		var err error
+		if reply, err = resolveReply(ctx, client, request.ReplyTo); err != nil {
+			return nil, err
+		}
+	}
+
+	session := newSession(client, request.DID, request.AuthToken, request.RefreshToken)
+
+	writes := make([]models.ApplyWritesCreate, len(posts))
+	refs := make([]models.StrongRef, len(posts))
+	for i := range posts {
+		post := &posts[i].Post
+		post.Facets = richtext.DetectFacets(ctx, post.Text, client)
+
+		if err := uploadMedia(ctx, client, session, request.DID, posts[i].Media, post); err != nil {
+			logrus.WithError(err).WithField("DID", request.DID).Error("Failed to upload media")
+			return nil, upstreamError(fmt.Sprintf("post %d: uploading media failed", i), err)
+		}
+
+		post.Reply = reply
+
+		cid, err := dagcbor.CID(*post)
+		if err != nil {
+			logrus.WithError(err).WithField("DID", request.DID).Error("Failed to compute record CID")
+			return nil, newError(CodeInternal, fmt.Errorf("post %d: computing CID failed: %w", i, err))
+		}
+		refs[i] = models.StrongRef{
+			URI: atproto.ATURI{DID: request.DID, Collection: models.FeedPostNSID, RKey: posts[i].RKey}.String(),
+			CID: cid,
+		}
+		writes[i] = models.ApplyWritesCreate{
+			Type:       models.ApplyWritesCreateType,
+			Collection: models.FeedPostNSID,
+			RKey:       posts[i].RKey,
+			Value:      *post,
+		}
+
+		root := refs[0]
+		if reply != nil {
+			root = reply.Root
+		}
+		reply = &models.ReplyRef{Root: root, Parent: refs[i]}
+	}
+
+	var resp *models.ApplyWritesResponse
+	err := session.Do(ctx, func(accessJwt string) error {
+		var err error
+		resp, err = client.ApplyWrites(ctx, accessJwt, models.ApplyWritesRequest{Repo: request.DID, Writes: writes})
+		return err
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("DID", request.DID).Error("Failed to publish thread")
+		return nil, upstreamError("publishing thread failed", err)
+	}
+	if resp == nil {
+		logrus.Error("ApplyWrites returned nil response with no error")
+		return nil, newError(CodeUpstreamError, errors.New("no response returned from ATProto"))
+	}
+
+	thread := &models.ThreadResponse{Posts: make([]models.PostResponse, len(refs))}
+	for i, ref := range refs {
+		post := models.PostResponse{URI: ref.URI, CID: ref.CID}
+		if resp.Commit != nil {
+			post.Commit = *resp.Commit
+		}
+		if i < len(resp.Results) {
+			result := resp.Results[i]
+			post.ValidationStatus = result.ValidationStatus
+			if result.CID != "" && result.CID != ref.CID {
+				// The thread is written, but replies to this post point at a
+				// CID the PDS does not have.
+				logrus.WithFields(logrus.Fields{"uri": ref.URI, "expected": ref.CID, "actual": result.CID}).Error("Record CID differs from the computed CID")
+				post.CID = result.CID
+			}
+		}
+		thread.Posts[i] = post
+	}
+	thread.Session = refreshedSession(session)
+
+	logrus.WithFields(logrus.Fields{"DID": request.DID, "posts": len(refs), "root": refs[0].URI}).Info("Published thread")
+	return thread, nil
+}
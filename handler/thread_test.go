@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/atproto/dagcbor"
+	"github.com/ShareFrame/posting-service/atproto/tid"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *MockATProtoClient) ApplyWrites(ctx context.Context, authToken string, req models.ApplyWritesRequest) (*models.ApplyWritesResponse, error) {
+	args := m.Called(ctx, authToken, req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.ApplyWritesResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func threadRequest(texts ...string) models.ThreadRequestPayload {
+	request := models.ThreadRequestPayload{AuthToken: "access", DID: "did:plc:alice"}
+	for _, text := range texts {
+		request.Posts = append(request.Posts, models.ThreadPost{Post: models.ShareFrameFeedPost{
+			NSID:      models.FeedPostNSID,
+			Text:      text,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}})
+	}
+	return request
+}
+
+func TestPostThread(t *testing.T) {
+	const parentURI = "at://did:plc:bob/social.shareframe.feed.post/3jzfcijpj2z2b"
+	rootRef := models.StrongRef{URI: "at://did:plc:carol/social.shareframe.feed.post/3jzfcijpj2z2a", CID: "bafyreiroot"}
+	parentRef := models.StrongRef{URI: parentURI, CID: "bafyreiparent"}
+
+	tests := []struct {
+		name         string
+		request      models.ThreadRequestPayload
+		setupMock    func(m *MockATProtoClient, captured *models.ApplyWritesRequest)
+		expectedRoot *models.StrongRef
+		expectedCode ErrorCode
+		expectedErr  string
+	}{
+		{
+			name:    "New thread",
+			request: threadRequest("First.", "Second.", "Third."),
+			setupMock: func(m *MockATProtoClient, captured *models.ApplyWritesRequest) {
+				m.On("ApplyWrites", mock.Anything, "access", mock.Anything).
+					Run(func(args mock.Arguments) { *captured = args.Get(2).(models.ApplyWritesRequest) }).
+					Return(&models.ApplyWritesResponse{Commit: &models.Commit{CID: "commit123", Rev: "rev123"}}, nil).Once()
+			},
+		},
+		{
+			name: "Thread under an existing reply",
+			request: func() models.ThreadRequestPayload {
+				r := threadRequest("First.", "Second.")
+				r.ReplyTo = parentURI
+				return r
+			}(),
+			setupMock: func(m *MockATProtoClient, captured *models.ApplyWritesRequest) {
+				m.On("GetRecord", mock.Anything, "", "did:plc:bob", "3jzfcijpj2z2b").Return(&models.GetRecordResponse{
+					URI:   parentURI,
+					CID:   "bafyreiparent",
+					Value: models.ShareFrameFeedPost{Reply: &models.ReplyRef{Root: rootRef, Parent: rootRef}},
+				}, nil).Once()
+				m.On("ApplyWrites", mock.Anything, "access", mock.Anything).
+					Run(func(args mock.Arguments) { *captured = args.Get(2).(models.ApplyWritesRequest) }).
+					Return(&models.ApplyWritesResponse{}, nil).Once()
+			},
+			expectedRoot: &rootRef,
+		},
+		{
+			name:         "Invalid post aborts before anything is written",
+			request:      threadRequest("Fine.", strings.Repeat("a", 301)),
+			setupMock:    func(m *MockATProtoClient, captured *models.ApplyWritesRequest) {},
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "post 1: invalid post",
+		},
+		{
+			name: "Stories are rejected",
+			request: func() models.ThreadRequestPayload {
+				r := threadRequest("First.")
+				r.Posts[0].Post.IsStory = true
+				return r
+			}(),
+			setupMock:    func(m *MockATProtoClient, captured *models.ApplyWritesRequest) {},
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "threads cannot contain stories",
+		},
+		{
+			name:         "Too many posts",
+			request:      threadRequest(make([]string, maxThreadPosts+1)...),
+			setupMock:    func(m *MockATProtoClient, captured *models.ApplyWritesRequest) {},
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "between 1 and 25 posts",
+		},
+		{
+			name:    "Upstream failure",
+			request: threadRequest("First.", "Second."),
+			setupMock: func(m *MockATProtoClient, captured *models.ApplyWritesRequest) {
+				m.On("ApplyWrites", mock.Anything, "access", mock.Anything).
+					Return(nil, &atproto.XRPCError{StatusCode: 400, ErrorName: "InvalidRequest"}).Once()
+			},
+			expectedCode: CodeInvalidRequest,
+			expectedErr:  "publishing thread failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured models.ApplyWritesRequest
+			mockClient := new(MockATProtoClient)
+			tt.setupMock(mockClient, &captured)
+
+			resp, err := PostThread(context.Background(), mockClient, Policy{}, tt.request)
+
+			mockClient.AssertExpectations(t)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				assert.Equal(t, tt.expectedCode, ErrorCodeOf(err))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, captured.Writes, len(tt.request.Posts))
+			require.Len(t, resp.Posts, len(tt.request.Posts))
+			assert.Equal(t, "did:plc:alice", captured.Repo)
+
+			var prev *models.StrongRef
+			for i, write := range captured.Writes {
+				assert.Equal(t, models.ApplyWritesCreateType, write.Type)
+				assert.True(t, tid.Valid(write.RKey))
+				if i > 0 {
+					assert.Greater(t, write.RKey, captured.Writes[i-1].RKey)
+				}
+
+				cid, err := dagcbor.CID(write.Value)
+				require.NoError(t, err)
+				ref := models.StrongRef{URI: "at://did:plc:alice/social.shareframe.feed.post/" + write.RKey, CID: cid}
+				assert.Equal(t, ref.URI, resp.Posts[i].URI)
+				assert.Equal(t, ref.CID, resp.Posts[i].CID)
+
+				switch {
+				case i == 0 && tt.expectedRoot == nil:
+					assert.Nil(t, write.Value.Reply)
+				case i == 0:
+					assert.Equal(t, &models.ReplyRef{Root: *tt.expectedRoot, Parent: parentRef}, write.Value.Reply)
+				default:
+					root := resp.Posts[0]
+					expectedRoot := models.StrongRef{URI: root.URI, CID: root.CID}
+					if tt.expectedRoot != nil {
+						expectedRoot = *tt.expectedRoot
+					}
+					assert.Equal(t, &models.ReplyRef{Root: expectedRoot, Parent: *prev}, write.Value.Reply)
+				}
+				prev = &ref
+			}
+		})
+	}
+}
+
+func TestPostThreadReportsPDSCID(t *testing.T) {
+	mockClient := new(MockATProtoClient)
+	mockClient.On("ApplyWrites", mock.Anything, "access", mock.Anything).Return(&models.ApplyWritesResponse{
+		Results: []models.ApplyWritesResult{{Type: "com.atproto.repo.applyWrites#createResult", CID: "bafyreiother", ValidationStatus: "valid"}},
+	}, nil).Once()
+
+	resp, err := PostThread(context.Background(), mockClient, Policy{}, threadRequest("Only."))
+
+	require.NoError(t, err)
+	assert.Equal(t, "bafyreiother", resp.Posts[0].CID)
+	assert.Equal(t, "valid", resp.Posts[0].ValidationStatus)
+}
+
+func TestSplitThreadText(t *testing.T) {
+	text := strings.Repeat("This sentence is exactly forty-one chars. ", 20)
+
+	chunks := SplitThreadText(text)
+
+	require.Len(t, chunks, 3)
+	for _, chunk := range chunks {
+		assert.True(t, strings.HasSuffix(chunk, "chars."))
+		require.NoError(t, validatePost(models.ShareFrameFeedPost{
+			NSID:      models.FeedPostNSID,
+			Text:      chunk,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		}, strictValidation))
+	}
+}
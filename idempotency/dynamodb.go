@@ -0,0 +1,187 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	reserveCondition  = "attribute_not_exists(pk) OR expiresAt <= :now"
+	completeCondition = "attribute_exists(pk) AND expiresAt > :now"
+	completeUpdate    = "SET #status = :status, #response = :response"
+)
+
+// DynamoDBAPI is the part of *dynamodb.Client that DynamoStore uses.
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, in *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, in *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoStore keeps records in a table keyed by the string attribute "pk",
+// so every Lambda instance sees the same reservations. expiresAt holds epoch
+// seconds so it can be the table's TTL attribute; TTL deletion lags, so the
+// conditions check it as well.
+type DynamoStore struct {
+	client DynamoDBAPI
+	table  string
+	now    func() time.Time
+}
+
+func NewDynamoStore(client DynamoDBAPI, table string) *DynamoStore {
+	return &DynamoStore{client: client, table: table, now: time.Now}
+}
+
+func (s *DynamoStore) Reserve(ctx context.Context, rec Record) (*Record, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:                           aws.String(s.table),
+			Item:                                toItem(rec),
+			ConditionExpression:                 aws.String(reserveCondition),
+			ExpressionAttributeValues:           map[string]types.AttributeValue{":now": epochSeconds(s.now())},
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		})
+		if err == nil {
+			return nil, nil
+		}
+
+		var failed *types.ConditionalCheckFailedException
+		if !errors.As(err, &failed) {
+			logrus.WithError(err).WithField("table", s.table).Error("Failed to reserve idempotency key")
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		if failed.Item != nil {
+			return fromItem(failed.Item)
+		}
+
+		existing, err := s.get(ctx, rec.Key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return existing, err
+	}
+	return nil, fmt.Errorf("failed to reserve idempotency key %q: concurrent writers", rec.Key)
+}
+
+func (s *DynamoStore) Complete(ctx context.Context, key string, response json.RawMessage) error {
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(s.table),
+		Key:                      itemKey(key),
+		UpdateExpression:         aws.String(completeUpdate),
+		ConditionExpression:      aws.String(completeCondition),
+		ExpressionAttributeNames: map[string]string{"#status": "status", "#response": "response"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":   &types.AttributeValueMemberS{Value: string(StatusComplete)},
+			":response": &types.AttributeValueMemberS{Value: string(response)},
+			":now":      epochSeconds(s.now()),
+		},
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return ErrNotFound
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("table", s.table).Error("Failed to complete idempotency record")
+		return fmt.Errorf("failed to complete idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoStore) Release(ctx context.Context, key string) error {
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.table),
+		Key:       itemKey(key),
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("table", s.table).Error("Failed to release idempotency key")
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoStore) get(ctx context.Context, key string) (*Record, error) {
+	out, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            itemKey(key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("table", s.table).Error("Failed to read idempotency record")
+		return nil, fmt.Errorf("failed to read idempotency record: %w", err)
+	}
+	if out.Item == nil {
+		return nil, ErrNotFound
+	}
+	return fromItem(out.Item)
+}
+
+func itemKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: key}}
+}
+
+func epochSeconds(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
+
+func toItem(rec Record) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		"pk":          &types.AttributeValueMemberS{Value: rec.Key},
+		"fingerprint": &types.AttributeValueMemberS{Value: rec.Fingerprint},
+		"rkey":        &types.AttributeValueMemberS{Value: rec.RKey},
+		"status":      &types.AttributeValueMemberS{Value: string(rec.Status)},
+		"createdAt":   &types.AttributeValueMemberN{Value: strconv.FormatInt(rec.CreatedAt.UnixMilli(), 10)},
+		"expiresAt":   epochSeconds(rec.ExpiresAt),
+	}
+	if len(rec.Response) > 0 {
+		item["response"] = &types.AttributeValueMemberS{Value: string(rec.Response)}
+	}
+	return item
+}
+
+func fromItem(item map[string]types.AttributeValue) (*Record, error) {
+	createdAt, err := strconv.ParseInt(number(item, "createdAt"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse idempotency record: createdAt: %w", err)
+	}
+	expiresAt, err := strconv.ParseInt(number(item, "expiresAt"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse idempotency record: expiresAt: %w", err)
+	}
+
+	rec := &Record{
+		Key:         str(item, "pk"),
+		Fingerprint: str(item, "fingerprint"),
+		RKey:        str(item, "rkey"),
+		Status:      Status(str(item, "status")),
+		CreatedAt:   time.UnixMilli(createdAt),
+		ExpiresAt:   time.Unix(expiresAt, 0),
+	}
+	if response := str(item, "response"); response != "" {
+		rec.Response = json.RawMessage(response)
+	}
+	return rec, nil
+}
+
+func str(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+func number(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberN); ok {
+		return v.Value
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDynamoDB evaluates the store's condition expressions against an
+// in-memory table.
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+	err   error
+	// omitOld drops the old item from condition failures, as DynamoDB does
+	// when the item was deleted by the time the error is built.
+	omitOld bool
+}
+
+func newFakeDynamoDB() *fakeDynamoDB {
+	return &fakeDynamoDB{items: make(map[string]map[string]types.AttributeValue)}
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &dynamodb.GetItemOutput{Item: f.items[str(in.Key, "pk")]}, nil
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	key := str(in.Item, "pk")
+	existing, exists := f.items[key]
+	if aws.ToString(in.ConditionExpression) != reserveCondition {
+		return nil, errors.New("unsupported condition: " + aws.ToString(in.ConditionExpression))
+	}
+	if exists && f.expiresAfter(existing, in.ExpressionAttributeValues) {
+		return nil, f.conditionFailed(existing, in.ReturnValuesOnConditionCheckFailure)
+	}
+
+	f.items[key] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	if aws.ToString(in.ConditionExpression) != completeCondition || aws.ToString(in.UpdateExpression) != completeUpdate {
+		return nil, errors.New("unsupported update: " + aws.ToString(in.UpdateExpression))
+	}
+
+	existing, exists := f.items[str(in.Key, "pk")]
+	if !exists || !f.expiresAfter(existing, in.ExpressionAttributeValues) {
+		return nil, f.conditionFailed(existing, in.ReturnValuesOnConditionCheckFailure)
+	}
+	existing[in.ExpressionAttributeNames["#status"]] = in.ExpressionAttributeValues[":status"]
+	existing[in.ExpressionAttributeNames["#response"]] = in.ExpressionAttributeValues[":response"]
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(ctx context.Context, in *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	delete(f.items, str(in.Key, "pk"))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) expiresAfter(item, values map[string]types.AttributeValue) bool {
+	expiresAt, _ := strconv.ParseInt(number(item, "expiresAt"), 10, 64)
+	now, _ := strconv.ParseInt(number(values, ":now"), 10, 64)
+	return expiresAt > now
+}
+
+func (f *fakeDynamoDB) conditionFailed(item map[string]types.AttributeValue, returnValues types.ReturnValuesOnConditionCheckFailure) error {
+	err := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	if returnValues == types.ReturnValuesOnConditionCheckFailureAllOld && !f.omitOld {
+		err.Item = item
+	}
+	return err
+}
+
+func TestDynamoStore(t *testing.T) {
+	testStore(t, func(now func() time.Time) Store {
+		store := NewDynamoStore(newFakeDynamoDB(), "idempotency")
+		store.now = now
+		return store
+	})
+}
+
+func TestDynamoStoreReadsBackWithoutOldItem(t *testing.T) {
+	db := newFakeDynamoDB()
+	db.omitOld = true
+	store := NewDynamoStore(db, "idempotency")
+	expiresAt := time.Now().Add(time.Hour)
+
+	existing, err := store.Reserve(context.Background(), Record{Key: "k", RKey: "rkey1", Status: StatusPending, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	assert.Nil(t, existing)
+
+	existing, err = store.Reserve(context.Background(), Record{Key: "k", RKey: "rkey2", Status: StatusPending, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, "rkey1", existing.RKey)
+}
+
+func TestDynamoStoreItemShape(t *testing.T) {
+	db := newFakeDynamoDB()
+	store := NewDynamoStore(db, "idempotency")
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	_, err := store.Reserve(context.Background(), Record{
+		Key:       "did:plc:alice/k",
+		RKey:      "3kq2ve7ruvk2a",
+		Status:    StatusPending,
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+
+	item := db.items["did:plc:alice/k"]
+	assert.Equal(t, "3kq2ve7ruvk2a", str(item, "rkey"))
+	assert.Equal(t, "pending", str(item, "status"))
+	assert.Equal(t, strconv.FormatInt(expiresAt.Unix(), 10), number(item, "expiresAt"))
+	assert.NotContains(t, item, "response")
+}
+
+func TestDynamoStoreClientError(t *testing.T) {
+	db := newFakeDynamoDB()
+	db.err = errors.New("throttled")
+	store := NewDynamoStore(db, "idempotency")
+
+	_, err := store.Reserve(context.Background(), Record{Key: "k"})
+	assert.ErrorContains(t, err, "throttled")
+	assert.ErrorContains(t, store.Complete(context.Background(), "k", nil), "throttled")
+	assert.ErrorContains(t, store.Release(context.Background(), "k"), "throttled")
+}
@@ -0,0 +1,76 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ShareFrame/posting-service/jsonstore"
+)
+
+var errTaken = errors.New("idempotency key is taken")
+
+// FileStore keeps one JSON file per key in a directory, which processes may
+// share: a record is only replaced while holding the directory lock, after
+// re-reading it to check that it has expired.
+type FileStore struct {
+	records jsonstore.Store[Record]
+	now     func() time.Time
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	records, err := jsonstore.NewFile[Record](dir, jsonstore.Config{Name: "idempotency record", ErrNotFound: ErrNotFound})
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{records: records, now: time.Now}, nil
+}
+
+func (s *FileStore) Reserve(ctx context.Context, rec Record) (*Record, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		err := s.records.Create(ctx, rec.Key, rec)
+		if !errors.Is(err, jsonstore.ErrExists) {
+			return nil, err
+		}
+
+		var existing Record
+		_, err = s.records.Update(ctx, rec.Key, func(r *Record) error {
+			if !r.expired(s.now()) {
+				existing = *r
+				return errTaken
+			}
+			*r = rec
+			return nil
+		})
+		switch {
+		case errors.Is(err, errTaken):
+			return &existing, nil
+		case errors.Is(err, ErrNotFound):
+			// Released between Create and Update.
+			continue
+		}
+		return nil, err
+	}
+	return nil, fmt.Errorf("failed to reserve idempotency key %q: concurrent writers", rec.Key)
+}
+
+func (s *FileStore) Complete(ctx context.Context, key string, response json.RawMessage) error {
+	_, err := s.records.Update(ctx, key, func(r *Record) error {
+		if r.expired(s.now()) {
+			return ErrNotFound
+		}
+		r.Status = StatusComplete
+		r.Response = response
+		return nil
+	})
+	return err
+}
+
+func (s *FileStore) Release(ctx context.Context, key string) error {
+	if err := s.records.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Expired records are dropped at most once per sweepInterval, as keys are
+// reserved.
+const sweepInterval = time.Minute
+
+// MemoryStore only dedupes retries that reach the same process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	now     func() time.Time
+	swept   time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record), now: time.Now}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, rec Record) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	if existing, ok := s.records[rec.Key]; ok && !existing.expired(now) {
+		return &existing, nil
+	}
+	s.records[rec.Key] = rec
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key string, response json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok || rec.expired(s.now()) {
+		return ErrNotFound
+	}
+	rec.Status = StatusComplete
+	rec.Response = response
+	s.records[key] = rec
+	return nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+	for key, rec := range s.records {
+		if rec.expired(now) {
+			delete(s.records, key)
+		}
+	}
+}
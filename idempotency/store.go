@@ -0,0 +1,42 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const DefaultTTL = 24 * time.Hour
+
+var ErrNotFound = errors.New("idempotency record not found")
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusComplete Status = "complete"
+)
+
+type Record struct {
+	Key         string          `json:"key"`
+	Fingerprint string          `json:"fingerprint"`
+	RKey        string          `json:"rkey"`
+	Status      Status          `json:"status"`
+	Response    json.RawMessage `json:"response,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	ExpiresAt   time.Time       `json:"expiresAt"`
+}
+
+func (r Record) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// Store remembers which idempotency keys have been seen. Reserve saves rec
+// and returns nil unless an unexpired record with the same key already exists,
+// in which case that record is returned and nothing is written.
+type Store interface {
+	Reserve(ctx context.Context, rec Record) (*Record, error)
+	Complete(ctx context.Context, key string, response json.RawMessage) error
+	Release(ctx context.Context, key string) error
+}
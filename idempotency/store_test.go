@@ -0,0 +1,222 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func testStore(t *testing.T, newStore func(now func() time.Time) Store) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := func(key, rkey string) Record {
+		return Record{
+			Key:         key,
+			Fingerprint: "fp-" + key,
+			RKey:        rkey,
+			Status:      StatusPending,
+			CreatedAt:   start,
+			ExpiresAt:   start.Add(DefaultTTL),
+		}
+	}
+
+	t.Run("First reservation wins", func(t *testing.T) {
+		store := newStore((&clock{start}).now)
+
+		existing, err := store.Reserve(ctx, record("did:plc:alice/k1", "rkey1"))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+
+		existing, err = store.Reserve(ctx, record("did:plc:alice/k1", "rkey2"))
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, "rkey1", existing.RKey)
+		assert.Equal(t, StatusPending, existing.Status)
+		assert.Equal(t, "fp-did:plc:alice/k1", existing.Fingerprint)
+
+		existing, err = store.Reserve(ctx, record("did:plc:bob/k1", "rkey3"))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("Completed record returns the response", func(t *testing.T) {
+		store := newStore((&clock{start}).now)
+
+		_, err := store.Reserve(ctx, record("k", "rkey1"))
+		require.NoError(t, err)
+		require.NoError(t, store.Complete(ctx, "k", json.RawMessage(`{"uri":"at://x"}`)))
+
+		existing, err := store.Reserve(ctx, record("k", "rkey2"))
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, StatusComplete, existing.Status)
+		assert.JSONEq(t, `{"uri":"at://x"}`, string(existing.Response))
+	})
+
+	t.Run("Released key can be reserved again", func(t *testing.T) {
+		store := newStore((&clock{start}).now)
+
+		_, err := store.Reserve(ctx, record("k", "rkey1"))
+		require.NoError(t, err)
+		require.NoError(t, store.Release(ctx, "k"))
+		require.NoError(t, store.Release(ctx, "k"))
+
+		existing, err := store.Reserve(ctx, record("k", "rkey2"))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("Expired record is replaced", func(t *testing.T) {
+		c := &clock{start}
+		store := newStore(c.now)
+
+		_, err := store.Reserve(ctx, record("k", "rkey1"))
+		require.NoError(t, err)
+
+		c.t = start.Add(DefaultTTL)
+		existing, err := store.Reserve(ctx, record("k", "rkey2"))
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+	})
+
+	t.Run("Completing an unknown key", func(t *testing.T) {
+		store := newStore((&clock{start}).now)
+
+		assert.ErrorIs(t, store.Complete(ctx, "missing", json.RawMessage(`{}`)), ErrNotFound)
+	})
+
+	t.Run("Concurrent reservations", func(t *testing.T) {
+		store := newStore((&clock{start}).now)
+
+		var wg sync.WaitGroup
+		results := make(chan *Record, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				existing, err := store.Reserve(ctx, record("k", "rkey"))
+				assert.NoError(t, err)
+				results <- existing
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		reserved := 0
+		for existing := range results {
+			if existing == nil {
+				reserved++
+			}
+		}
+		assert.Equal(t, 1, reserved)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(now func() time.Time) Store {
+		store := NewMemoryStore()
+		store.now = now
+		return store
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, func(now func() time.Time) Store {
+		store, err := NewFileStore(t.TempDir())
+		require.NoError(t, err)
+		store.now = now
+		return store
+	})
+}
+
+func TestFileStorePersistsAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	first, err := NewFileStore(dir)
+	require.NoError(t, err)
+	_, err = first.Reserve(ctx, Record{Key: "k", RKey: "rkey1", Status: StatusPending, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, first.Complete(ctx, "k", json.RawMessage(`{"cid":"bafy"}`)))
+
+	second, err := NewFileStore(dir)
+	require.NoError(t, err)
+	existing, err := second.Reserve(ctx, Record{Key: "k", RKey: "rkey2", Status: StatusPending, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+	assert.Equal(t, "rkey1", existing.RKey)
+	assert.JSONEq(t, `{"cid":"bafy"}`, string(existing.Response))
+}
+
+func TestMemoryStoreSweepsExpiredRecords(t *testing.T) {
+	ctx := context.Background()
+	c := &clock{time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = c.now
+
+	_, err := store.Reserve(ctx, Record{Key: "old", ExpiresAt: c.t.Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = store.Reserve(ctx, Record{Key: "new", ExpiresAt: c.t.Add(3 * time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, store.records, 2)
+
+	c.t = c.t.Add(2 * time.Hour)
+	_, err = store.Reserve(ctx, Record{Key: "newer", ExpiresAt: c.t.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.NotContains(t, store.records, "old")
+	assert.Contains(t, store.records, "new")
+	assert.Len(t, store.records, 2)
+}
+
+func TestFileStoreReplacesExpiredRecordOnce(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c := &clock{time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	// Separate instances stand in for separate processes sharing the
+	// directory.
+	var stores []*FileStore
+	for i := 0; i < 4; i++ {
+		store, err := NewFileStore(dir)
+		require.NoError(t, err)
+		store.now = c.now
+		stores = append(stores, store)
+	}
+	_, err := stores[0].Reserve(ctx, Record{Key: "k", RKey: "expired", ExpiresAt: c.t.Add(-time.Minute)})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make(chan *Record, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			existing, err := stores[i%len(stores)].Reserve(ctx, Record{Key: "k", RKey: "fresh", ExpiresAt: c.t.Add(time.Hour)})
+			assert.NoError(t, err)
+			results <- existing
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	reserved := 0
+	for existing := range results {
+		if existing == nil {
+			reserved++
+			continue
+		}
+		assert.Equal(t, "fresh", existing.RKey)
+	}
+	assert.Equal(t, 1, reserved)
+}
@@ -0,0 +1,193 @@
+package jsonstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// File keeps one JSON file per value in a directory, named after a hash of
+// the key. Every file is replaced with a rename, so readers never see a
+// partial write. Writers hold an flock on the directory's .lock file, so
+// processes sharing the directory, such as Lambdas mounting the same EFS
+// volume, see each Create and Update as atomic.
+type File[T any] struct {
+	mu   sync.Mutex
+	cfg  Config
+	dir  string
+	lock *os.File
+}
+
+func NewFile[T any](dir string, cfg Config) (*File[T], error) {
+	cfg = cfg.withDefaults()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		logrus.WithError(err).WithField("dir", dir).Errorf("Failed to create %s directory", cfg.Name)
+		return nil, fmt.Errorf("failed to create %s directory: %w", cfg.Name, err)
+	}
+	lock, err := os.OpenFile(filepath.Join(dir, ".lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		logrus.WithError(err).WithField("dir", dir).Errorf("Failed to open %s lock file", cfg.Name)
+		return nil, fmt.Errorf("failed to open %s lock file: %w", cfg.Name, err)
+	}
+	return &File[T]{cfg: cfg, dir: dir, lock: lock}, nil
+}
+
+func (s *File[T]) Create(ctx context.Context, key string, value T) error {
+	unlock, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := s.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return s.cfg.ErrExists
+	}
+	return s.write(path, value)
+}
+
+func (s *File[T]) Put(ctx context.Context, key string, value T) error {
+	unlock, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.write(s.path(key), value)
+}
+
+func (s *File[T]) Get(ctx context.Context, key string) (*T, error) {
+	return s.read(s.path(key))
+}
+
+func (s *File[T]) List(ctx context.Context) ([]T, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %ss: %w", s.cfg.Name, err)
+	}
+
+	var values []T
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		value, err := s.read(filepath.Join(s.dir, f.Name()))
+		if errors.Is(err, s.cfg.ErrNotFound) {
+			// Deleted since the directory was read.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, *value)
+	}
+	return values, nil
+}
+
+func (s *File[T]) Update(ctx context.Context, key string, fn func(*T) error) (*T, error) {
+	unlock, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	path := s.path(key)
+	value, err := s.read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(value); err != nil {
+		return nil, err
+	}
+	if err := s.write(path, *value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *File[T]) Delete(ctx context.Context, key string) error {
+	unlock, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.cfg.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", s.cfg.Name, err)
+	}
+	return nil
+}
+
+// acquire takes the in-process mutex, then the directory's flock.
+func (s *File[T]) acquire() (func(), error) {
+	s.mu.Lock()
+	if err := lockFile(s.lock); err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to lock %s directory: %w", s.cfg.Name, err)
+	}
+	return func() {
+		unlockFile(s.lock)
+		s.mu.Unlock()
+	}, nil
+}
+
+func (s *File[T]) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *File[T]) read(path string) (*T, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s.cfg.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.cfg.Name, err)
+	}
+
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to parse %s %s: %w", s.cfg.Name, filepath.Base(path), err)
+	}
+	return &value, nil
+}
+
+func (s *File[T]) write(path string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", s.cfg.Name, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", s.cfg.Name, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save %s: %w", s.cfg.Name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save %s: %w", s.cfg.Name, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save %s: %w", s.cfg.Name, err)
+	}
+	return nil
+}
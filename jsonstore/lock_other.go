@@ -0,0 +1,15 @@
+//go:build !unix
+
+package jsonstore
+
+import "os"
+
+// Without flock, writers are only serialized within a process.
+
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package jsonstore
+
+import (
+	"context"
+	"sync"
+)
+
+type Memory[T any] struct {
+	mu     sync.Mutex
+	cfg    Config
+	values map[string]T
+}
+
+func NewMemory[T any](cfg Config) *Memory[T] {
+	return &Memory[T]{cfg: cfg.withDefaults(), values: make(map[string]T)}
+}
+
+func (s *Memory[T]) Create(ctx context.Context, key string, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; ok {
+		return s.cfg.ErrExists
+	}
+	s.values[key] = value
+	return nil
+}
+
+func (s *Memory[T]) Put(ctx context.Context, key string, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	return nil
+}
+
+func (s *Memory[T]) Get(ctx context.Context, key string) (*T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return nil, s.cfg.ErrNotFound
+	}
+	return &value, nil
+}
+
+func (s *Memory[T]) List(ctx context.Context) ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make([]T, 0, len(s.values))
+	for _, v := range s.values {
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (s *Memory[T]) Update(ctx context.Context, key string, fn func(*T) error) (*T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return nil, s.cfg.ErrNotFound
+	}
+	if err := fn(&value); err != nil {
+		return nil, err
+	}
+	s.values[key] = value
+	return &value, nil
+}
+
+func (s *Memory[T]) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; !ok {
+		return s.cfg.ErrNotFound
+	}
+	delete(s.values, key)
+	return nil
+}
@@ -0,0 +1,48 @@
+// Package jsonstore keeps values by key, in memory or as one JSON file per
+// value in a directory. The schedule, expiry and draft stores are built on it.
+package jsonstore
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+)
+
+// Config names the stored values in error messages. ErrNotFound and
+// ErrExists, when set, are returned in place of the package's own errors so
+// callers keep their existing sentinel errors.
+type Config struct {
+	Name        string
+	ErrNotFound error
+	ErrExists   error
+}
+
+func (c Config) withDefaults() Config {
+	if c.Name == "" {
+		c.Name = "value"
+	}
+	if c.ErrNotFound == nil {
+		c.ErrNotFound = ErrNotFound
+	}
+	if c.ErrExists == nil {
+		c.ErrExists = ErrExists
+	}
+	return c
+}
+
+// Store holds values of type T by key. Create fails with ErrExists when the
+// key is taken, while Put replaces whatever is there. Update loads a value,
+// applies fn to it and saves the result atomically; when fn returns an error
+// nothing is saved and that error is returned.
+type Store[T any] interface {
+	Create(ctx context.Context, key string, value T) error
+	Put(ctx context.Context, key string, value T) error
+	Get(ctx context.Context, key string) (*T, error)
+	List(ctx context.Context) ([]T, error)
+	Update(ctx context.Context, key string, fn func(*T) error) (*T, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,179 @@
+package jsonstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type note struct {
+	Text  string   `json:"text"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+var (
+	errNoNote     = errors.New("note not found")
+	errNoteExists = errors.New("note already exists")
+)
+
+func TestStores(t *testing.T) {
+	cfg := Config{Name: "note", ErrNotFound: errNoNote, ErrExists: errNoteExists}
+
+	stores := []struct {
+		name     string
+		newStore func(t *testing.T) Store[note]
+	}{
+		{name: "Memory", newStore: func(t *testing.T) Store[note] { return NewMemory[note](cfg) }},
+		{name: "File", newStore: func(t *testing.T) Store[note] {
+			store, err := NewFile[note](t.TempDir(), cfg)
+			require.NoError(t, err)
+			return store
+		}},
+	}
+
+	ctx := context.Background()
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			t.Run("Create and get", func(t *testing.T) {
+				store := s.newStore(t)
+
+				require.NoError(t, store.Create(ctx, "did:plc:alice/a", note{Text: "first", Tags: []string{"x"}}))
+				assert.ErrorIs(t, store.Create(ctx, "did:plc:alice/a", note{Text: "second"}), errNoteExists)
+
+				got, err := store.Get(ctx, "did:plc:alice/a")
+				require.NoError(t, err)
+				assert.Equal(t, note{Text: "first", Tags: []string{"x"}}, *got)
+
+				_, err = store.Get(ctx, "did:plc:bob/a")
+				assert.ErrorIs(t, err, errNoNote)
+			})
+
+			t.Run("Put replaces", func(t *testing.T) {
+				store := s.newStore(t)
+
+				require.NoError(t, store.Put(ctx, "a", note{Text: "first"}))
+				require.NoError(t, store.Put(ctx, "a", note{Text: "second"}))
+
+				got, err := store.Get(ctx, "a")
+				require.NoError(t, err)
+				assert.Equal(t, "second", got.Text)
+			})
+
+			t.Run("List", func(t *testing.T) {
+				store := s.newStore(t)
+
+				all, err := store.List(ctx)
+				require.NoError(t, err)
+				assert.Empty(t, all)
+
+				require.NoError(t, store.Create(ctx, "a", note{Text: "a"}))
+				require.NoError(t, store.Create(ctx, "b", note{Text: "b"}))
+
+				all, err = store.List(ctx)
+				require.NoError(t, err)
+				assert.ElementsMatch(t, []note{{Text: "a"}, {Text: "b"}}, all)
+			})
+
+			t.Run("Update", func(t *testing.T) {
+				store := s.newStore(t)
+				require.NoError(t, store.Create(ctx, "a", note{Text: "a"}))
+
+				updated, err := store.Update(ctx, "a", func(n *note) error {
+					n.Count++
+					return nil
+				})
+				require.NoError(t, err)
+				assert.Equal(t, 1, updated.Count)
+
+				errAbort := errors.New("abort")
+				_, err = store.Update(ctx, "a", func(n *note) error {
+					n.Count = 100
+					return errAbort
+				})
+				assert.ErrorIs(t, err, errAbort)
+
+				got, err := store.Get(ctx, "a")
+				require.NoError(t, err)
+				assert.Equal(t, 1, got.Count)
+
+				_, err = store.Update(ctx, "missing", func(n *note) error { return nil })
+				assert.ErrorIs(t, err, errNoNote)
+			})
+
+			t.Run("Delete", func(t *testing.T) {
+				store := s.newStore(t)
+				require.NoError(t, store.Create(ctx, "a", note{Text: "a"}))
+
+				assert.ErrorIs(t, store.Delete(ctx, "b"), errNoNote)
+				require.NoError(t, store.Delete(ctx, "a"))
+				assert.ErrorIs(t, store.Delete(ctx, "a"), errNoNote)
+
+				_, err := store.Get(ctx, "a")
+				assert.ErrorIs(t, err, errNoNote)
+			})
+		})
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	store := NewMemory[note](Config{})
+
+	_, err := store.Get(context.Background(), "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, store.Create(context.Background(), "a", note{}))
+	assert.ErrorIs(t, store.Create(context.Background(), "a", note{}), ErrExists)
+}
+
+func TestFileIgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFile[note](dir, Config{Name: "note"})
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "a", note{Text: "a"}))
+	require.NoError(t, os.WriteFile(dir+"/.tmp-123", []byte("partial"), 0o600))
+	require.NoError(t, os.Mkdir(dir+"/nested.json", 0o700))
+
+	all, err := store.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []note{{Text: "a"}}, all)
+}
+
+func TestFileUpdatesAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Each instance has its own mutex, so only the directory lock keeps
+	// their read-modify-write cycles from interleaving.
+	var stores []*File[note]
+	for i := 0; i < 4; i++ {
+		store, err := NewFile[note](dir, Config{Name: "note"})
+		require.NoError(t, err)
+		stores = append(stores, store)
+	}
+	require.NoError(t, stores[0].Create(ctx, "counter", note{}))
+
+	var wg sync.WaitGroup
+	for _, store := range stores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				_, err := store.Update(ctx, "counter", func(n *note) error {
+					n.Count++
+					return nil
+				})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := stores[0].Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, 100, got.Count)
+}
@@ -0,0 +1,219 @@
+// Package lease tracks work that is handed to one worker at a time and
+// retried with backoff until it succeeds or runs out of attempts. Scheduled
+// posts and expiring stories both embed its State.
+package lease
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusFailed  Status = "failed"
+	// StatusNeedsAuth parks an entry whose attempts cannot succeed until its
+	// author authorizes the service again. It is not due until Resume.
+	StatusNeedsAuth Status = "needs_auth"
+)
+
+var (
+	errNotDue    = errors.New("entry is not due")
+	errLeaseLost = errors.New("entry changed while it was attempted")
+)
+
+// State is the bookkeeping embedded in every leased entry. A claimed entry
+// keeps StatusPending but is not due again until its lease runs out, so a
+// worker that dies mid-attempt only delays the work.
+type State struct {
+	Status        Status    `json:"status"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"lastError,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// Lease returns s, which lets Run and Due reach the State embedded in an
+// entry.
+func (s *State) Lease() *State {
+	return s
+}
+
+func (s *State) Due(now time.Time) bool {
+	return s.Status == StatusPending && !s.NextAttemptAt.After(now)
+}
+
+// Resume makes an entry parked with StatusNeedsAuth due at now. It reports
+// whether the entry was parked.
+func (s *State) Resume(now time.Time) bool {
+	if s.Status != StatusNeedsAuth {
+		return false
+	}
+	s.Status = StatusPending
+	s.NextAttemptAt = now
+	s.UpdatedAt = now
+	return true
+}
+
+// Entry is satisfied by a pointer to any struct that embeds State.
+type Entry[T any] interface {
+	*T
+	Lease() *State
+}
+
+// Due returns the entries that are due at now, those waiting longest first,
+// up to limit when it is positive.
+func Due[T any, P Entry[T]](entries []T, now time.Time, limit int) []T {
+	var due []T
+	for i := range entries {
+		if P(&entries[i]).Lease().Due(now) {
+			due = append(due, entries[i])
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return P(&due[i]).Lease().NextAttemptAt.Before(P(&due[j]).Lease().NextAttemptAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
+
+// Job is one pass over the due entries of a store.
+type Job[T any] struct {
+	// Name describes an entry in log messages, e.g. "scheduled post".
+	Name        string
+	BatchSize   int
+	MaxAttempts int
+	// Lease is how long a claimed entry is hidden from other workers.
+	Lease time.Duration
+	// RetryDelay is doubled after every failed attempt.
+	RetryDelay time.Duration
+	// Done is the status an entry moves to once Attempt succeeds.
+	Done Status
+
+	Due    func(ctx context.Context, now time.Time, limit int) ([]T, error)
+	Update func(ctx context.Context, entry T, fn func(*T) error) (*T, error)
+
+	// Attempt does the work for a claimed entry. The returned function, when
+	// not nil, is applied to the entry as the outcome is saved.
+	Attempt func(ctx context.Context, entry *T) (func(*T), error)
+	// Retryable reports whether a failed attempt may be tried again.
+	Retryable func(err error) bool
+	// NeedsAuth reports whether a failed attempt was refused for want of
+	// authorization, which parks the entry instead of failing it.
+	NeedsAuth func(err error) bool
+	// Fields identify an entry in log messages.
+	Fields func(entry T) logrus.Fields
+	// Finished is called once an attempt's outcome is saved, for logging.
+	Finished func(entry *T, status Status, err error)
+}
+
+type Result struct {
+	Done      int
+	Retrying  int
+	NeedsAuth int
+	Failed    int
+}
+
+// Run claims each due entry, attempts it and saves the outcome. It only
+// returns an error when the due entries cannot be listed.
+func Run[T any, P Entry[T]](ctx context.Context, job Job[T], now time.Time) (*Result, error) {
+	due, err := job.Due(ctx, now, job.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, candidate := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		entry, err := job.Update(ctx, candidate, func(e *T) error {
+			state := P(e).Lease()
+			if !state.Due(now) {
+				return errNotDue
+			}
+			state.Attempts++
+			state.NextAttemptAt = now.Add(job.Lease)
+			state.UpdatedAt = now
+			return nil
+		})
+		if errors.Is(err, errNotDue) {
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).WithFields(job.Fields(candidate)).Errorf("Failed to claim %s", job.Name)
+			continue
+		}
+
+		switch finish[T, P](ctx, job, entry, now) {
+		case "":
+		case job.Done:
+			result.Done++
+		case StatusPending:
+			result.Retrying++
+		case StatusNeedsAuth:
+			result.NeedsAuth++
+		default:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// finish attempts a claimed entry and saves the outcome. It returns "" when
+// the entry was canceled or rescheduled during the attempt, which then wins.
+func finish[T any, P Entry[T]](ctx context.Context, job Job[T], entry *T, now time.Time) Status {
+	claimed := *P(entry).Lease()
+	apply, attemptErr := job.Attempt(ctx, entry)
+
+	status := job.Done
+	switch {
+	case attemptErr == nil:
+	case job.NeedsAuth != nil && job.NeedsAuth(attemptErr):
+		status = StatusNeedsAuth
+	case job.Retryable(attemptErr) && P(entry).Lease().Attempts < job.MaxAttempts:
+		status = StatusPending
+	default:
+		status = StatusFailed
+	}
+
+	_, err := job.Update(ctx, *entry, func(e *T) error {
+		state := P(e).Lease()
+		if state.Status != claimed.Status || state.Attempts != claimed.Attempts || !state.NextAttemptAt.Equal(claimed.NextAttemptAt) {
+			return errLeaseLost
+		}
+		state.Status = status
+		state.UpdatedAt = now
+		state.LastError = ""
+		if attemptErr != nil {
+			state.LastError = attemptErr.Error()
+		}
+		if status == StatusPending {
+			state.NextAttemptAt = now.Add(job.RetryDelay << (state.Attempts - 1))
+		}
+		if apply != nil {
+			apply(e)
+		}
+		return nil
+	})
+	if errors.Is(err, errLeaseLost) {
+		logrus.WithError(attemptErr).WithFields(job.Fields(*entry)).Warnf("Dropped %s outcome: the entry changed during the attempt", job.Name)
+		return ""
+	}
+	if err != nil {
+		logrus.WithError(err).WithFields(job.Fields(*entry)).Errorf("Failed to record %s outcome", job.Name)
+	}
+
+	if job.Finished != nil {
+		job.Finished(entry, status, attemptErr)
+	}
+	return status
+}
@@ -0,0 +1,283 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/jsonstore"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type task struct {
+	ID     string `json:"id"`
+	Result string `json:"result,omitempty"`
+	State
+}
+
+const statusDone Status = "done"
+
+var (
+	errTemporary = errors.New("temporary")
+	errPermanent = errors.New("permanent")
+	errNoAuth    = errors.New("no auth")
+)
+
+func newJob(store jsonstore.Store[task], attempt func(ctx context.Context, t *task) (func(*task), error)) Job[task] {
+	return Job[task]{
+		Name:        "task",
+		BatchSize:   10,
+		MaxAttempts: 3,
+		Lease:       5 * time.Minute,
+		RetryDelay:  time.Minute,
+		Done:        statusDone,
+		Due: func(ctx context.Context, now time.Time, limit int) ([]task, error) {
+			all, err := store.List(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return Due(all, now, limit), nil
+		},
+		Update: func(ctx context.Context, t task, fn func(*task) error) (*task, error) {
+			return store.Update(ctx, t.ID, fn)
+		},
+		Attempt:   attempt,
+		Retryable: func(err error) bool { return errors.Is(err, errTemporary) },
+		NeedsAuth: func(err error) bool { return errors.Is(err, errNoAuth) },
+		Fields:    func(t task) logrus.Fields { return logrus.Fields{"id": t.ID} },
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tasks := []task{
+		{ID: "later", State: State{Status: StatusPending, NextAttemptAt: now.Add(time.Minute)}},
+		{ID: "second", State: State{Status: StatusPending, NextAttemptAt: now.Add(-time.Minute)}},
+		{ID: "failed", State: State{Status: StatusFailed, NextAttemptAt: now.Add(-time.Hour)}},
+		{ID: "first", State: State{Status: StatusPending, NextAttemptAt: now.Add(-time.Hour)}},
+		{ID: "now", State: State{Status: StatusPending, NextAttemptAt: now}},
+	}
+
+	var ids []string
+	for _, t := range Due(tasks, now, 0) {
+		ids = append(ids, t.ID)
+	}
+	assert.Equal(t, []string{"first", "second", "now"}, ids)
+
+	due := Due(tasks, now, 1)
+	require.Len(t, due, 1)
+	assert.Equal(t, "first", due[0].ID)
+}
+
+func TestRun(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		attempts       int
+		attemptErr     error
+		expectedResult Result
+		expectedStatus Status
+		expectedNext   time.Time
+		expectedError  string
+		expectedOutput string
+	}{
+		{
+			name:           "Success",
+			expectedResult: Result{Done: 1},
+			expectedStatus: statusDone,
+			expectedNext:   now.Add(5 * time.Minute),
+			expectedOutput: "ok",
+		},
+		{
+			name:           "Retryable failure backs off",
+			attempts:       1,
+			attemptErr:     errTemporary,
+			expectedResult: Result{Retrying: 1},
+			expectedStatus: StatusPending,
+			expectedNext:   now.Add(2 * time.Minute),
+			expectedError:  "temporary",
+			expectedOutput: "tried",
+		},
+		{
+			name:           "Retryable failure on the last attempt",
+			attempts:       2,
+			attemptErr:     errTemporary,
+			expectedResult: Result{Failed: 1},
+			expectedStatus: StatusFailed,
+			expectedNext:   now.Add(5 * time.Minute),
+			expectedError:  "temporary",
+			expectedOutput: "tried",
+		},
+		{
+			name:           "Authorization failure parks the entry",
+			attempts:       2,
+			attemptErr:     errNoAuth,
+			expectedResult: Result{NeedsAuth: 1},
+			expectedStatus: StatusNeedsAuth,
+			expectedNext:   now.Add(5 * time.Minute),
+			expectedError:  "no auth",
+			expectedOutput: "tried",
+		},
+		{
+			name:           "Permanent failure",
+			attemptErr:     errPermanent,
+			expectedResult: Result{Failed: 1},
+			expectedStatus: StatusFailed,
+			expectedNext:   now.Add(5 * time.Minute),
+			expectedError:  "permanent",
+			expectedOutput: "tried",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := jsonstore.NewMemory[task](jsonstore.Config{})
+			require.NoError(t, store.Put(ctx, "due", task{ID: "due", State: State{Status: StatusPending, NextAttemptAt: now.Add(-time.Minute), Attempts: tt.attempts, LastError: "earlier"}}))
+			require.NoError(t, store.Put(ctx, "later", task{ID: "later", State: State{Status: StatusPending, NextAttemptAt: now.Add(time.Hour)}}))
+
+			var finished []Status
+			job := newJob(store, func(ctx context.Context, t *task) (func(*task), error) {
+				if tt.attemptErr != nil {
+					return func(t *task) { t.Result = "tried" }, tt.attemptErr
+				}
+				return func(t *task) { t.Result = "ok" }, nil
+			})
+			job.Finished = func(t *task, status Status, err error) {
+				finished = append(finished, status)
+			}
+
+			result, err := Run(ctx, job, now)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedResult, *result)
+			assert.Equal(t, []Status{tt.expectedStatus}, finished)
+
+			got, err := store.Get(ctx, "due")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, got.Status)
+			assert.Equal(t, tt.attempts+1, got.Attempts)
+			assert.Equal(t, tt.expectedNext, got.NextAttemptAt)
+			assert.Equal(t, tt.expectedError, got.LastError)
+			assert.Equal(t, tt.expectedOutput, got.Result)
+			assert.Equal(t, now, got.UpdatedAt)
+
+			later, err := store.Get(ctx, "later")
+			require.NoError(t, err)
+			assert.Zero(t, later.Attempts)
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	parked := State{Status: StatusNeedsAuth, NextAttemptAt: now.Add(-time.Hour), Attempts: 2}
+	assert.False(t, parked.Due(now))
+	assert.True(t, parked.Resume(now))
+	assert.True(t, parked.Due(now))
+	assert.Equal(t, 2, parked.Attempts)
+
+	failed := State{Status: StatusFailed}
+	assert.False(t, failed.Resume(now))
+	assert.Equal(t, StatusFailed, failed.Status)
+}
+
+func TestRunSkipsEntriesClaimedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := jsonstore.NewMemory[task](jsonstore.Config{})
+	require.NoError(t, store.Put(ctx, "a", task{ID: "a", State: State{Status: StatusPending, NextAttemptAt: now}}))
+
+	job := newJob(store, func(ctx context.Context, t *task) (func(*task), error) {
+		return nil, nil
+	})
+	due := job.Due
+	job.Due = func(ctx context.Context, now time.Time, limit int) ([]task, error) {
+		tasks, err := due(ctx, now, limit)
+		// Another worker claims the task between listing and claiming.
+		_, claimErr := store.Update(ctx, "a", func(t *task) error {
+			t.NextAttemptAt = now.Add(time.Minute)
+			return nil
+		})
+		require.NoError(t, claimErr)
+		return tasks, err
+	}
+
+	result, err := Run(ctx, job, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, Result{}, *result)
+}
+
+func TestRunKeepsChangesMadeDuringAttempt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		change         func(t *task)
+		expectedStatus Status
+		expectedNext   time.Time
+	}{
+		{
+			name:           "Canceled",
+			change:         func(t *task) { t.Status = "canceled" },
+			expectedStatus: "canceled",
+			expectedNext:   now.Add(5 * time.Minute),
+		},
+		{
+			name:           "Rescheduled",
+			change:         func(t *task) { t.NextAttemptAt = now.Add(time.Hour) },
+			expectedStatus: StatusPending,
+			expectedNext:   now.Add(time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := jsonstore.NewMemory[task](jsonstore.Config{})
+			require.NoError(t, store.Put(ctx, "a", task{ID: "a", State: State{Status: StatusPending, NextAttemptAt: now}}))
+
+			finished := false
+			job := newJob(store, func(ctx context.Context, t *task) (func(*task), error) {
+				_, err := store.Update(ctx, "a", func(t *task) error {
+					tt.change(t)
+					return nil
+				})
+				return func(t *task) { t.Result = "ok" }, err
+			})
+			job.Finished = func(t *task, status Status, err error) {
+				finished = true
+			}
+
+			result, err := Run(ctx, job, now)
+
+			require.NoError(t, err)
+			assert.Equal(t, Result{}, *result)
+			assert.False(t, finished)
+
+			got, err := store.Get(ctx, "a")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, got.Status)
+			assert.Equal(t, tt.expectedNext, got.NextAttemptAt)
+			assert.Empty(t, got.Result)
+		})
+	}
+}
+
+func TestRunListError(t *testing.T) {
+	errList := errors.New("disk on fire")
+	job := newJob(nil, nil)
+	job.Due = func(ctx context.Context, now time.Time, limit int) ([]task, error) {
+		return nil, errList
+	}
+
+	_, err := Run(context.Background(), job, time.Now())
+
+	assert.ErrorIs(t, err, errList)
+}
@@ -0,0 +1,137 @@
+package lexicon
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+//go:embed app com social
+var Files embed.FS
+
+type Schema struct {
+	Lexicon     int             `json:"lexicon"`
+	ID          string          `json:"id"`
+	Description string          `json:"description,omitempty"`
+	Defs        map[string]*Def `json:"defs"`
+}
+
+type Def struct {
+	Type         string            `json:"type"`
+	Description  string            `json:"description,omitempty"`
+	Key          string            `json:"key,omitempty"`
+	Record       *Def              `json:"record,omitempty"`
+	Required     []string          `json:"required,omitempty"`
+	Nullable     []string          `json:"nullable,omitempty"`
+	Properties   map[string]*Def   `json:"properties,omitempty"`
+	Items        *Def              `json:"items,omitempty"`
+	Ref          string            `json:"ref,omitempty"`
+	Refs         []string          `json:"refs,omitempty"`
+	Closed       bool              `json:"closed,omitempty"`
+	Format       string            `json:"format,omitempty"`
+	MinLength    *int              `json:"minLength,omitempty"`
+	MaxLength    *int              `json:"maxLength,omitempty"`
+	MinGraphemes *int              `json:"minGraphemes,omitempty"`
+	MaxGraphemes *int              `json:"maxGraphemes,omitempty"`
+	Minimum      *int64            `json:"minimum,omitempty"`
+	Maximum      *int64            `json:"maximum,omitempty"`
+	Enum         []json.RawMessage `json:"enum,omitempty"`
+	KnownValues  []string          `json:"knownValues,omitempty"`
+	Const        json.RawMessage   `json:"const,omitempty"`
+	Accept       []string          `json:"accept,omitempty"`
+	MaxSize      *int64            `json:"maxSize,omitempty"`
+}
+
+type Catalog struct {
+	schemas map[string]*Schema
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{schemas: make(map[string]*Schema)}
+}
+
+func (c *Catalog) Add(schema *Schema) error {
+	if schema.Lexicon != 1 {
+		return fmt.Errorf("lexicon %s: unsupported lexicon version %d", schema.ID, schema.Lexicon)
+	}
+	if schema.ID == "" {
+		return fmt.Errorf("lexicon is missing an id")
+	}
+	if _, ok := c.schemas[schema.ID]; ok {
+		return fmt.Errorf("lexicon %s is already loaded", schema.ID)
+	}
+	c.schemas[schema.ID] = schema
+	return nil
+}
+
+func (c *Catalog) LoadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		raw, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return fmt.Errorf("failed to read lexicon %s: %w", path, err)
+		}
+
+		var schema Schema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return fmt.Errorf("failed to parse lexicon %s: %w", path, err)
+		}
+		return c.Add(&schema)
+	})
+}
+
+func (c *Catalog) Schema(nsid string) (*Schema, bool) {
+	schema, ok := c.schemas[nsid]
+	return schema, ok
+}
+
+func (c *Catalog) resolve(ref, context string) (*Def, string, error) {
+	nsid, name, _ := strings.Cut(ref, "#")
+	if nsid == "" {
+		nsid = context
+	}
+	if name == "" {
+		name = "main"
+	}
+
+	schema, ok := c.schemas[nsid]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown lexicon %s", nsid)
+	}
+	def, ok := schema.Defs[name]
+	if !ok {
+		return nil, "", fmt.Errorf("lexicon %s has no definition %q", nsid, name)
+	}
+	return def, nsid, nil
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+	defaultErr     error
+)
+
+func Default() (*Catalog, error) {
+	defaultOnce.Do(func() {
+		defaultCatalog = NewCatalog()
+		defaultErr = defaultCatalog.LoadFS(Files)
+	})
+	return defaultCatalog, defaultErr
+}
+
+func ValidateRecord(nsid string, record interface{}) error {
+	catalog, err := Default()
+	if err != nil {
+		return err
+	}
+	return catalog.ValidateRecord(nsid, record)
+}
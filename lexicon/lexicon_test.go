@@ -0,0 +1,109 @@
+package lexicon
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	catalog, err := Default()
+	assert.NoError(t, err)
+
+	for _, nsid := range []string{
+		"social.shareframe.feed.post",
+		"app.bsky.richtext.facet",
+		"com.atproto.repo.strongRef",
+	} {
+		schema, ok := catalog.Schema(nsid)
+		if assert.True(t, ok, nsid) {
+			assert.Equal(t, nsid, schema.ID)
+			assert.Contains(t, schema.Defs, "main")
+		}
+	}
+
+	post, _ := catalog.Schema("social.shareframe.feed.post")
+	assert.Equal(t, "record", post.Defs["main"].Type)
+	assert.Equal(t, "tid", post.Defs["main"].Key)
+}
+
+func TestCatalogRefsResolve(t *testing.T) {
+	catalog, err := Default()
+	assert.NoError(t, err)
+
+	var walk func(def *Def, context string)
+	walk = func(def *Def, context string) {
+		if def == nil {
+			return
+		}
+		if def.Type == "ref" {
+			_, _, err := catalog.resolve(def.Ref, context)
+			assert.NoError(t, err, "%s: %s", context, def.Ref)
+		}
+		for _, ref := range def.Refs {
+			_, _, err := catalog.resolve(ref, context)
+			assert.NoError(t, err, "%s: %s", context, ref)
+		}
+		walk(def.Record, context)
+		walk(def.Items, context)
+		for _, prop := range def.Properties {
+			walk(prop, context)
+		}
+	}
+
+	for id, schema := range catalog.schemas {
+		for _, def := range schema.Defs {
+			walk(def, id)
+		}
+	}
+}
+
+func TestCatalogLoadFS(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     fstest.MapFS
+		expectErr string
+	}{
+		{
+			name: "Valid schema",
+			files: fstest.MapFS{
+				"com/example/thing.json": {Data: []byte(`{"lexicon":1,"id":"com.example.thing","defs":{"main":{"type":"object"}}}`)},
+				"README.md":              {Data: []byte(`not a lexicon`)},
+			},
+		},
+		{
+			name: "Malformed JSON",
+			files: fstest.MapFS{
+				"com/example/thing.json": {Data: []byte(`{"lexicon":`)},
+			},
+			expectErr: "failed to parse lexicon com/example/thing.json",
+		},
+		{
+			name: "Unsupported version",
+			files: fstest.MapFS{
+				"com/example/thing.json": {Data: []byte(`{"lexicon":2,"id":"com.example.thing","defs":{}}`)},
+			},
+			expectErr: "unsupported lexicon version 2",
+		},
+		{
+			name: "Duplicate id",
+			files: fstest.MapFS{
+				"a.json": {Data: []byte(`{"lexicon":1,"id":"com.example.thing","defs":{}}`)},
+				"b.json": {Data: []byte(`{"lexicon":1,"id":"com.example.thing","defs":{}}`)},
+			},
+			expectErr: "lexicon com.example.thing is already loaded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewCatalog().LoadFS(tt.files)
+			if tt.expectErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package lexicon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto/syntax"
+	"github.com/ShareFrame/posting-service/atproto/tid"
+	"github.com/ShareFrame/posting-service/richtext/grapheme"
+)
+
+var (
+	handleFormat   = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	atURIFormat    = regexp.MustCompile(`^at://[^/?#\s]+(/[a-zA-Z0-9.-]+(/[a-zA-Z0-9._:~-]+)?)?$`)
+	cidFormat      = regexp.MustCompile(`^[a-zA-Z0-9+=]{8,256}$`)
+	languageFormat = regexp.MustCompile(`^(i|[a-zA-Z]{2,3})(-[a-zA-Z0-9]{1,8})*$`)
+)
+
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+func invalid(path, format string, args ...interface{}) error {
+	return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *Catalog) ValidateRecord(nsid string, record interface{}) error {
+	def, _, err := c.resolve(nsid, "")
+	if err != nil {
+		return err
+	}
+	if def.Type != "record" || def.Record == nil {
+		return fmt.Errorf("lexicon %s is not a record type", nsid)
+	}
+
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	value, err := decode(raw)
+	if err != nil {
+		return fmt.Errorf("failed to decode record: %w", err)
+	}
+
+	return c.validate(def.Record, value, "", nsid)
+}
+
+func decode(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (c *Catalog) validate(def *Def, value interface{}, path, context string) error {
+	switch def.Type {
+	case "object":
+		return c.validateObject(def, value, path, context)
+	case "ref":
+		target, nsid, err := c.resolve(def.Ref, context)
+		if err != nil {
+			return err
+		}
+		return c.validate(target, value, path, nsid)
+	case "union":
+		return c.validateUnion(def, value, path, context)
+	case "array":
+		return c.validateArray(def, value, path, context)
+	case "string":
+		return validateString(def, value, path)
+	case "integer":
+		return validateInteger(def, value, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid(path, "must be a boolean")
+		}
+		return validateConst(def, value, path)
+	case "blob":
+		return validateBlob(def, value, path)
+	case "cid-link":
+		link, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid(path, "must be a CID link")
+		}
+		if s, ok := link["$link"].(string); !ok || !cidFormat.MatchString(s) {
+			return invalid(path, "must be a CID link")
+		}
+		return nil
+	case "bytes":
+		b, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid(path, "must be bytes")
+		}
+		if _, ok := b["$bytes"].(string); !ok {
+			return invalid(path, "must be bytes")
+		}
+		return nil
+	case "unknown":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return invalid(path, "must be an object")
+		}
+		return nil
+	case "null":
+		if value != nil {
+			return invalid(path, "must be null")
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: unsupported lexicon type %q", path, def.Type)
+}
+
+func (c *Catalog) validateObject(def *Def, value interface{}, path, context string) error {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return invalid(path, "must be an object")
+	}
+
+	for _, name := range def.Required {
+		if v, ok := obj[name]; !ok || (v == nil && !contains(def.Nullable, name)) {
+			return invalid(join(path, name), "is required")
+		}
+	}
+
+	for name, prop := range def.Properties {
+		v, ok := obj[name]
+		if !ok || (v == nil && contains(def.Nullable, name)) {
+			continue
+		}
+		if err := c.validate(prop, v, join(path, name), context); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) validateUnion(def *Def, value interface{}, path, context string) error {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return invalid(path, "must be an object")
+	}
+	typ, ok := obj["$type"].(string)
+	if !ok || typ == "" {
+		return invalid(path, "must have a $type")
+	}
+
+	for _, ref := range def.Refs {
+		if canonicalRef(ref, context) != canonicalRef(typ, "") {
+			continue
+		}
+		target, nsid, err := c.resolve(ref, context)
+		if err != nil {
+			return err
+		}
+		return c.validate(target, value, path, nsid)
+	}
+
+	if def.Closed {
+		return invalid(path, "$type %s is not one of %s", typ, strings.Join(def.Refs, ", "))
+	}
+	return nil
+}
+
+func (c *Catalog) validateArray(def *Def, value interface{}, path, context string) error {
+	items, ok := value.([]interface{})
+	if !ok {
+		return invalid(path, "must be an array")
+	}
+	if def.MaxLength != nil && len(items) > *def.MaxLength {
+		return invalid(path, "must have at most %d items (got %d)", *def.MaxLength, len(items))
+	}
+	if def.MinLength != nil && len(items) < *def.MinLength {
+		return invalid(path, "must have at least %d items (got %d)", *def.MinLength, len(items))
+	}
+	if def.Items == nil {
+		return nil
+	}
+	for i, item := range items {
+		if err := c.validate(def.Items, item, fmt.Sprintf("%s[%d]", path, i), context); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(def *Def, value interface{}, path string) error {
+	s, ok := value.(string)
+	if !ok {
+		return invalid(path, "must be a string")
+	}
+
+	if err := validateConst(def, s, path); err != nil {
+		return err
+	}
+	if def.MaxLength != nil && len(s) > *def.MaxLength {
+		return invalid(path, "must be at most %d bytes (got %d)", *def.MaxLength, len(s))
+	}
+	if def.MinLength != nil && len(s) < *def.MinLength {
+		return invalid(path, "must be at least %d bytes (got %d)", *def.MinLength, len(s))
+	}
+	if def.MaxGraphemes != nil || def.MinGraphemes != nil {
+		count := grapheme.Count(s)
+		if def.MaxGraphemes != nil && count > *def.MaxGraphemes {
+			return invalid(path, "must be at most %d characters (got %d)", *def.MaxGraphemes, count)
+		}
+		if def.MinGraphemes != nil && count < *def.MinGraphemes {
+			return invalid(path, "must be at least %d characters (got %d)", *def.MinGraphemes, count)
+		}
+	}
+	if def.Format != "" && !validFormat(def.Format, s) {
+		return invalid(path, "must be a valid %s (got %q)", def.Format, s)
+	}
+	return nil
+}
+
+func validateInteger(def *Def, value interface{}, path string) error {
+	num, ok := value.(json.Number)
+	if !ok {
+		return invalid(path, "must be an integer")
+	}
+	n, err := num.Int64()
+	if err != nil {
+		return invalid(path, "must be an integer")
+	}
+
+	if err := validateConst(def, num, path); err != nil {
+		return err
+	}
+	if def.Minimum != nil && n < *def.Minimum {
+		return invalid(path, "must be at least %d (got %d)", *def.Minimum, n)
+	}
+	if def.Maximum != nil && n > *def.Maximum {
+		return invalid(path, "must be at most %d (got %d)", *def.Maximum, n)
+	}
+	return nil
+}
+
+func validateConst(def *Def, value interface{}, path string) error {
+	if len(def.Const) > 0 {
+		expected, err := decode(def.Const)
+		if err != nil {
+			return fmt.Errorf("%s: invalid const in lexicon: %w", path, err)
+		}
+		if !reflect.DeepEqual(expected, value) {
+			return invalid(path, "must be %s", def.Const)
+		}
+	}
+
+	if len(def.Enum) == 0 {
+		return nil
+	}
+	for _, raw := range def.Enum {
+		if option, err := decode(raw); err == nil && reflect.DeepEqual(option, value) {
+			return nil
+		}
+	}
+	return invalid(path, "must be one of the allowed values")
+}
+
+func validateBlob(def *Def, value interface{}, path string) error {
+	blob, ok := value.(map[string]interface{})
+	if !ok || blob["$type"] != "blob" {
+		return invalid(path, "must be a blob")
+	}
+	ref, ok := blob["ref"].(map[string]interface{})
+	if !ok {
+		return invalid(path, "blob is missing ref")
+	}
+	if link, ok := ref["$link"].(string); !ok || !cidFormat.MatchString(link) {
+		return invalid(path, "blob ref must be a CID link")
+	}
+
+	mimeType, ok := blob["mimeType"].(string)
+	if !ok || mimeType == "" {
+		return invalid(path, "blob is missing mimeType")
+	}
+	if len(def.Accept) > 0 && !acceptsMimeType(def.Accept, mimeType) {
+		return invalid(path, "blob type %s is not accepted", mimeType)
+	}
+
+	num, ok := blob["size"].(json.Number)
+	if !ok {
+		return invalid(path, "blob is missing size")
+	}
+	size, err := num.Int64()
+	if err != nil || size < 0 {
+		return invalid(path, "blob size must be a non-negative integer")
+	}
+	if def.MaxSize != nil && size > *def.MaxSize {
+		return invalid(path, "blob must be at most %d bytes (got %d)", *def.MaxSize, size)
+	}
+	return nil
+}
+
+func acceptsMimeType(accept []string, mimeType string) bool {
+	for _, pattern := range accept {
+		if pattern == "*/*" || strings.EqualFold(pattern, mimeType) {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(strings.ToLower(mimeType), strings.ToLower(prefix)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "datetime":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+	case "at-uri":
+		return atURIFormat.MatchString(s)
+	case "did":
+		return syntax.ValidDID(s)
+	case "handle":
+		return handleFormat.MatchString(s)
+	case "at-identifier":
+		return syntax.ValidDID(s) || handleFormat.MatchString(s)
+	case "nsid":
+		return syntax.ValidNSID(s)
+	case "cid":
+		return cidFormat.MatchString(s)
+	case "language":
+		return languageFormat.MatchString(s)
+	case "tid":
+		return tid.Valid(s)
+	case "record-key":
+		return syntax.ValidRecordKey(s)
+	}
+	return true
+}
+
+func canonicalRef(ref, context string) string {
+	nsid, name, _ := strings.Cut(ref, "#")
+	if nsid == "" {
+		nsid = context
+	}
+	if name == "" || name == "main" {
+		return nsid
+	}
+	return nsid + "#" + name
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
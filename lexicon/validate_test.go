@@ -0,0 +1,275 @@
+package lexicon
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func validPost() models.ShareFrameFeedPost {
+	return models.ShareFrameFeedPost{
+		NSID:      models.FeedPostNSID,
+		Text:      "Hello @alice.shareframe.social",
+		CreatedAt: "2025-01-02T03:04:05Z",
+		SourceApp: "ShareFrame",
+		Facets: []models.Facet{{
+			Index: models.FacetByteSlice{ByteStart: 6, ByteEnd: 30},
+			Features: []models.FacetFeature{{
+				Mention: &models.FacetMention{Type: models.FacetMentionType, DID: "did:plc:alice"},
+			}},
+		}},
+		Images: []models.Blob{{
+			Type:     "blob",
+			Ref:      models.BlobLink{Link: "bafkreibme22gw2h7y2h7tg2fhqotaqjucnbc24deqo72b6mkl2egezxhvy"},
+			MimeType: "image/jpeg",
+			Size:     1024,
+		}},
+		Reply: &models.ReplyRef{
+			Root:   models.StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/3jzfcijpj2z2a", CID: "bafyreiroot"},
+			Parent: models.StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/3jzfcijpj2z2a", CID: "bafyreiroot"},
+		},
+		ImageMetadata: &models.ImageMetadata{Width: 1080, Height: 1350},
+	}
+}
+
+func TestValidateRecord(t *testing.T) {
+	tests := []struct {
+		name        string
+		mutate      func(*models.ShareFrameFeedPost)
+		expectedErr string
+	}{
+		{
+			name:   "Valid post",
+			mutate: func(p *models.ShareFrameFeedPost) {},
+		},
+		{
+			name:        "Missing createdAt",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.CreatedAt = "" },
+			expectedErr: `createdAt: must be a valid datetime (got "")`,
+		},
+		{
+			name:        "Wrong nsid const",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.NSID = "app.bsky.feed.post" },
+			expectedErr: `nsid: must be "social.shareframe.feed.post"`,
+		},
+		{
+			name:        "Invalid datetime format",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.ExpiresAt = "tomorrow" },
+			expectedErr: `expiresAt: must be a valid datetime (got "tomorrow")`,
+		},
+		{
+			name:        "Too many graphemes",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Text = strings.Repeat("文", 301) },
+			expectedErr: "text: must be at most 300 characters (got 301)",
+		},
+		{
+			name:        "Too many bytes",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Text = strings.Repeat("👨‍👩‍👧‍👦", 150) },
+			expectedErr: "text: must be at most 3000 bytes (got 3750)",
+		},
+		{
+			name:   "Value outside knownValues",
+			mutate: func(p *models.ShareFrameFeedPost) { p.SourceApp = "Other" },
+		},
+		{
+			name:        "Tag too long",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Tags = []string{"ok", strings.Repeat("t", 65)} },
+			expectedErr: "tags[1]: must be at most 64 characters (got 65)",
+		},
+		{
+			name:        "Image URI without scheme",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.ImageUris = []string{"photo.jpg"} },
+			expectedErr: `imageUris[0]: must be a valid uri (got "photo.jpg")`,
+		},
+		{
+			name:        "Blob with rejected mime type",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Images[0].MimeType = "video/mp4" },
+			expectedErr: "images[0]: blob type video/mp4 is not accepted",
+		},
+		{
+			name:        "Blob too large",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Images[0].Size = 60 << 20 },
+			expectedErr: "images[0]: blob must be at most 52428800 bytes (got 62914560)",
+		},
+		{
+			name:        "Blob without $type",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Images[0].Type = "" },
+			expectedErr: "images[0]: must be a blob",
+		},
+		{
+			name:        "Strong ref with invalid AT-URI",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Reply.Parent.URI = "https://example.com" },
+			expectedErr: `reply.parent.uri: must be a valid at-uri (got "https://example.com")`,
+		},
+		{
+			name: "Strong ref missing CID",
+			mutate: func(p *models.ShareFrameFeedPost) {
+				p.QuoteOf = &models.StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/x"}
+			},
+			expectedErr: `quoteOf.cid: must be a valid cid (got "")`,
+		},
+		{
+			name:        "Facet with negative index",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Facets[0].Index.ByteStart = -1 },
+			expectedErr: "facets[0].index.byteStart: must be at least 0 (got -1)",
+		},
+		{
+			name: "Facet mention with invalid DID",
+			mutate: func(p *models.ShareFrameFeedPost) {
+				p.Facets[0].Features[0].Mention.DID = "alice"
+			},
+			expectedErr: `facets[0].features[0].did: must be a valid did (got "alice")`,
+		},
+		{
+			name:        "Invalid language",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.Language = "english!" },
+			expectedErr: `language: must be a valid language (got "english!")`,
+		},
+		{
+			name:        "Edit history entry without timestamp",
+			mutate:      func(p *models.ShareFrameFeedPost) { p.EditHistory = []models.EditHistoryEntry{{Text: "old"}} },
+			expectedErr: `editHistory[0].editedAt: must be a valid datetime (got "")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := validPost()
+			tt.mutate(&post)
+
+			err := ValidateRecord(models.FeedPostNSID, post)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.expectedErr)
+			var validationErr *ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+		})
+	}
+}
+
+func TestValidateUnion(t *testing.T) {
+	catalog := NewCatalog()
+	assert.NoError(t, catalog.Add(&Schema{
+		Lexicon: 1,
+		ID:      "com.example.record",
+		Defs: map[string]*Def{
+			"main": {
+				Type: "record",
+				Record: &Def{
+					Type: "object",
+					Properties: map[string]*Def{
+						"open":   {Type: "union", Refs: []string{"#a"}},
+						"closed": {Type: "union", Refs: []string{"#a"}, Closed: true},
+					},
+				},
+			},
+			"a": {
+				Type:       "object",
+				Required:   []string{"n"},
+				Properties: map[string]*Def{"n": {Type: "integer"}},
+			},
+		},
+	}))
+
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name:   "Matching local ref",
+			record: map[string]interface{}{"open": map[string]interface{}{"$type": "com.example.record#a", "n": 1}},
+		},
+		{
+			name:   "Unknown type in open union",
+			record: map[string]interface{}{"open": map[string]interface{}{"$type": "com.example.other"}},
+		},
+		{
+			name:        "Unknown type in closed union",
+			record:      map[string]interface{}{"closed": map[string]interface{}{"$type": "com.example.other"}},
+			expectedErr: "closed: $type com.example.other is not one of #a",
+		},
+		{
+			name:        "Missing $type",
+			record:      map[string]interface{}{"open": map[string]interface{}{"n": 1}},
+			expectedErr: "open: must have a $type",
+		},
+		{
+			name:        "Matching ref is validated",
+			record:      map[string]interface{}{"open": map[string]interface{}{"$type": "com.example.record#a", "n": "one"}},
+			expectedErr: "open.n: must be an integer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := catalog.ValidateRecord("com.example.record", tt.record)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestValidateStringValues(t *testing.T) {
+	catalog := NewCatalog()
+	assert.NoError(t, catalog.Add(&Schema{
+		Lexicon: 1,
+		ID:      "com.example.record",
+		Defs: map[string]*Def{
+			"main": {
+				Type: "record",
+				Record: &Def{
+					Type: "object",
+					Properties: map[string]*Def{
+						"known": {Type: "string", KnownValues: []string{"a", "b"}},
+						"enum":  {Type: "string", Enum: []json.RawMessage{[]byte(`"a"`), []byte(`"b"`)}},
+					},
+				},
+			},
+		},
+	}))
+
+	tests := []struct {
+		name        string
+		record      map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name:   "Known value",
+			record: map[string]interface{}{"known": "a"},
+		},
+		{
+			name:   "Unknown value is allowed",
+			record: map[string]interface{}{"known": "c"},
+		},
+		{
+			name:   "Enum value",
+			record: map[string]interface{}{"enum": "b"},
+		},
+		{
+			name:        "Value outside enum",
+			record:      map[string]interface{}{"enum": "c"},
+			expectedErr: "enum: must be one of the allowed values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := catalog.ValidateRecord("com.example.record", tt.record)
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
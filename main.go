@@ -2,63 +2,240 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"encoding/base64"
+	"flag"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/auth"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/draft"
+	"github.com/ShareFrame/posting-service/expiry"
 	"github.com/ShareFrame/posting-service/handler"
-	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/idempotency"
+	"github.com/ShareFrame/posting-service/media"
+	"github.com/ShareFrame/posting-service/schedule"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/sirupsen/logrus"
 )
 
-type CreatePostInput struct {
-	AuthToken string   `json:"authToken"`
-	DID       string   `json:"did"`
-	Text      string   `json:"text,omitempty"`
-	ImageUris []string `json:"imageUris,omitempty"`
-	VideoUris []string `json:"videoUris,omitempty"`
+func newATProtoService(pdsURL string) *atproto.ATProtoService {
+	cfg := atproto.Config{
+		BaseURL: pdsURL,
+		Retry: atproto.RetryPolicy{
+			MaxAttempts: envInt("PDS_MAX_ATTEMPTS", 0),
+		},
+	}
+	if cfg.BaseURL == "" {
+		cfg.Resolver = atproto.NewDIDResolver(nil, atproto.DIDResolverConfig{
+			PLCDirectoryURL: os.Getenv("PLC_DIRECTORY_URL"),
+		})
+		// Leave the client to atproto, which keeps requests to resolved PDS
+		// hosts off private addresses.
+		return atproto.NewATProtoService(nil, cfg)
+	}
+	return atproto.NewATProtoService(http.DefaultClient, cfg)
+}
+
+func newVerifier() *auth.Verifier {
+	var keys auth.KeySet
+	switch {
+	case os.Getenv("AUTH_JWKS_URL") != "":
+		keys = auth.NewJWKS(nil, os.Getenv("AUTH_JWKS_URL"), 0)
+	case os.Getenv("AUTH_HMAC_SECRET") != "":
+		keys = auth.StaticKeys{"": []byte(os.Getenv("AUTH_HMAC_SECRET"))}
+	default:
+		logrus.Warn("No AUTH_JWKS_URL or AUTH_HMAC_SECRET set; auth tokens are forwarded without verification")
+		return nil
+	}
+
+	verifier, err := auth.NewVerifier(auth.Config{
+		Keys:     keys,
+		Issuers:  envList("AUTH_ISSUERS"),
+		Audience: os.Getenv("AUTH_AUDIENCE"),
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("AUTH_AUDIENCE must be set when auth tokens are verified")
+	}
+	return verifier
+}
+
+func newIdempotencyStore() idempotency.Store {
+	if table := os.Getenv("IDEMPOTENCY_TABLE"); table != "" {
+		cfg, err := config.LoadDefaultConfig(context.Background())
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to load AWS config for the idempotency table")
+		}
+		return idempotency.NewDynamoStore(dynamodb.NewFromConfig(cfg), table)
+	}
+
+	dir := os.Getenv("IDEMPOTENCY_DIR")
+	if dir == "" {
+		return idempotency.NewMemoryStore()
+	}
+
+	store, err := idempotency.NewFileStore(dir)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open idempotency store")
+	}
+	return store
+}
+
+func newScheduleStore() schedule.Store {
+	dir := os.Getenv("SCHEDULE_DIR")
+	if dir == "" {
+		return nil
+	}
+
+	store, err := schedule.NewFileStore(dir)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open schedule store")
+	}
+	return store
+}
+
+func newDraftStore() draft.Store {
+	dir := os.Getenv("DRAFT_DIR")
+	if dir == "" {
+		return nil
+	}
+
+	store, err := draft.NewFileStore(dir)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open draft store")
+	}
+	return store
 }
 
-var client = atproto.NewATProtoService(http.DefaultClient)
+func newExpiryStore() expiry.Store {
+	dir := os.Getenv("STORY_EXPIRY_DIR")
+	if dir == "" {
+		return nil
+	}
 
-type LambdaUnitPayload struct {
-	Body string `json:"body"`
+	store, err := expiry.NewFileStore(dir)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open story expiry store")
+	}
+	return store
 }
 
-func handlerFunc(ctx context.Context, event LambdaUnitPayload) (models.PostResponse, error) {
-	var input CreatePostInput
-	if err := json.Unmarshal([]byte(event.Body), &input); err != nil {
-		logrus.WithError(err).Error("Failed to parse request body")
-		return models.PostResponse{}, fmt.Errorf("invalid input")
+func newCredentialStore() credential.Store {
+	dir := os.Getenv("CREDENTIAL_DIR")
+	if dir == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("CREDENTIAL_KEY"))
+	if err != nil {
+		logrus.WithError(err).Fatal("CREDENTIAL_KEY must be base64")
 	}
+	store, err := credential.NewFileStore(dir, key)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open credential store")
+	}
+	return store
+}
 
-	post := models.ShareFrameFeedPost{
-		NSID:      "social.shareframe.feed.post",
-		Text:      input.Text,
-		ImageUris: input.ImageUris,
-		VideoUris: input.VideoUris,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-		SourceApp: "ShareFrame",
+func newMediaVerifier() *media.Verifier {
+	if enabled, _ := strconv.ParseBool(os.Getenv("VERIFY_MEDIA_URLS")); !enabled {
+		return nil
 	}
 
-	payload := models.RequestPayload{
-		AuthToken: input.AuthToken,
-		DID:       input.DID,
-		Post:      post,
+	return media.NewVerifier(media.Config{
+		MaxImageSize: int64(envInt("MEDIA_MAX_IMAGE_BYTES", 0)),
+		MaxVideoSize: int64(envInt("MEDIA_MAX_VIDEO_BYTES", 0)),
+	})
+}
+
+func envList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
 	}
+	return values
+}
 
-	resp, err := handler.PostHandler(ctx, client, payload)
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
 	if err != nil {
-		logrus.WithError(err).Error("PostHandler failed")
-		return models.PostResponse{}, err
+		return fallback
 	}
+	return value
+}
 
-	return *resp, nil
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
 }
 
 func main() {
-	lambda.Start(handlerFunc)
+	httpAddr := flag.String("http", "", "serve over HTTP on this address (e.g. :8080) instead of running as a Lambda function")
+	pdsURL := flag.String("pds", os.Getenv("PDS_URL"), "PDS base URL; when empty each user's PDS is resolved from their DID")
+	mode := flag.String("handler", os.Getenv("HANDLER"), "Lambda handler to run: api (default), dispatcher or sweeper")
+	flag.Parse()
+
+	a := &app{
+		client: newATProtoService(*pdsURL),
+		policy: handler.Policy{
+			MediaVerifier:    newMediaVerifier(),
+			MaxStoryLifetime: envDuration("STORY_MAX_LIFETIME", handler.DefaultMaxStoryLifetime),
+		},
+		verifier:    newVerifier(),
+		idempotency: newIdempotencyStore(),
+		schedules:   newScheduleStore(),
+		expiries:    newExpiryStore(),
+		drafts:      newDraftStore(),
+		credentials: newCredentialStore(),
+	}
+
+	switch *mode {
+	case "", "api":
+	case "dispatcher":
+		if a.schedules == nil || a.credentials == nil {
+			logrus.Fatal("The dispatcher requires SCHEDULE_DIR and CREDENTIAL_DIR")
+		}
+		lambda.Start(a.dispatchScheduled)
+		return
+	case "sweeper":
+		if a.expiries == nil || a.credentials == nil {
+			logrus.Fatal("The sweeper requires STORY_EXPIRY_DIR and CREDENTIAL_DIR")
+		}
+		lambda.Start(a.sweepExpired)
+		return
+	default:
+		logrus.WithField("handler", *mode).Fatal("Unknown handler")
+	}
+
+	if *httpAddr == "" {
+		lambda.Start(a.handleEvent)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", *httpAddr)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to listen")
+	}
+
+	logrus.WithField("addr", ln.Addr().String()).Info("Serving HTTP")
+	if err := serve(ctx, ln, a); err != nil {
+		logrus.WithError(err).Fatal("HTTP server failed")
+	}
 }
@@ -0,0 +1,434 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type Kind int
+
+const (
+	Image Kind = iota
+	Video
+)
+
+func (k Kind) String() string {
+	if k == Video {
+		return "video"
+	}
+	return "image"
+}
+
+// The defaults match the blob maxSize in the social.shareframe.feed.post
+// lexicon.
+const (
+	DefaultMaxImageSize int64 = 50 << 20
+	DefaultMaxVideoSize int64 = 50 << 20
+
+	defaultTimeout = 10 * time.Second
+	fetchTimeout   = time.Minute
+	maxRedirects   = 5
+	sniffLen       = 512
+)
+
+var (
+	ErrInvalidURL      = errors.New("invalid media URL")
+	ErrBlockedAddress  = errors.New("URL resolves to a private or reserved address")
+	ErrUnavailable     = errors.New("media is not available")
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("media is too large")
+	ErrContentMismatch = errors.New("media content does not match its type")
+)
+
+var allowedTypes = map[Kind]map[string]struct{}{
+	Image: {"image/jpeg": {}, "image/png": {}, "image/gif": {}, "image/heic": {}, "image/heif": {}},
+	Video: {"video/mp4": {}, "video/quicktime": {}, "video/webm": {}},
+}
+
+// Types that share a container format and are routinely labelled as each
+// other.
+var typeFamily = map[string]string{
+	"image/heif":      "image/heic",
+	"video/quicktime": "video/mp4",
+}
+
+// reservedPrefixes are the IANA special-purpose ranges that are not reachable
+// on the public internet, or that tunnel or translate to addresses which may
+// not be. IPv4-mapped IPv6 addresses are checked as IPv4.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("10.0.0.0/8"),      // private
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, including cloud metadata
+	netip.MustParsePrefix("172.16.0.0/12"),   // private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // private
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+
+	netip.MustParsePrefix("::/96"),          // unspecified, loopback and IPv4-compatible
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("100::/64"),       // discard-only
+	netip.MustParsePrefix("2001::/23"),      // IETF protocol assignments, including Teredo
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("2002::/16"),      // 6to4
+	netip.MustParsePrefix("fc00::/7"),       // unique local
+	netip.MustParsePrefix("fe80::/10"),      // link-local
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+type Config struct {
+	// Client sends the HEAD, range GET and Fetch requests. When nil, a client
+	// whose dialer refuses private addresses is used.
+	Client *http.Client
+
+	MaxImageSize int64
+	MaxVideoSize int64
+
+	// AllowPrivateNetworks turns off the address checks, for tests and
+	// deployments that host media on an internal network.
+	AllowPrivateNetworks bool
+
+	// Resolver looks up hosts before anything is requested. Defaults to
+	// net.DefaultResolver.
+	Resolver Resolver
+}
+
+// Verifier checks that a media URL really serves an image or video of an
+// accepted type and size, without downloading it. It also downloads media for
+// upload under the same address checks.
+type Verifier struct {
+	client       *http.Client
+	resolver     Resolver
+	maxSize      map[Kind]int64
+	allowPrivate bool
+}
+
+func NewVerifier(cfg Config) *Verifier {
+	v := &Verifier{
+		resolver:     cfg.Resolver,
+		allowPrivate: cfg.AllowPrivateNetworks,
+		maxSize: map[Kind]int64{
+			Image: cfg.MaxImageSize,
+			Video: cfg.MaxVideoSize,
+		},
+	}
+	if v.resolver == nil {
+		v.resolver = net.DefaultResolver
+	}
+	if v.maxSize[Image] <= 0 {
+		v.maxSize[Image] = DefaultMaxImageSize
+	}
+	if v.maxSize[Video] <= 0 {
+		v.maxSize[Video] = DefaultMaxVideoSize
+	}
+
+	if cfg.Client != nil {
+		client := *cfg.Client
+		v.client = &client
+	} else {
+		// Checking the dialled address as well as the resolved one stops a
+		// host from passing the lookup and then rebinding to a private
+		// address.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		dialer := &net.Dialer{Timeout: defaultTimeout, Control: v.checkDial}
+		transport.DialContext = dialer.DialContext
+		transport.ResponseHeaderTimeout = defaultTimeout
+		// Verify and Fetch set their own deadlines; a download may take
+		// longer than a check.
+		v.client = &http.Client{Transport: transport}
+	}
+
+	next := v.client.CheckRedirect
+	v.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("%w: too many redirects", ErrUnavailable)
+		}
+		if err := v.checkURL(req.Context(), req.URL); err != nil {
+			return err
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		return nil
+	}
+	return v
+}
+
+// Verify sends a HEAD request to check the status, Content-Type and size,
+// then a range GET for the first bytes to check they match the type.
+func (v *Verifier) Verify(ctx context.Context, rawURL string, kind Kind) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if err := v.checkRawURL(ctx, rawURL); err != nil {
+		return err
+	}
+
+	head, err := v.send(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	head.Body.Close()
+	// Some servers only answer GET, which the range request below covers.
+	if head.StatusCode != http.StatusMethodNotAllowed && head.StatusCode != http.StatusNotImplemented {
+		if _, err := v.checkResponse(head, kind); err != nil {
+			return err
+		}
+	}
+
+	resp, err := v.send(ctx, http.MethodGet, rawURL, http.Header{"Range": {fmt.Sprintf("bytes=0-%d", sniffLen-1)}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	contentType, err := v.checkResponse(resp, kind)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, sniffLen))
+	if err != nil {
+		return fmt.Errorf("%w: reading %s: %v", ErrUnavailable, rawURL, err)
+	}
+	detected := sniff(data)
+	if detected == "" || family(detected) != family(contentType) {
+		return fmt.Errorf("%w: %s is served as %s but does not look like one", ErrContentMismatch, rawURL, contentType)
+	}
+	return nil
+}
+
+// Fetch downloads at most limit bytes of media. The body's leading bytes,
+// not the Content-Type the server sends, decide its type, which must be an
+// accepted image or video type and is returned.
+func (v *Verifier) Fetch(ctx context.Context, rawURL string, limit int64) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	if err := v.checkRawURL(ctx, rawURL); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := v.send(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%w: %s returned status %d", ErrUnavailable, rawURL, resp.StatusCode)
+	}
+	if resp.ContentLength > limit {
+		return nil, "", fmt.Errorf("%w: %s is %d bytes, the limit is %d", ErrTooLarge, rawURL, resp.ContentLength, limit)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		return nil, "", fmt.Errorf("%w: reading %s: %v", ErrUnavailable, rawURL, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, "", fmt.Errorf("%w: %s is over the %d byte limit", ErrTooLarge, rawURL, limit)
+	}
+
+	detected := sniff(data)
+	for _, types := range allowedTypes {
+		if _, ok := types[detected]; ok {
+			return data, detected, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: %s is not an accepted image or video", ErrUnsupportedType, rawURL)
+}
+
+func (v *Verifier) checkRawURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidURL, rawURL)
+	}
+	return v.checkURL(ctx, u)
+}
+
+func (v *Verifier) send(ctx context.Context, method, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) || errors.Is(err, ErrInvalidURL) || ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s %s: %v", ErrUnavailable, method, rawURL, err)
+	}
+	return resp, nil
+}
+
+// checkResponse returns the response's media type once its status, type and
+// declared size are acceptable for kind.
+func (v *Verifier) checkResponse(resp *http.Response, kind Kind) (string, error) {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return "", fmt.Errorf("%w: %s returned status %d", ErrUnavailable, resp.Request.URL, resp.StatusCode)
+	}
+
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s has no valid Content-Type", ErrUnsupportedType, resp.Request.URL)
+	}
+	if _, ok := allowedTypes[kind][contentType]; !ok {
+		return "", fmt.Errorf("%w: %s is %s, not an accepted %s type", ErrUnsupportedType, resp.Request.URL, contentType, kind)
+	}
+
+	if size := contentSize(resp); size > v.maxSize[kind] {
+		return "", fmt.Errorf("%w: %s is %d bytes, the %s limit is %d", ErrTooLarge, resp.Request.URL, size, kind, v.maxSize[kind])
+	}
+	return contentType, nil
+}
+
+// contentSize returns the full size of the resource, or -1 when the server
+// does not say.
+func contentSize(resp *http.Response) int64 {
+	if resp.StatusCode != http.StatusPartialContent {
+		return resp.ContentLength
+	}
+	// Content-Range: bytes 0-511/12345
+	contentRange := resp.Header.Get("Content-Range")
+	i := strings.LastIndexByte(contentRange, '/')
+	if i < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+func (v *Verifier) checkURL(ctx context.Context, u *url.URL) error {
+	if v.allowPrivate {
+		return nil
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if isBlocked(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := v.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: resolving %s: %v", ErrUnavailable, host, err)
+	}
+	for _, addr := range addrs {
+		if isBlocked(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) checkDial(network, address string, c syscall.RawConn) error {
+	if v.allowPrivate {
+		return nil
+	}
+	return CheckDial(network, address, c)
+}
+
+// CheckDial is a net.Dialer Control function that refuses to connect to the
+// reserved ranges. Other clients that fetch from hosts a caller chose use it
+// so they are held to the same ranges as media.
+func CheckDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || isBlocked(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+func isBlocked(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniff identifies the accepted formats from their magic bytes.
+func sniff(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "video/webm"
+	case len(data) < 12:
+		return ""
+	}
+
+	// ISO base media files start with a box: a 4-byte size, then its type.
+	switch string(data[4:8]) {
+	case "ftyp":
+		switch string(data[8:12]) {
+		case "heic", "heix", "hevc", "hevx", "heim", "heis":
+			return "image/heic"
+		case "mif1", "msf1":
+			return "image/heif"
+		case "qt  ":
+			return "video/quicktime"
+		}
+		return "video/mp4"
+	case "moov", "mdat", "wide", "free", "skip":
+		return "video/quicktime"
+	}
+	return ""
+}
+
+func family(contentType string) string {
+	if f, ok := typeFamily[contentType]; ok {
+		return f
+	}
+	return contentType
+}
@@ -0,0 +1,322 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	pngData  = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 600)...)
+	jpegData = append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, make([]byte, 600)...)
+	mp4Data  = append([]byte("\x00\x00\x00\x18ftypisom"), make([]byte, 600)...)
+	movData  = append([]byte("\x00\x00\x00\x14ftypqt  "), make([]byte, 600)...)
+)
+
+func serve(contentType string, data []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}
+}
+
+func newMediaServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/photo.png", serve("image/png", pngData))
+	mux.Handle("/photo.jpg", serve("image/jpeg; charset=binary", jpegData))
+	mux.Handle("/page.jpg", serve("text/html; charset=utf-8", []byte("<html><body>not an image</body></html>")))
+	mux.Handle("/fake.png", serve("image/png", jpegData))
+	mux.Handle("/text.jpg", serve("image/jpeg", []byte("just some text pretending to be a photo")))
+	mux.Handle("/clip.mp4", serve("video/mp4", mp4Data))
+	mux.Handle("/clip.mov", serve("video/mp4", movData))
+	mux.HandleFunc("/huge.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Content-Length", "2147483648")
+	})
+	mux.HandleFunc("/huge-no-head.jpg", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Content-Range", "bytes 0-511/2147483648")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(jpegData[:sniffLen])
+	})
+	mux.HandleFunc("/no-head-no-range.jpg", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(jpegData)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerify(t *testing.T) {
+	server := newMediaServer(t)
+
+	tests := []struct {
+		name        string
+		path        string
+		kind        Kind
+		config      Config
+		expectedErr error
+	}{
+		{name: "PNG image", path: "/photo.png", kind: Image},
+		{name: "JPEG with media type parameters", path: "/photo.jpg", kind: Image},
+		{name: "MP4 video", path: "/clip.mp4", kind: Video},
+		{name: "QuickTime labelled as MP4", path: "/clip.mov", kind: Video},
+		{name: "Server without HEAD or range support", path: "/no-head-no-range.jpg", kind: Image},
+		{name: "HTML behind an image extension", path: "/page.jpg", kind: Image, expectedErr: ErrUnsupportedType},
+		{name: "Missing file", path: "/missing.jpg", kind: Image, expectedErr: ErrUnavailable},
+		{name: "Image where a video is expected", path: "/photo.png", kind: Video, expectedErr: ErrUnsupportedType},
+		{name: "JPEG bytes served as PNG", path: "/fake.png", kind: Image, expectedErr: ErrContentMismatch},
+		{name: "Text served as JPEG", path: "/text.jpg", kind: Image, expectedErr: ErrContentMismatch},
+		{name: "Size from HEAD over the limit", path: "/huge.jpg", kind: Image, expectedErr: ErrTooLarge},
+		{name: "Size from Content-Range over the limit", path: "/huge-no-head.jpg", kind: Image, expectedErr: ErrTooLarge},
+		{
+			name:        "Limits are per media type",
+			path:        "/photo.png",
+			kind:        Image,
+			config:      Config{MaxImageSize: 100},
+			expectedErr: ErrTooLarge,
+		},
+		{
+			name:   "Video limit does not apply to images",
+			path:   "/photo.png",
+			kind:   Image,
+			config: Config{MaxVideoSize: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Client = server.Client()
+			tt.config.AllowPrivateNetworks = true
+			v := NewVerifier(tt.config)
+
+			err := v.Verify(context.Background(), server.URL+tt.path, tt.kind)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifyInvalidURL(t *testing.T) {
+	v := NewVerifier(Config{AllowPrivateNetworks: true})
+
+	for _, uri := range []string{"ftp://example.com/photo.jpg", "https:///photo.jpg", "not a url"} {
+		assert.ErrorIs(t, v.Verify(context.Background(), uri, Image), ErrInvalidURL, uri)
+	}
+}
+
+type staticResolver map[string][]net.IPAddr
+
+func (r staticResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestVerifyRejectsPrivateAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/redirect.png" {
+			http.Redirect(w, r, "http://127.0.0.1/photo.png", http.StatusFound)
+			return
+		}
+		serve("image/png", pngData)(w, r)
+	}))
+	defer server.Close()
+
+	// Every host is routed to the test server, so only the verifier's own
+	// checks decide what is reachable.
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	v := NewVerifier(Config{
+		Client: &http.Client{Transport: transport},
+		Resolver: staticResolver{
+			"media.example":    {{IP: net.ParseIP("93.184.216.34")}},
+			"internal.example": {{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("10.0.0.5")}},
+			"metadata.example": {{IP: net.ParseIP("169.254.169.254")}},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		url         string
+		expectedErr error
+	}{
+		{name: "Public host", url: "http://media.example/photo.png"},
+		{name: "Loopback literal", url: "http://127.0.0.1/photo.png", expectedErr: ErrBlockedAddress},
+		{name: "IPv6 loopback literal", url: "http://[::1]/photo.png", expectedErr: ErrBlockedAddress},
+		{name: "Any private address", url: "http://internal.example/photo.png", expectedErr: ErrBlockedAddress},
+		{name: "Link-local metadata address", url: "http://metadata.example/photo.png", expectedErr: ErrBlockedAddress},
+		{name: "Redirect to loopback", url: "http://media.example/redirect.png", expectedErr: ErrBlockedAddress},
+		{name: "Unresolvable host", url: "http://missing.example/photo.png", expectedErr: ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(context.Background(), tt.url, Image)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// Only the public host and the first hop of the redirect reach the
+	// server: HEAD and GET for the first, HEAD for the second.
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestFetch(t *testing.T) {
+	server := newMediaServer(t)
+	v := NewVerifier(Config{Client: server.Client(), AllowPrivateNetworks: true})
+
+	tests := []struct {
+		name         string
+		path         string
+		limit        int64
+		expectedType string
+		expectedErr  error
+	}{
+		{name: "PNG image", path: "/photo.png", limit: 1 << 20, expectedType: "image/png"},
+		{name: "Type comes from the bytes", path: "/fake.png", limit: 1 << 20, expectedType: "image/jpeg"},
+		{name: "QuickTime labelled as MP4", path: "/clip.mov", limit: 1 << 20, expectedType: "video/quicktime"},
+		{name: "HTML behind an image extension", path: "/page.jpg", limit: 1 << 20, expectedErr: ErrUnsupportedType},
+		{name: "Text served as JPEG", path: "/text.jpg", limit: 1 << 20, expectedErr: ErrUnsupportedType},
+		{name: "Missing file", path: "/missing.jpg", limit: 1 << 20, expectedErr: ErrUnavailable},
+		{name: "Declared size over the limit", path: "/huge.jpg", limit: 1 << 20, expectedErr: ErrTooLarge},
+		{name: "Body over the limit", path: "/photo.png", limit: 100, expectedErr: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := v.Fetch(context.Background(), server.URL+tt.path, tt.limit)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, data)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedType, contentType)
+			assert.NotEmpty(t, data)
+		})
+	}
+}
+
+func TestFetchRejectsPrivateAddresses(t *testing.T) {
+	v := NewVerifier(Config{})
+
+	for _, uri := range []string{"http://127.0.0.1/photo.png", "http://[::1]/photo.png", "http://169.254.169.254/latest/meta-data", "http://198.18.0.1/photo.png"} {
+		_, _, err := v.Fetch(context.Background(), uri, 1<<20)
+		assert.ErrorIs(t, err, ErrBlockedAddress, uri)
+	}
+	_, _, err := v.Fetch(context.Background(), "file:///etc/passwd", 1<<20)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
+
+func TestCheckDial(t *testing.T) {
+	v := NewVerifier(Config{})
+
+	tests := []struct {
+		address string
+		blocked bool
+	}{
+		{"93.184.216.34:443", false},
+		{"[2606:4700::1111]:443", false},
+		{"127.0.0.1:80", true},
+		{"10.1.2.3:80", true},
+		{"172.16.0.1:80", true},
+		{"192.168.1.1:80", true},
+		{"169.254.169.254:80", true},
+		{"100.64.0.1:80", true},
+		{"0.0.0.0:80", true},
+		{"[::1]:80", true},
+		{"[fd00::1]:80", true},
+		{"[fe80::1]:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+		{"0.1.2.3:80", true},
+		{"198.18.0.1:80", true},
+		{"198.19.255.255:80", true},
+		{"240.0.0.1:80", true},
+		{"255.255.255.255:80", true},
+		{"192.0.2.1:80", true},
+		{"224.0.0.1:80", true},
+		{"[::]:80", true},
+		{"[64:ff9b::7f00:1]:80", true},
+		{"[64:ff9b::a9fe:a9fe]:80", true},
+		{"[2002:7f00:1::]:80", true},
+		{"[2001::1]:80", true},
+		{"[2001:db8::1]:80", true},
+		{"[ff02::1]:80", true},
+		{"[::ffff:169.254.169.254]:80", true},
+		{"198.20.0.1:443", false},
+		{"[2001:4860:4860::8888]:443", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := v.checkDial("tcp", tt.address, nil)
+			if tt.blocked {
+				assert.ErrorIs(t, err, ErrBlockedAddress)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	require.NoError(t, NewVerifier(Config{AllowPrivateNetworks: true}).checkDial("tcp", "127.0.0.1:80", nil))
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"JPEG", jpegData, "image/jpeg"},
+		{"PNG", pngData, "image/png"},
+		{"GIF", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"HEIC", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), "image/heic"},
+		{"HEIF", []byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00"), "image/heif"},
+		{"MP4", mp4Data, "video/mp4"},
+		{"QuickTime", movData, "video/quicktime"},
+		{"QuickTime without ftyp", []byte("\x00\x00\x00\x08wide\x00\x00\x00\x00"), "video/quicktime"},
+		{"WebM", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F}, "video/webm"},
+		{"HTML", []byte("<!doctype html><html></html>"), ""},
+		{"Empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sniff(tt.data))
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+// PostEngagement holds the counters for a post. They are derived from other
+// users' like, share and comment records by the indexer and served alongside
+// the post; they are never written into the author's repo.
+type PostEngagement struct {
+	URI           string  `json:"uri"`
+	Likes         int     `json:"likes"`
+	Shares        int     `json:"shares"`
+	Comments      int     `json:"comments"`
+	Rewatches     int     `json:"rewatches"`
+	Saves         int     `json:"saves"`
+	WatchTime     int     `json:"watchTime"`
+	TrendingScore float64 `json:"trendingScore"`
+	IndexedAt     string  `json:"indexedAt,omitempty"`
+}
+
+var ServerOwnedFields = []string{
+	"likes",
+	"shares",
+	"comments",
+	"rewatches",
+	"saves",
+	"watchTime",
+	"trendingScore",
+}
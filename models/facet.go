@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	FacetMentionType = "app.bsky.richtext.facet#mention"
+	FacetLinkType    = "app.bsky.richtext.facet#link"
+	FacetTagType     = "app.bsky.richtext.facet#tag"
+)
+
+type Facet struct {
+	Index    FacetByteSlice `json:"index"`
+	Features []FacetFeature `json:"features"`
+}
+
+type FacetByteSlice struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+type FacetMention struct {
+	Type string `json:"$type"`
+	DID  string `json:"did"`
+}
+
+type FacetLink struct {
+	Type string `json:"$type"`
+	URI  string `json:"uri"`
+}
+
+type FacetTag struct {
+	Type string `json:"$type"`
+	Tag  string `json:"tag"`
+}
+
+type FacetFeature struct {
+	Mention *FacetMention
+	Link    *FacetLink
+	Tag     *FacetTag
+
+	unknown json.RawMessage
+}
+
+func (f FacetFeature) MarshalJSON() ([]byte, error) {
+	switch {
+	case f.Mention != nil:
+		mention := *f.Mention
+		mention.Type = FacetMentionType
+		return json.Marshal(mention)
+	case f.Link != nil:
+		link := *f.Link
+		link.Type = FacetLinkType
+		return json.Marshal(link)
+	case f.Tag != nil:
+		tag := *f.Tag
+		tag.Type = FacetTagType
+		return json.Marshal(tag)
+	case f.unknown != nil:
+		return f.unknown, nil
+	}
+	return nil, errors.New("facet feature has no value")
+}
+
+func (f *FacetFeature) UnmarshalJSON(data []byte) error {
+	var typed struct {
+		Type string `json:"$type"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+
+	switch typed.Type {
+	case FacetMentionType:
+		f.Mention = new(FacetMention)
+		return json.Unmarshal(data, f.Mention)
+	case FacetLinkType:
+		f.Link = new(FacetLink)
+		return json.Unmarshal(data, f.Link)
+	case FacetTagType:
+		f.Tag = new(FacetTag)
+		return json.Unmarshal(data, f.Tag)
+	case "":
+		return errors.New("facet feature is missing $type")
+	}
+
+	f.unknown = append(json.RawMessage(nil), data...)
+	return nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFacetFeatureJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		feature FacetFeature
+		json    string
+	}{
+		{
+			name:    "Mention",
+			feature: FacetFeature{Mention: &FacetMention{DID: "did:plc:alice"}},
+			json:    `{"$type":"app.bsky.richtext.facet#mention","did":"did:plc:alice"}`,
+		},
+		{
+			name:    "Link",
+			feature: FacetFeature{Link: &FacetLink{URI: "https://shareframe.social"}},
+			json:    `{"$type":"app.bsky.richtext.facet#link","uri":"https://shareframe.social"}`,
+		},
+		{
+			name:    "Tag",
+			feature: FacetFeature{Tag: &FacetTag{Tag: "sunset"}},
+			json:    `{"$type":"app.bsky.richtext.facet#tag","tag":"sunset"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.feature)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.json, string(data))
+
+			var decoded FacetFeature
+			assert.NoError(t, json.Unmarshal(data, &decoded))
+			redone, err := json.Marshal(decoded)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.json, string(redone))
+		})
+	}
+}
+
+func TestFacetFeaturePreservesUnknownTypes(t *testing.T) {
+	const unknown = `{"$type":"app.example.facet#highlight","color":"yellow"}`
+
+	var feature FacetFeature
+	assert.NoError(t, json.Unmarshal([]byte(unknown), &feature))
+	assert.Nil(t, feature.Mention)
+	assert.Nil(t, feature.Link)
+	assert.Nil(t, feature.Tag)
+
+	data, err := json.Marshal(feature)
+	assert.NoError(t, err)
+	assert.JSONEq(t, unknown, string(data))
+}
+
+func TestFacetFeatureErrors(t *testing.T) {
+	var feature FacetFeature
+	assert.Error(t, json.Unmarshal([]byte(`{"did":"did:plc:alice"}`), &feature))
+
+	_, err := json.Marshal(FacetFeature{})
+	assert.Error(t, err)
+}
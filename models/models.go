@@ -1,59 +1,232 @@
 package models
 
-type ShareFrameFeedPost struct {
-	Text              string                   `json:"text,omitempty"`
-	ImageUris         []string                 `json:"imageUris,omitempty"`
-	VideoUris         []string                 `json:"videoUris,omitempty"`
-	CreatedAt         string                   `json:"createdAt,omitempty"`
-	Likes             int                      `json:"likes,omitempty"`
-	Shares            int                      `json:"shares,omitempty"`
-	Comments          int                      `json:"comments,omitempty"`
-	Rewatches         int                      `json:"rewatches,omitempty"`
-	Saves             int                      `json:"saves,omitempty"`
-	WatchTime         int                      `json:"watchTime,omitempty"`
-	LocationString    string                   `json:"locationString,omitempty"`
-	City              string                   `json:"city,omitempty"`
-	Region            string                   `json:"region,omitempty"`
-	Country           string                   `json:"country,omitempty"`
-	TimeZone          string                   `json:"timeZone,omitempty"`
-	Geohash           string                   `json:"geohash,omitempty"`
-	TrendingScore     float64                  `json:"trendingScore,omitempty"`
-	IsStory           bool                     `json:"isStory,omitempty"`
-	ExpiresAt         string                   `json:"expiresAt,omitempty"`
-	Language          string                   `json:"language,omitempty"`
-	Tags              []string                 `json:"tags,omitempty"`
-	Keywords          []string                 `json:"keywords,omitempty"`
-	ReplyTo           string                   `json:"replyTo,omitempty"`
-	QuoteOf           string                   `json:"quoteOf,omitempty"`
-	AuthorDisplayName string                   `json:"authorDisplayName,omitempty"`
-	AuthorHandle      string                   `json:"authorHandle,omitempty"`
-	ImageMetadata     map[string]interface{}   `json:"imageMetadata,omitempty"`
-	VideoMetadata     map[string]interface{}   `json:"videoMetadata,omitempty"`
-	EditHistory       []map[string]interface{} `json:"editHistory,omitempty"`
-	SourceApp         string                   `json:"sourceApp,omitempty"`
-	NSID              string                   `json:"nsid,omitempty"`
+//go:generate go run ../cmd/lexgen -dir ../lexicon -package models -out post_gen.go -gen social.shareframe.feed.post=ShareFrameFeedPost -gen com.atproto.repo.strongRef=StrongRef -ref app.bsky.richtext.facet=Facet
+
+const FeedPostNSID = "social.shareframe.feed.post"
+
+type PostInput struct {
+	Text              string         `json:"text,omitempty"`
+	ImageUris         []string       `json:"imageUris,omitempty"`
+	VideoUris         []string       `json:"videoUris,omitempty"`
+	LocationString    string         `json:"locationString,omitempty"`
+	City              string         `json:"city,omitempty"`
+	Region            string         `json:"region,omitempty"`
+	Country           string         `json:"country,omitempty"`
+	TimeZone          string         `json:"timeZone,omitempty"`
+	Geohash           string         `json:"geohash,omitempty"`
+	IsStory           bool           `json:"isStory,omitempty"`
+	ExpiresAt         string         `json:"expiresAt,omitempty"`
+	Language          string         `json:"language,omitempty"`
+	Tags              []string       `json:"tags,omitempty"`
+	Keywords          []string       `json:"keywords,omitempty"`
+	AuthorDisplayName string         `json:"authorDisplayName,omitempty"`
+	AuthorHandle      string         `json:"authorHandle,omitempty"`
+	ImageMetadata     *ImageMetadata `json:"imageMetadata,omitempty"`
+	VideoMetadata     *VideoMetadata `json:"videoMetadata,omitempty"`
+}
+
+func (p PostInput) Record() ShareFrameFeedPost {
+	return ShareFrameFeedPost{
+		Text:              p.Text,
+		ImageUris:         p.ImageUris,
+		VideoUris:         p.VideoUris,
+		LocationString:    p.LocationString,
+		City:              p.City,
+		Region:            p.Region,
+		Country:           p.Country,
+		TimeZone:          p.TimeZone,
+		Geohash:           p.Geohash,
+		IsStory:           p.IsStory,
+		ExpiresAt:         p.ExpiresAt,
+		Language:          p.Language,
+		Tags:              p.Tags,
+		Keywords:          p.Keywords,
+		AuthorDisplayName: p.AuthorDisplayName,
+		AuthorHandle:      p.AuthorHandle,
+		ImageMetadata:     p.ImageMetadata,
+		VideoMetadata:     p.VideoMetadata,
+	}
 }
 
 type CreateRecordRequest struct {
 	Repo       string             `json:"repo"`
 	Collection string             `json:"collection"`
+	RKey       string             `json:"rkey,omitempty"`
+	Validate   *bool              `json:"validate,omitempty"`
+	Record     ShareFrameFeedPost `json:"record"`
+	SwapCommit string             `json:"swapCommit,omitempty"`
+}
+
+const ApplyWritesCreateType = "com.atproto.repo.applyWrites#create"
+
+type ApplyWritesRequest struct {
+	Repo       string              `json:"repo"`
+	Validate   *bool               `json:"validate,omitempty"`
+	Writes     []ApplyWritesCreate `json:"writes"`
+	SwapCommit string              `json:"swapCommit,omitempty"`
+}
+
+type ApplyWritesCreate struct {
+	Type       string             `json:"$type"`
+	Collection string             `json:"collection"`
+	RKey       string             `json:"rkey,omitempty"`
+	Value      ShareFrameFeedPost `json:"value"`
+}
+
+type ApplyWritesResponse struct {
+	Commit  *Commit             `json:"commit,omitempty"`
+	Results []ApplyWritesResult `json:"results,omitempty"`
+}
+
+type ApplyWritesResult struct {
+	Type             string `json:"$type"`
+	URI              string `json:"uri,omitempty"`
+	CID              string `json:"cid,omitempty"`
+	ValidationStatus string `json:"validationStatus,omitempty"`
+}
+
+type DeleteRecordRequest struct {
+	Repo       string `json:"repo"`
+	Collection string `json:"collection"`
+	RKey       string `json:"rkey"`
+}
+
+type PutRecordRequest struct {
+	Repo       string             `json:"repo"`
+	Collection string             `json:"collection"`
+	RKey       string             `json:"rkey"`
 	Record     ShareFrameFeedPost `json:"record"`
+	SwapRecord string             `json:"swapRecord,omitempty"`
+}
+
+type GetRecordResponse struct {
+	URI   string             `json:"uri"`
+	CID   string             `json:"cid"`
+	Value ShareFrameFeedPost `json:"value"`
 }
 
 type RequestPayload struct {
-	AuthToken string             `json:"authToken"`
-	DID       string             `json:"did"`
-	Post      ShareFrameFeedPost `json:"post"`
+	AuthToken    string             `json:"authToken"`
+	RefreshToken string             `json:"refreshToken,omitempty"`
+	DID          string             `json:"did"`
+	Post         ShareFrameFeedPost `json:"post"`
+	Media        []MediaUpload      `json:"media,omitempty"`
+	ReplyTo      string             `json:"replyTo,omitempty"`
+	QuoteOf      string             `json:"quoteOf,omitempty"`
+	RKey         string             `json:"rkey,omitempty"`
+}
+
+// ThreadRequestPayload publishes Posts in order, each replying to the one
+// before it. ReplyTo optionally hangs the whole thread under an existing post.
+type ThreadRequestPayload struct {
+	AuthToken    string       `json:"authToken"`
+	RefreshToken string       `json:"refreshToken,omitempty"`
+	DID          string       `json:"did"`
+	Posts        []ThreadPost `json:"posts"`
+	ReplyTo      string       `json:"replyTo,omitempty"`
+}
+
+type ThreadPost struct {
+	Post  ShareFrameFeedPost `json:"post"`
+	Media []MediaUpload      `json:"media,omitempty"`
+}
+
+type MediaUpload struct {
+	Data      []byte `json:"data,omitempty"`
+	MimeType  string `json:"mimeType,omitempty"`
+	SourceURL string `json:"sourceUrl,omitempty"`
+}
+
+type Blob struct {
+	Type     string   `json:"$type"`
+	Ref      BlobLink `json:"ref"`
+	MimeType string   `json:"mimeType"`
+	Size     int64    `json:"size"`
+}
+
+type BlobLink struct {
+	Link string `json:"$link"`
+}
+
+type UploadBlobResponse struct {
+	Blob Blob `json:"blob"`
+}
+
+type DeleteRequestPayload struct {
+	AuthToken    string `json:"authToken"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	DID          string `json:"did"`
+	URI          string `json:"uri"`
+}
+
+type EditRequestPayload struct {
+	AuthToken    string         `json:"authToken"`
+	RefreshToken string         `json:"refreshToken,omitempty"`
+	DID          string         `json:"did"`
+	URI          string         `json:"uri"`
+	Text         *string        `json:"text,omitempty"`
+	ImageUris    *[]string      `json:"imageUris,omitempty"`
+	VideoUris    *[]string      `json:"videoUris,omitempty"`
+	Media        *[]MediaUpload `json:"media,omitempty"`
+}
+
+type DeleteResponse struct {
+	URI     string   `json:"uri"`
+	Session *Session `json:"session,omitempty"`
+}
+
+type ResolveHandleResponse struct {
+	DID string `json:"did"`
+}
+
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
 }
 
 type PostResponse struct {
-	URI              string `json:"uri"`
-	CID              string `json:"cid"`
-	Commit           Commit `json:"commit"`
-	ValidationStatus string `json:"validationStatus"`
+	URI              string   `json:"uri"`
+	CID              string   `json:"cid"`
+	Commit           Commit   `json:"commit"`
+	ValidationStatus string   `json:"validationStatus"`
+	ExpiresAt        string   `json:"expiresAt,omitempty"`
+	Session          *Session `json:"session,omitempty"`
+}
+
+type ThreadResponse struct {
+	Posts   []PostResponse `json:"posts"`
+	Session *Session       `json:"session,omitempty"`
+}
+
+type ScheduledPostResponse struct {
+	ID          string `json:"id"`
+	URI         string `json:"uri"`
+	Status      string `json:"status"`
+	ScheduledAt string `json:"scheduledAt"`
+	Attempts    int    `json:"attempts,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+	CID         string `json:"cid,omitempty"`
+}
+
+type CredentialResponse struct {
+	DID       string `json:"did"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
 }
 
 type Commit struct {
 	CID string `json:"cid"`
 	Rev string `json:"rev"`
 }
+
+type CreateSessionRequest struct {
+	Identifier string `json:"identifier"`
+	Password   string `json:"password"`
+}
+
+type Session struct {
+	DID        string `json:"did"`
+	Handle     string `json:"handle,omitempty"`
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+}
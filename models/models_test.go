@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareFrameFeedPostJSON(t *testing.T) {
+	post := ShareFrameFeedPost{
+		NSID:          FeedPostNSID,
+		Text:          "Sunset",
+		CreatedAt:     "2025-01-02T03:04:05Z",
+		ImageMetadata: &ImageMetadata{Alt: "A beach at dusk", Width: 1080, Height: 1350},
+		Reply: &ReplyRef{
+			Root:   StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/a", CID: "bafyreiroot"},
+			Parent: StrongRef{URI: "at://did:plc:bob/social.shareframe.feed.post/b", CID: "bafyreiparent"},
+		},
+	}
+
+	raw, err := json.Marshal(post)
+	assert.NoError(t, err)
+
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(raw, &fields))
+	assert.Equal(t, FeedPostNSID, fields["$type"])
+	assert.Equal(t, map[string]interface{}{"alt": "A beach at dusk", "width": float64(1080), "height": float64(1350)}, fields["imageMetadata"])
+	assert.NotContains(t, fields, "videoMetadata")
+
+	var decoded ShareFrameFeedPost
+	assert.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Equal(t, post, decoded)
+}
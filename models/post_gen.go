@@ -0,0 +1,88 @@
+// Code generated by lexgen. DO NOT EDIT.
+
+package models
+
+import (
+	"encoding/json"
+)
+
+type StrongRef struct {
+	CID string `json:"cid"`
+	URI string `json:"uri"`
+}
+
+type ShareFrameFeedPost struct {
+	AuthorDisplayName string             `json:"authorDisplayName,omitempty"`
+	AuthorHandle      string             `json:"authorHandle,omitempty"`
+	City              string             `json:"city,omitempty"`
+	Country           string             `json:"country,omitempty"`
+	CreatedAt         string             `json:"createdAt"`
+	EditHistory       []EditHistoryEntry `json:"editHistory,omitempty"`
+	ExpiresAt         string             `json:"expiresAt,omitempty"`
+	Facets            []Facet            `json:"facets,omitempty"`
+	Geohash           string             `json:"geohash,omitempty"`
+	ImageMetadata     *ImageMetadata     `json:"imageMetadata,omitempty"`
+	ImageUris         []string           `json:"imageUris,omitempty"`
+	Images            []Blob             `json:"images,omitempty"`
+	IsStory           bool               `json:"isStory,omitempty"`
+	Keywords          []string           `json:"keywords,omitempty"`
+	Language          string             `json:"language,omitempty"`
+	LocationString    string             `json:"locationString,omitempty"`
+	NSID              string             `json:"nsid"`
+	QuoteOf           *StrongRef         `json:"quoteOf,omitempty"`
+	Region            string             `json:"region,omitempty"`
+	Reply             *ReplyRef          `json:"reply,omitempty"`
+	SourceApp         string             `json:"sourceApp,omitempty"`
+	Tags              []string           `json:"tags,omitempty"`
+	Text              string             `json:"text,omitempty"`
+	TimeZone          string             `json:"timeZone,omitempty"`
+	VideoMetadata     *VideoMetadata     `json:"videoMetadata,omitempty"`
+	VideoUris         []string           `json:"videoUris,omitempty"`
+	Videos            []Blob             `json:"videos,omitempty"`
+}
+
+func (r ShareFrameFeedPost) MarshalJSON() ([]byte, error) {
+	type plain ShareFrameFeedPost
+	return marshalVariant("social.shareframe.feed.post", plain(r))
+}
+
+type EditHistoryEntry struct {
+	EditedAt  string   `json:"editedAt"`
+	ImageUris []string `json:"imageUris,omitempty"`
+	Images    []Blob   `json:"images,omitempty"`
+	Text      string   `json:"text,omitempty"`
+	VideoUris []string `json:"videoUris,omitempty"`
+	Videos    []Blob   `json:"videos,omitempty"`
+}
+
+type ImageMetadata struct {
+	Alt    string `json:"alt,omitempty"`
+	Height int64  `json:"height,omitempty"`
+	Width  int64  `json:"width,omitempty"`
+}
+
+type ReplyRef struct {
+	Parent StrongRef `json:"parent"`
+	Root   StrongRef `json:"root"`
+}
+
+type VideoMetadata struct {
+	Alt        string `json:"alt,omitempty"`
+	DurationMs int64  `json:"durationMs,omitempty"`
+	Height     int64  `json:"height,omitempty"`
+	Thumbnail  string `json:"thumbnail,omitempty"`
+	Width      int64  `json:"width,omitempty"`
+}
+
+func marshalVariant(typeID string, v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	fields["$type"], _ = json.Marshal(typeID)
+	return json.Marshal(fields)
+}
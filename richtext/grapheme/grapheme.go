@@ -0,0 +1,22 @@
+// Package grapheme counts and splits text in extended grapheme clusters, the
+// unit the lexicon's maxGraphemes limits are measured in. Segmentation is
+// delegated to rivo/uniseg, which implements the Unicode 15.0 UAX #29 rules
+// from the UCD tables.
+package grapheme
+
+import "github.com/rivo/uniseg"
+
+func Count(s string) int {
+	return uniseg.GraphemeClusterCount(s)
+}
+
+func Clusters(s string) []string {
+	var clusters []string
+	state := -1
+	for s != "" {
+		var cluster string
+		cluster, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
@@ -0,0 +1,93 @@
+package grapheme
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{name: "Empty", text: "", expected: 0},
+		{name: "ASCII", text: "Hello World!", expected: 12},
+		{name: "CRLF is one grapheme", text: "a\r\nb", expected: 3},
+		{name: "Control characters stand alone", text: "\x00\x00\t", expected: 3},
+		{name: "Combining accent", text: "école", expected: 5},
+		{name: "Japanese", text: "こんにちは世界", expected: 7},
+		{name: "Chinese", text: "你好，世界！", expected: 6},
+		{name: "Korean precomposed", text: "안녕하세요", expected: 5},
+		{name: "Korean conjoining jamo", text: "각가", expected: 2},
+		{name: "Hindi with spacing marks", text: "नमस्ते", expected: 4},
+		{name: "Thai with vowel marks", text: "สวัสดี", expected: 4},
+		{name: "Arabic", text: "مرحبا", expected: 5},
+		{name: "Simple emoji", text: "😀😀", expected: 2},
+		{name: "Emoji with skin tone", text: "👍🏽", expected: 1},
+		{name: "ZWJ family", text: "👨‍👩‍👧‍👦", expected: 1},
+		{name: "ZWJ sequence with variation selector", text: "❤️‍🔥", expected: 1},
+		{name: "Flags pair regional indicators", text: "🇯🇵🇺🇸🇫", expected: 3},
+		{name: "Subdivision flag with tag characters", text: "🏴󠁧󠁢󠁳󠁣󠁴󠁿", expected: 1},
+		{name: "Keycap", text: "1️⃣", expected: 1},
+		{name: "ZWJ without pictographic does not join", text: "a‍b", expected: 2},
+		{name: "Mixed", text: "Hi 👋🏻 東京 🇯🇵!", expected: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Count(tt.text))
+			assert.Equal(t, tt.text, strings.Join(Clusters(tt.text), ""))
+			assert.Len(t, Clusters(tt.text), tt.expected)
+		})
+	}
+}
+
+// TestConformance runs the official UAX #29 test cases. The file is the
+// Unicode 14.0 GraphemeBreakTest.txt; the 15.0 release that uniseg
+// implements ships the same cases.
+func TestConformance(t *testing.T) {
+	f, err := os.Open("testdata/GraphemeBreakTest.txt")
+	require.NoError(t, err)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	cases := 0
+	for scanner.Scan() {
+		line, comment, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var text strings.Builder
+		var expected []string
+		cluster := ""
+		for _, field := range strings.Fields(line) {
+			switch field {
+			case "÷":
+				if cluster != "" {
+					expected = append(expected, cluster)
+				}
+				cluster = ""
+			case "×":
+			default:
+				r, err := strconv.ParseUint(field, 16, 32)
+				require.NoError(t, err, line)
+				cluster += string(rune(r))
+				text.WriteRune(rune(r))
+			}
+		}
+
+		cases++
+		assert.Equal(t, expected, Clusters(text.String()), strings.TrimSpace(comment))
+		assert.Equal(t, len(expected), Count(text.String()), strings.TrimSpace(comment))
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, 602, cases)
+}
@@ -0,0 +1,114 @@
+package richtext
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+const maxTagLength = 64
+
+var (
+	mentionRegex = regexp.MustCompile(`(^|\s|\()(@)([a-zA-Z0-9.-]+)\b`)
+	urlRegex     = regexp.MustCompile(`(^|\s|\()(https?://[^\s]+)`)
+	tagRegex     = regexp.MustCompile(`(^|\s)[#＃]([^\s\x{00AD}\x{2060}\x{200A}\x{200B}\x{200C}\x{200D}\x{20e2}]*[^\d\s\p{P}\x{00AD}\x{2060}\x{200A}\x{200B}\x{200C}\x{200D}\x{20e2}]+[^\s\x{00AD}\x{2060}\x{200A}\x{200B}\x{200C}\x{200D}\x{20e2}]*)`)
+	handleRegex  = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+type HandleResolver interface {
+	ResolveHandle(ctx context.Context, handle string) (string, error)
+}
+
+func DetectFacets(ctx context.Context, text string, resolver HandleResolver) []models.Facet {
+	var facets []models.Facet
+	facets = append(facets, detectMentions(ctx, text, resolver)...)
+	facets = append(facets, detectLinks(text)...)
+	facets = append(facets, detectTags(text)...)
+
+	sort.SliceStable(facets, func(i, j int) bool {
+		return facets[i].Index.ByteStart < facets[j].Index.ByteStart
+	})
+	return facets
+}
+
+func detectMentions(ctx context.Context, text string, resolver HandleResolver) []models.Facet {
+	if resolver == nil {
+		return nil
+	}
+
+	var facets []models.Facet
+	for _, m := range mentionRegex.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[4], m[7]
+		handle := strings.ToLower(text[m[6]:m[7]])
+		if len(handle) > 253 || !handleRegex.MatchString(handle) {
+			continue
+		}
+
+		did, err := resolver.ResolveHandle(ctx, handle)
+		if err != nil || did == "" {
+			logrus.WithError(err).WithField("handle", handle).Warn("Skipping unresolvable mention")
+			continue
+		}
+
+		facets = append(facets, models.Facet{
+			Index:    models.FacetByteSlice{ByteStart: start, ByteEnd: end},
+			Features: []models.FacetFeature{{Mention: &models.FacetMention{Type: models.FacetMentionType, DID: did}}},
+		})
+	}
+	return facets
+}
+
+func detectLinks(text string) []models.Facet {
+	var facets []models.Facet
+	for _, m := range urlRegex.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[4], m[5]
+		uri := trimURL(text[start:end])
+		if !strings.Contains(strings.SplitN(uri, "://", 2)[1], ".") {
+			continue
+		}
+
+		facets = append(facets, models.Facet{
+			Index:    models.FacetByteSlice{ByteStart: start, ByteEnd: start + len(uri)},
+			Features: []models.FacetFeature{{Link: &models.FacetLink{Type: models.FacetLinkType, URI: uri}}},
+		})
+	}
+	return facets
+}
+
+func trimURL(uri string) string {
+	for {
+		r, size := utf8.DecodeLastRuneInString(uri)
+		switch {
+		case strings.ContainsRune(".,;:!?\"'", r), r > unicode.MaxASCII && unicode.IsPunct(r):
+			uri = uri[:len(uri)-size]
+		case r == ')' && strings.Count(uri, "(") < strings.Count(uri, ")"):
+			uri = uri[:len(uri)-size]
+		default:
+			return uri
+		}
+	}
+}
+
+func detectTags(text string) []models.Facet {
+	var facets []models.Facet
+	for _, m := range tagRegex.FindAllStringSubmatchIndex(text, -1) {
+		hashStart, tagStart, tagEnd := m[3], m[4], m[5]
+
+		tag := strings.TrimRightFunc(text[tagStart:tagEnd], unicode.IsPunct)
+		if tag == "" || strings.HasPrefix(tag, "\ufe0f") || utf8.RuneCountInString(tag) > maxTagLength {
+			continue
+		}
+
+		facets = append(facets, models.Facet{
+			Index:    models.FacetByteSlice{ByteStart: hashStart, ByteEnd: tagStart + len(tag)},
+			Features: []models.FacetFeature{{Tag: &models.FacetTag{Type: models.FacetTagType, Tag: tag}}},
+		})
+	}
+	return facets
+}
@@ -0,0 +1,175 @@
+package richtext
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubResolver map[string]string
+
+func (s stubResolver) ResolveHandle(ctx context.Context, handle string) (string, error) {
+	did, ok := s[handle]
+	if !ok {
+		return "", errors.New("handle not found")
+	}
+	return did, nil
+}
+
+var resolver = stubResolver{
+	"alice.shareframe.social": "did:plc:alice",
+	"bob.example.com":         "did:plc:bob",
+}
+
+type span struct {
+	text  string
+	kind  string
+	value string
+}
+
+func spans(text string, facets []models.Facet) []span {
+	var out []span
+	for _, f := range facets {
+		s := span{text: text[f.Index.ByteStart:f.Index.ByteEnd]}
+		feature := f.Features[0]
+		switch {
+		case feature.Mention != nil:
+			s.kind, s.value = "mention", feature.Mention.DID
+		case feature.Link != nil:
+			s.kind, s.value = "link", feature.Link.URI
+		case feature.Tag != nil:
+			s.kind, s.value = "tag", feature.Tag.Tag
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestDetectFacets(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []span
+	}{
+		{
+			name:     "Plain text",
+			text:     "Hello World!",
+			expected: nil,
+		},
+		{
+			name: "Mention at start",
+			text: "@alice.shareframe.social hi",
+			expected: []span{
+				{"@alice.shareframe.social", "mention", "did:plc:alice"},
+			},
+		},
+		{
+			name: "Mention with trailing period and in parentheses",
+			text: "cc (@bob.example.com) and @alice.shareframe.social.",
+			expected: []span{
+				{"@bob.example.com", "mention", "did:plc:bob"},
+				{"@alice.shareframe.social", "mention", "did:plc:alice"},
+			},
+		},
+		{
+			name:     "Unresolvable and invalid mentions are skipped",
+			text:     "@nobody.example.com @localhost email@alice.shareframe.social",
+			expected: nil,
+		},
+		{
+			name: "Mention is case-insensitive",
+			text: "@Alice.ShareFrame.Social",
+			expected: []span{
+				{"@Alice.ShareFrame.Social", "mention", "did:plc:alice"},
+			},
+		},
+		{
+			name: "Links with trailing punctuation",
+			text: "see https://example.com/path?q=1. and (https://en.wikipedia.org/wiki/Go_(language)) ok",
+			expected: []span{
+				{"https://example.com/path?q=1", "link", "https://example.com/path?q=1"},
+				{"https://en.wikipedia.org/wiki/Go_(language)", "link", "https://en.wikipedia.org/wiki/Go_(language)"},
+			},
+		},
+		{
+			name:     "Link without a dotted host is ignored",
+			text:     "http://localhost:8080/ is local",
+			expected: nil,
+		},
+		{
+			name: "Hashtags",
+			text: "#golang is fun #100DaysOfCode! #2024 #",
+			expected: []span{
+				{"#golang", "tag", "golang"},
+				{"#100DaysOfCode", "tag", "100DaysOfCode"},
+			},
+		},
+		{
+			name: "Fullwidth hash and non-Latin tag",
+			text: "旅行 ＃東京 #日本",
+			expected: []span{
+				{"＃東京", "tag", "東京"},
+				{"#日本", "tag", "日本"},
+			},
+		},
+		{
+			name:     "Fragment inside a word is not a tag",
+			text:     "issue#42 and a#b",
+			expected: nil,
+		},
+		{
+			name: "Emoji shift byte offsets",
+			text: "👨‍👩‍👧‍👦🎉 @alice.shareframe.social #família https://shareframe.social/🙂",
+			expected: []span{
+				{"@alice.shareframe.social", "mention", "did:plc:alice"},
+				{"#família", "tag", "família"},
+				{"https://shareframe.social/🙂", "link", "https://shareframe.social/🙂"},
+			},
+		},
+		{
+			name: "Japanese text around facets",
+			text: "こんにちは @bob.example.com さん、#旅 #写真 を見て https://example.jp/写真。",
+			expected: []span{
+				{"@bob.example.com", "mention", "did:plc:bob"},
+				{"#写真", "tag", "写真"},
+				{"https://example.jp/写真", "link", "https://example.jp/写真"},
+			},
+		},
+		{
+			name:     "Keycap emoji is not a tag",
+			text:     "#️⃣ keycap",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facets := DetectFacets(context.Background(), tt.text, resolver)
+			assert.Equal(t, tt.expected, spans(tt.text, facets))
+
+			for _, f := range facets {
+				assert.Len(t, f.Features, 1)
+				assert.True(t, f.Index.ByteStart < f.Index.ByteEnd)
+				assert.LessOrEqual(t, f.Index.ByteEnd, len(tt.text))
+			}
+		})
+	}
+}
+
+func TestDetectFacetsWithoutResolver(t *testing.T) {
+	facets := DetectFacets(context.Background(), "@alice.shareframe.social #tag", nil)
+
+	assert.Equal(t, []span{{"#tag", "tag", "tag"}}, spans("@alice.shareframe.social #tag", facets))
+}
+
+func TestDetectFacetsByteOffsets(t *testing.T) {
+	text := "✨ #tag"
+	facets := DetectFacets(context.Background(), text, nil)
+
+	if assert.Len(t, facets, 1) {
+		assert.Equal(t, models.FacetByteSlice{ByteStart: 4, ByteEnd: 8}, facets[0].Index)
+	}
+}
@@ -0,0 +1,124 @@
+package richtext
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ShareFrame/posting-service/richtext/grapheme"
+)
+
+type splitLevel int
+
+const (
+	splitSentences splitLevel = iota
+	splitWords
+	splitGraphemes
+)
+
+// Split breaks text into chunks of at most maxGraphemes graphemes and
+// maxBytes bytes. It breaks between sentences where it can, between words
+// when a sentence is too long on its own, and inside a word only when the
+// word itself does not fit.
+func Split(text string, maxGraphemes, maxBytes int) []string {
+	fits := func(s string) bool {
+		s = strings.TrimSpace(s)
+		return len(s) <= maxBytes && grapheme.Count(s) <= maxGraphemes
+	}
+
+	var chunks []string
+	current := ""
+	flush := func() {
+		if chunk := strings.TrimSpace(current); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current = ""
+	}
+
+	var add func(piece string, level splitLevel)
+	add = func(piece string, level splitLevel) {
+		if fits(current + piece) {
+			current += piece
+			return
+		}
+		flush()
+		if fits(piece) || level == splitGraphemes {
+			current = piece
+			return
+		}
+
+		var parts []string
+		if level == splitSentences {
+			parts = words(piece)
+		} else {
+			parts = grapheme.Clusters(piece)
+		}
+		for _, part := range parts {
+			add(part, level+1)
+		}
+	}
+
+	for _, sentence := range sentences(text) {
+		add(sentence, splitSentences)
+	}
+	flush()
+	return chunks
+}
+
+// sentences cuts text after sentence-ending punctuation followed by
+// whitespace, after ideographic full stops and after line breaks. Each
+// sentence keeps its trailing whitespace so joining them gives back text.
+func sentences(text string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+
+		next, _ := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\n', strings.ContainsRune("。！？", r):
+		case strings.ContainsRune(".!?…", r) && i < len(text) && unicode.IsSpace(next):
+		default:
+			continue
+		}
+
+		i = skipSpace(text, i)
+		out = append(out, text[start:i])
+		start = i
+	}
+	if start < len(text) {
+		out = append(out, text[start:])
+	}
+	return out
+}
+
+func words(text string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		i = skipSpace(text, i)
+		out = append(out, text[start:i])
+		start = i
+	}
+	if start < len(text) {
+		out = append(out, text[start:])
+	}
+	return out
+}
+
+func skipSpace(text string, i int) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
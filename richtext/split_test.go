@@ -0,0 +1,101 @@
+package richtext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/richtext/grapheme"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		maxGraphemes int
+		maxBytes     int
+		expected     []string
+	}{
+		{
+			name:         "Short text is left alone",
+			text:         "Hello world.",
+			maxGraphemes: 300,
+			maxBytes:     3000,
+			expected:     []string{"Hello world."},
+		},
+		{
+			name:         "Breaks between sentences",
+			text:         "First one. Second one! Third one? Fourth.",
+			maxGraphemes: 24,
+			maxBytes:     3000,
+			expected:     []string{"First one. Second one!", "Third one? Fourth."},
+		},
+		{
+			name:         "Abbreviation-like dots inside words do not break",
+			text:         "Version 1.2 is out. Update now.",
+			maxGraphemes: 20,
+			maxBytes:     3000,
+			expected:     []string{"Version 1.2 is out.", "Update now."},
+		},
+		{
+			name:         "Line breaks end sentences",
+			text:         "Line one\nLine two\nLine three",
+			maxGraphemes: 18,
+			maxBytes:     3000,
+			expected:     []string{"Line one\nLine two", "Line three"},
+		},
+		{
+			name:         "Long sentence falls back to words",
+			text:         "one two three four five six seven",
+			maxGraphemes: 14,
+			maxBytes:     3000,
+			expected:     []string{"one two three", "four five six", "seven"},
+		},
+		{
+			name:         "Long word falls back to graphemes",
+			text:         "abcdefghij",
+			maxGraphemes: 4,
+			maxBytes:     3000,
+			expected:     []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:         "Ideographic full stops",
+			text:         "今日は晴れ。明日は雨。",
+			maxGraphemes: 6,
+			maxBytes:     3000,
+			expected:     []string{"今日は晴れ。", "明日は雨。"},
+		},
+		{
+			name:         "Emoji are never cut in half",
+			text:         strings.Repeat("👍🏽", 5),
+			maxGraphemes: 2,
+			maxBytes:     3000,
+			expected:     []string{"👍🏽👍🏽", "👍🏽👍🏽", "👍🏽"},
+		},
+		{
+			name:         "Byte limit applies too",
+			text:         "ああ。いい。",
+			maxGraphemes: 300,
+			maxBytes:     9,
+			expected:     []string{"ああ。", "いい。"},
+		},
+		{
+			name:         "Empty text",
+			text:         "  ",
+			maxGraphemes: 300,
+			maxBytes:     3000,
+			expected:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Split(tt.text, tt.maxGraphemes, tt.maxBytes)
+			assert.Equal(t, tt.expected, chunks)
+			for _, chunk := range chunks {
+				assert.LessOrEqual(t, grapheme.Count(chunk), tt.maxGraphemes)
+				assert.LessOrEqual(t, len(chunk), tt.maxBytes)
+			}
+		})
+	}
+}
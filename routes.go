@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ShareFrame/posting-service/atproto"
+	"github.com/ShareFrame/posting-service/auth"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/draft"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/ShareFrame/posting-service/idempotency"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/schedule"
+	"github.com/sirupsen/logrus"
+)
+
+type CreatePostInput struct {
+	AuthToken      string               `json:"authToken"`
+	RefreshToken   string               `json:"refreshToken,omitempty"`
+	DID            string               `json:"did"`
+	Media          []models.MediaUpload `json:"media,omitempty"`
+	ReplyTo        string               `json:"replyTo,omitempty"`
+	QuoteOf        string               `json:"quoteOf,omitempty"`
+	RKey           string               `json:"rkey,omitempty"`
+	IdempotencyKey string               `json:"idempotencyKey,omitempty"`
+	ScheduledAt    string               `json:"scheduledAt,omitempty"`
+
+	models.PostInput
+}
+
+type DeletePostInput struct {
+	AuthToken    string `json:"authToken"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	DID          string `json:"did"`
+	URI          string `json:"uri"`
+}
+
+type EditPostInput struct {
+	AuthToken    string                `json:"authToken"`
+	RefreshToken string                `json:"refreshToken,omitempty"`
+	DID          string                `json:"did"`
+	URI          string                `json:"uri"`
+	Text         *string               `json:"text,omitempty"`
+	ImageUris    *[]string             `json:"imageUris,omitempty"`
+	VideoUris    *[]string             `json:"videoUris,omitempty"`
+	Media        *[]models.MediaUpload `json:"media,omitempty"`
+}
+
+type app struct {
+	client      atproto.ATProtoClient
+	policy      handler.Policy
+	verifier    *auth.Verifier
+	idempotency idempotency.Store
+	schedules   schedule.Store
+	expiries    expiry.Store
+	drafts      draft.Store
+	credentials credential.Store
+}
+
+func (a *app) route(ctx context.Context, req apiRequest) apiResponse {
+	if isCredentialsPath(req.Path) {
+		return a.routeCredentials(ctx, req)
+	}
+	if isSchedulesPath(req.Path) {
+		return a.routeSchedules(ctx, req)
+	}
+	if isDraftsPath(req.Path) {
+		return a.routeDrafts(ctx, req)
+	}
+	if isThreadsPath(req.Path) {
+		return a.routeThreads(ctx, req)
+	}
+
+	var call func(context.Context, apiRequest) (interface{}, error)
+	status := http.StatusOK
+
+	switch req.Method {
+	case http.MethodPost:
+		call, status = a.createPost, http.StatusCreated
+	case http.MethodPut, http.MethodPatch:
+		call = a.editPost
+	case http.MethodDelete:
+		call = a.deletePost
+	default:
+		return errorResponse(&handler.Error{
+			Code: handler.CodeMethodNotAllowed,
+			Err:  errors.New("method not allowed: " + req.Method),
+		})
+	}
+
+	result, err := call(ctx, req)
+	return respond(status, result, err)
+}
+
+func (a *app) createPost(ctx context.Context, req apiRequest) (interface{}, error) {
+	var input CreatePostInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, err
+	}
+	if err := handler.RejectServerOwnedFields([]byte(req.Body)); err != nil {
+		return nil, err
+	}
+	// A scheduled post is published later with the author's app password,
+	// so the PDS never sees this token and cannot reject it for us.
+	authorize := a.authorizeForwarded
+	if input.ScheduledAt != "" {
+		authorize = a.authorize
+	}
+	if err := authorize(ctx, input.AuthToken, input.DID); err != nil {
+		return nil, err
+	}
+
+	post := input.PostInput.Record()
+	post.NSID = models.FeedPostNSID
+	post.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	post.SourceApp = "ShareFrame"
+
+	payload := models.RequestPayload{
+		AuthToken:    input.AuthToken,
+		RefreshToken: input.RefreshToken,
+		DID:          input.DID,
+		Post:         post,
+		Media:        input.Media,
+		ReplyTo:      input.ReplyTo,
+		QuoteOf:      input.QuoteOf,
+		RKey:         input.RKey,
+	}
+
+	if input.ScheduledAt != "" {
+		return a.schedulePost(ctx, payload, input.ScheduledAt)
+	}
+
+	key := req.Headers["idempotency-key"]
+	if key == "" {
+		key = input.IdempotencyKey
+	}
+
+	resp, err := handler.IdempotentPostHandler(ctx, a.client, a.policy, a.idempotency, key, payload)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("PostHandler failed")
+		return nil, err
+	}
+
+	handler.TrackStoryExpiry(ctx, a.expiries, payload, resp)
+	return resp, nil
+}
+
+func (a *app) schedulePost(ctx context.Context, payload models.RequestPayload, scheduledAt string) (interface{}, error) {
+	if a.schedules == nil || a.credentials == nil {
+		return nil, &handler.Error{Code: handler.CodeInvalidRequest, Err: errors.New("invalid request: scheduled posts are not enabled")}
+	}
+
+	resp, err := handler.SchedulePost(ctx, a.policy, a.schedules, a.credentials, payload, scheduledAt)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("SchedulePost failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (a *app) editPost(ctx context.Context, req apiRequest) (interface{}, error) {
+	var input EditPostInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, err
+	}
+	if err := handler.RejectServerOwnedFields([]byte(req.Body)); err != nil {
+		return nil, err
+	}
+	if err := a.authorizeForwarded(ctx, input.AuthToken, input.DID); err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.EditHandler(ctx, a.client, a.policy, models.EditRequestPayload{
+		AuthToken:    input.AuthToken,
+		RefreshToken: input.RefreshToken,
+		DID:          input.DID,
+		URI:          input.URI,
+		Text:         input.Text,
+		ImageUris:    input.ImageUris,
+		VideoUris:    input.VideoUris,
+		Media:        input.Media,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("EditHandler failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (a *app) deletePost(ctx context.Context, req apiRequest) (interface{}, error) {
+	var input DeletePostInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, err
+	}
+	if err := a.authorizeForwarded(ctx, input.AuthToken, input.DID); err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.DeleteHandler(ctx, a.client, models.DeleteRequestPayload{
+		AuthToken:    input.AuthToken,
+		RefreshToken: input.RefreshToken,
+		DID:          input.DID,
+		URI:          input.URI,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("DeleteHandler failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// authorize checks authToken is a current access token for did. An expired
+// access token is rejected even when a refresh token is sent: the client
+// refreshes its session itself. Without a verifier every request is refused.
+func (a *app) authorize(ctx context.Context, authToken, did string) error {
+	if a.verifier == nil {
+		return &handler.Error{Code: handler.CodeForbidden, Err: errors.New("this route requires auth token verification")}
+	}
+	if authToken == "" || did == "" {
+		return &handler.Error{Code: handler.CodeInvalidRequest, Err: errors.New("invalid request: missing 'authToken' or 'did'")}
+	}
+
+	_, err := a.verifier.Verify(ctx, authToken, did)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, auth.ErrInvalidToken) {
+		logrus.WithError(err).Error("Failed to verify auth token")
+		return &handler.Error{Code: handler.CodeUpstreamUnavailable, Err: fmt.Errorf("verifying auth token failed: %w", err)}
+	}
+
+	logrus.WithError(err).WithField("DID", did).Warn("Rejected auth token")
+	return &handler.Error{Code: handler.CodeUnauthorized, Err: fmt.Errorf("unauthorized: %w", err)}
+}
+
+// authorizeForwarded is for routes that send authToken on to the PDS, which
+// rejects a bad token itself. It only calls authorize when a verifier is
+// configured, and leaves a missing token for the handler to report.
+func (a *app) authorizeForwarded(ctx context.Context, authToken, did string) error {
+	if a.verifier == nil || authToken == "" || did == "" {
+		return nil
+	}
+	return a.authorize(ctx, authToken, did)
+}
+
+func decodeBody(req apiRequest, v interface{}) error {
+	if err := json.Unmarshal([]byte(req.Body), v); err != nil {
+		logrus.WithError(err).Error("Failed to parse request body")
+		return &handler.Error{Code: handler.CodeInvalidRequest, Err: errors.New("invalid input")}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ShareFrame/posting-service/jsonstore"
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+)
+
+var (
+	ErrNotFound = errors.New("scheduled post not found")
+	ErrExists   = errors.New("scheduled post already exists")
+)
+
+type Status = lease.Status
+
+const (
+	StatusPending   = lease.StatusPending
+	StatusPublished = Status("published")
+	StatusFailed    = lease.StatusFailed
+	StatusCanceled  = Status("canceled")
+)
+
+// Entry is a post waiting to be published. The ID doubles as the record key
+// the post will be written under, so URI is known as soon as it is scheduled.
+type Entry struct {
+	ID          string                `json:"id"`
+	DID         string                `json:"did"`
+	URI         string                `json:"uri"`
+	Request     models.RequestPayload `json:"request"`
+	ScheduledAt time.Time             `json:"scheduledAt"`
+	CID         string                `json:"cid,omitempty"`
+	CreatedAt   time.Time             `json:"createdAt"`
+	lease.State
+}
+
+// Store persists scheduled posts. Update loads an entry, applies fn to it and
+// saves the result atomically; when fn returns an error nothing is saved and
+// that error is returned.
+type Store interface {
+	Create(ctx context.Context, entry Entry) error
+	Get(ctx context.Context, did, id string) (*Entry, error)
+	List(ctx context.Context, did string) ([]Entry, error)
+	Due(ctx context.Context, now time.Time, limit int) ([]Entry, error)
+	Update(ctx context.Context, did, id string, fn func(*Entry) error) (*Entry, error)
+}
+
+var config = jsonstore.Config{Name: "scheduled post", ErrNotFound: ErrNotFound, ErrExists: ErrExists}
+
+func NewMemoryStore() Store {
+	return &store{entries: jsonstore.NewMemory[Entry](config)}
+}
+
+func NewFileStore(dir string) (Store, error) {
+	entries, err := jsonstore.NewFile[Entry](dir, config)
+	if err != nil {
+		return nil, err
+	}
+	return &store{entries: entries}, nil
+}
+
+type store struct {
+	entries jsonstore.Store[Entry]
+}
+
+func (s *store) Create(ctx context.Context, entry Entry) error {
+	return s.entries.Create(ctx, entryKey(entry.DID, entry.ID), entry)
+}
+
+func (s *store) Get(ctx context.Context, did, id string) (*Entry, error) {
+	return s.entries.Get(ctx, entryKey(did, id))
+}
+
+func (s *store) List(ctx context.Context, did string) ([]Entry, error) {
+	all, err := s.entries.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, e := range all {
+		if e.DID == did {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ScheduledAt.Before(entries[j].ScheduledAt)
+	})
+	return entries, nil
+}
+
+func (s *store) Due(ctx context.Context, now time.Time, limit int) ([]Entry, error) {
+	all, err := s.entries.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lease.Due(all, now, limit), nil
+}
+
+func (s *store) Update(ctx context.Context, did, id string, fn func(*Entry) error) (*Entry, error) {
+	return s.entries.Update(ctx, entryKey(did, id), fn)
+}
+
+func entryKey(did, id string) string {
+	return did + "/" + id
+}
@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/lease"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(did, id string, at time.Time) Entry {
+		return Entry{
+			ID:          id,
+			DID:         did,
+			URI:         "at://" + did + "/social.shareframe.feed.post/" + id,
+			Request:     models.RequestPayload{DID: did, Post: models.ShareFrameFeedPost{Text: "Later"}},
+			ScheduledAt: at,
+			CreatedAt:   start,
+			State:       lease.State{Status: StatusPending, NextAttemptAt: at},
+		}
+	}
+
+	store := NewMemoryStore()
+	require.NoError(t, store.Create(ctx, entry("did:plc:alice", "b", start.Add(2*time.Hour))))
+	require.NoError(t, store.Create(ctx, entry("did:plc:alice", "a", start.Add(-time.Minute))))
+	require.NoError(t, store.Create(ctx, entry("did:plc:bob", "c", start.Add(-time.Hour))))
+	canceled := entry("did:plc:bob", "canceled", start.Add(-2*time.Hour))
+	canceled.Status = StatusCanceled
+	require.NoError(t, store.Create(ctx, canceled))
+	assert.ErrorIs(t, store.Create(ctx, entry("did:plc:alice", "a", start)), ErrExists)
+
+	t.Run("Get is per DID", func(t *testing.T) {
+		got, err := store.Get(ctx, "did:plc:alice", "a")
+		require.NoError(t, err)
+		assert.Equal(t, "Later", got.Request.Post.Text)
+
+		_, err = store.Get(ctx, "did:plc:bob", "a")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("List is per DID and ordered by schedule", func(t *testing.T) {
+		entries, err := store.List(ctx, "did:plc:alice")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "a", entries[0].ID)
+		assert.Equal(t, "b", entries[1].ID)
+	})
+
+	t.Run("Due skips canceled and future posts", func(t *testing.T) {
+		due, err := store.Due(ctx, start, 0)
+		require.NoError(t, err)
+		var ids []string
+		for _, e := range due {
+			ids = append(ids, e.ID)
+		}
+		assert.Equal(t, []string{"c", "a"}, ids)
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+)
+
+type ScheduleInput struct {
+	AuthToken    string `json:"authToken"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	DID          string `json:"did"`
+	ID           string `json:"id"`
+	ScheduledAt  string `json:"scheduledAt,omitempty"`
+}
+
+func isSchedulesPath(path string) bool {
+	return strings.HasSuffix(strings.TrimRight(path, "/"), "/schedules")
+}
+
+func (a *app) routeSchedules(ctx context.Context, req apiRequest) apiResponse {
+	var call func(context.Context, apiRequest) (interface{}, error)
+
+	switch req.Method {
+	case http.MethodPut, http.MethodPatch:
+		call = a.reschedulePost
+	case http.MethodDelete:
+		call = a.cancelScheduledPost
+	default:
+		return errorResponse(&handler.Error{
+			Code: handler.CodeMethodNotAllowed,
+			Err:  errors.New("method not allowed: " + req.Method),
+		})
+	}
+
+	result, err := call(ctx, req)
+	return respond(http.StatusOK, result, err)
+}
+
+func (a *app) reschedulePost(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.scheduleInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.ReschedulePost(ctx, a.schedules, input.DID, input.ID, input.ScheduledAt)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("ReschedulePost failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (a *app) cancelScheduledPost(ctx context.Context, req apiRequest) (interface{}, error) {
+	input, err := a.scheduleInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler.CancelScheduledPost(ctx, a.schedules, input.DID, input.ID)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("CancelScheduledPost failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Changing a schedule never reaches the PDS, so unlike the other routes it
+// cannot fall back on the PDS to reject a bad token.
+func (a *app) scheduleInput(ctx context.Context, req apiRequest) (*ScheduleInput, error) {
+	if a.schedules == nil {
+		return nil, &handler.Error{Code: handler.CodeNotFound, Err: errors.New("scheduled posts are not enabled")}
+	}
+
+	var input ScheduleInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, err
+	}
+	if err := a.authorize(ctx, input.AuthToken, input.DID); err != nil {
+		return nil, err
+	}
+
+	return &input, nil
+}
+
+func (a *app) dispatchScheduled(ctx context.Context, event events.EventBridgeEvent) (*handler.DispatchResult, error) {
+	logrus.WithFields(logrus.Fields{"id": event.ID, "detailType": event.DetailType}).Info("Dispatching scheduled posts")
+	return handler.DispatchScheduledPosts(ctx, a.client, a.policy, a.schedules, a.credentials, a.expiries, time.Now())
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/auth"
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/ShareFrame/posting-service/schedule"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduleEvent(method, body string) json.RawMessage {
+	return mustMarshal(map[string]interface{}{
+		"httpMethod": method,
+		"path":       "/schedules",
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       body,
+	})
+}
+
+func TestScheduledPostLifecycle(t *testing.T) {
+	secret := []byte("test-secret")
+	alice := hs256Token(secret, "did:plc:alice", time.Now().Add(time.Hour))
+	mallory := hs256Token(secret, "did:plc:mallory", time.Now().Add(time.Hour))
+
+	serviceToken := hs256ScopedToken([]byte("pds-secret"), "did:plc:alice", "com.atproto.appPass", time.Now().Add(time.Hour))
+
+	var published []string
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/com.atproto.server.createSession") {
+			w.Write([]byte(`{"did":"did:plc:alice","accessJwt":"` + serviceToken + `","refreshJwt":"refresh"}`))
+			return
+		}
+		assert.Equal(t, "Bearer "+serviceToken, r.Header.Get("Authorization"))
+		var req struct {
+			RKey string `json:"rkey"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		published = append(published, req.RKey)
+		w.Write([]byte(`{"uri":"at://did:plc:alice/social.shareframe.feed.post/` + req.RKey + `","cid":"bafyre123456"}`))
+	})
+	a.schedules = schedule.NewMemoryStore()
+	a.credentials = credential.NewMemoryStore()
+	a.verifier = newTestVerifier(t, auth.Config{Keys: auth.StaticKeys{"": secret}, Audience: "did:web:pds.test"})
+
+	resp, err := a.handleEvent(context.Background(), restEvent(http.MethodPost, `{"authToken":"`+alice+`","did":"did:plc:alice","text":"Later","scheduledAt":"`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"}`))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "scheduling needs an app password")
+
+	resp, err = a.handleEvent(context.Background(), credentialEvent(http.MethodPut, `{"authToken":"`+alice+`","did":"did:plc:alice","appPassword":"aaaa-bbbb-cccc-dddd"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, resp.Body)
+
+	schedulePost := func(at time.Time) models.ScheduledPostResponse {
+		body := string(mustMarshal(map[string]string{
+			"authToken":   alice,
+			"did":         "did:plc:alice",
+			"text":        "Later",
+			"scheduledAt": at.UTC().Format(time.RFC3339),
+		}))
+		resp, err := a.handleEvent(context.Background(), restEvent(http.MethodPost, body))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, resp.Body)
+
+		var scheduled models.ScheduledPostResponse
+		require.NoError(t, json.Unmarshal([]byte(resp.Body), &scheduled))
+		return scheduled
+	}
+
+	kept := schedulePost(time.Now().Add(time.Hour))
+	canceled := schedulePost(time.Now().Add(2 * time.Hour))
+	assert.Equal(t, "pending", kept.Status)
+	assert.Empty(t, published)
+
+	resp, err = a.handleEvent(context.Background(), scheduleEvent(http.MethodDelete, `{"authToken":"`+mallory+`","did":"did:plc:alice","id":"`+canceled.ID+`"}`))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = a.handleEvent(context.Background(), scheduleEvent(http.MethodDelete, `{"authToken":"`+alice+`","did":"did:plc:alice","id":"`+canceled.ID+`"}`))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = a.handleEvent(context.Background(), scheduleEvent(http.MethodPatch, `{"authToken":"`+alice+`","did":"did:plc:alice","id":"`+canceled.ID+`","scheduledAt":"`+time.Now().Add(3*time.Hour).UTC().Format(time.RFC3339)+`"}`))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	_, err = a.schedules.Update(context.Background(), "did:plc:alice", kept.ID, func(e *schedule.Entry) error {
+		e.NextAttemptAt = time.Now().Add(-time.Second)
+		return nil
+	})
+	require.NoError(t, err)
+
+	result, err := a.dispatchScheduled(context.Background(), events.EventBridgeEvent{DetailType: "Scheduled Event"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Published)
+	assert.Equal(t, []string{kept.ID}, published)
+}
+
+func TestSchedulesRequireVerification(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected PDS call to %s", r.URL.Path)
+	})
+	a.schedules = schedule.NewMemoryStore()
+
+	resp, err := a.handleEvent(context.Background(), scheduleEvent(http.MethodDelete, `{"authToken":"token","did":"did:plc:alice","id":"3kq2ve7ruvk2a"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestSchedulingRequiresVerification(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected PDS call to %s", r.URL.Path)
+	})
+	a.schedules = schedule.NewMemoryStore()
+	a.credentials = credential.NewMemoryStore()
+	require.NoError(t, a.credentials.Put(context.Background(), credential.Credential{DID: "did:plc:alice", AppPassword: "aaaa-bbbb-cccc-dddd"}))
+
+	resp, err := a.handleEvent(context.Background(), restEvent(http.MethodPost, `{"authToken":"token","did":"did:plc:alice","text":"Later","scheduledAt":"`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	entries, err := a.schedules.List(context.Background(), "did:plc:alice")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxRequestBody  = 10 << 20
+	shutdownTimeout = 15 * time.Second
+)
+
+func (a *app) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read request body")
+		writeResponse(w, errorResponse(&handler.Error{
+			Code: handler.CodeInvalidRequest,
+			Err:  errors.New("invalid request: body could not be read"),
+		}))
+		return
+	}
+
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		headers[strings.ToLower(k)] = strings.Join(v, ",")
+	}
+
+	writeResponse(w, a.route(r.Context(), apiRequest{
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Headers: headers,
+		Body:    string(body),
+	}))
+}
+
+func writeResponse(w http.ResponseWriter, resp apiResponse) {
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.WriteString(w, resp.Body); err != nil {
+		logrus.WithError(err).Warn("Failed to write response body")
+	}
+}
+
+func serve(ctx context.Context, ln net.Listener, h http.Handler) error {
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	logrus.Info("Shutting down HTTP server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logrus.WithError(err).Error("HTTP server did not shut down cleanly")
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
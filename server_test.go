@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeHTTP(t *testing.T) {
+	const created = `{"uri":"at://did:plc:alice/social.shareframe.feed.post/xyz","cid":"bafyre123456"}`
+
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		pds          http.HandlerFunc
+		expectStatus int
+		expectCode   string
+	}{
+		{
+			name:         "Create post",
+			method:       http.MethodPost,
+			body:         `{"authToken":"valid_token","did":"did:plc:alice","text":"Hello World!"}`,
+			pds:          pdsReplying(http.StatusOK, created, nil),
+			expectStatus: http.StatusCreated,
+		},
+		{
+			name:         "Validation failure",
+			method:       http.MethodPost,
+			body:         `{"authToken":"valid_token","did":"did:plc:alice","likes":5}`,
+			expectStatus: http.StatusBadRequest,
+			expectCode:   "invalid_request",
+		},
+		{
+			name:         "PDS rate limits",
+			method:       http.MethodPost,
+			body:         `{"authToken":"valid_token","did":"did:plc:alice","text":"hi"}`,
+			pds:          pdsReplying(http.StatusTooManyRequests, `{"error":"RateLimitExceeded"}`, nil),
+			expectStatus: http.StatusTooManyRequests,
+			expectCode:   "rate_limited",
+		},
+		{
+			name:         "Unsupported method",
+			method:       http.MethodGet,
+			expectStatus: http.StatusMethodNotAllowed,
+			expectCode:   "method_not_allowed",
+		},
+		{
+			name:         "Body too large",
+			method:       http.MethodPost,
+			body:         `{"text":"` + strings.Repeat("a", maxRequestBody) + `"}`,
+			expectStatus: http.StatusBadRequest,
+			expectCode:   "invalid_request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pds := tt.pds
+			if pds == nil {
+				pds = func(w http.ResponseWriter, r *http.Request) {
+					t.Errorf("unexpected PDS call to %s", r.URL.Path)
+				}
+			}
+			srv := httptest.NewServer(newTestApp(t, pds))
+			defer srv.Close()
+
+			req, err := http.NewRequest(tt.method, srv.URL+"/posts", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := srv.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectStatus, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			if tt.expectCode != "" {
+				var body models.ErrorResponse
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.Equal(t, tt.expectCode, body.Error)
+			} else {
+				var body models.PostResponse
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+				assert.Equal(t, "bafyre123456", body.CID)
+			}
+		})
+	}
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, ln, h)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-served:
+		t.Fatal("server stopped before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	res := <-inFlight
+	assert.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+
+	_, err = http.Get("http://" + ln.Addr().String())
+	assert.Error(t, err)
+}
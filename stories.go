@@ -0,0 +1,15 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+)
+
+func (a *app) sweepExpired(ctx context.Context, event events.EventBridgeEvent) (*handler.SweepResult, error) {
+	logrus.WithFields(logrus.Fields{"id": event.ID, "detailType": event.DetailType}).Info("Sweeping expired stories")
+	return handler.SweepExpiredStories(ctx, a.client, a.expiries, a.credentials, time.Now())
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ShareFrame/posting-service/credential"
+	"github.com/ShareFrame/posting-service/expiry"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoryExpiry(t *testing.T) {
+	serviceToken := hs256ScopedToken([]byte("pds-secret"), "did:plc:alice", "com.atproto.appPass", time.Now().Add(time.Hour))
+
+	var deleted []string
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			w.Write([]byte(`{"did":"did:plc:alice","accessJwt":"` + serviceToken + `","refreshJwt":"refresh"}`))
+		case "/xrpc/com.atproto.repo.createRecord":
+			w.Write([]byte(`{"uri":"at://did:plc:alice/social.shareframe.feed.post/3kq2ve7ruvk2a","cid":"bafyre123456"}`))
+		case "/xrpc/com.atproto.repo.deleteRecord":
+			assert.Equal(t, "Bearer "+serviceToken, r.Header.Get("Authorization"))
+			var req struct {
+				RKey string `json:"rkey"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			deleted = append(deleted, req.RKey)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected PDS call to %s", r.URL.Path)
+		}
+	})
+	a.expiries = expiry.NewMemoryStore()
+	a.credentials = credential.NewMemoryStore()
+	require.NoError(t, a.credentials.Put(context.Background(), credential.Credential{DID: "did:plc:alice", AppPassword: "aaaa-bbbb-cccc-dddd"}))
+
+	resp, err := a.handleEvent(context.Background(), restEvent(http.MethodPost, `{"authToken":"valid_token","did":"did:plc:alice","text":"Gone soon","isStory":true}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, resp.Body)
+
+	var created models.PostResponse
+	require.NoError(t, json.Unmarshal([]byte(resp.Body), &created))
+	assert.NotEmpty(t, created.ExpiresAt)
+
+	result, err := a.sweepExpired(context.Background(), events.EventBridgeEvent{DetailType: "Scheduled Event"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Deleted)
+	assert.Empty(t, deleted)
+
+	_, err = a.expiries.Update(context.Background(), created.URI, func(e *expiry.Entry) error {
+		e.NextAttemptAt = time.Now().Add(-time.Second)
+		return nil
+	})
+	require.NoError(t, err)
+
+	result, err = a.sweepExpired(context.Background(), events.EventBridgeEvent{DetailType: "Scheduled Event"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Deleted)
+	assert.Equal(t, []string{"3kq2ve7ruvk2a"}, deleted)
+
+	entry, err := a.expiries.Get(context.Background(), created.URI)
+	require.NoError(t, err)
+	assert.Equal(t, expiry.StatusDeleted, entry.Status)
+}
+
+func TestCreatePostRejectsExpiresAtBeyondMaximum(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected PDS call to %s", r.URL.Path)
+	})
+
+	expiresAt := time.Now().Add(30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	resp, err := a.handleEvent(context.Background(), restEvent(http.MethodPost, `{"authToken":"valid_token","did":"did:plc:alice","text":"Gone soon","isStory":true,"expiresAt":"`+expiresAt+`"}`))
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, resp.Body, "expiresAt: must be no more than")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ShareFrame/posting-service/handler"
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/sirupsen/logrus"
+)
+
+// ThreadInput takes either Posts, or Text that is split into posts at
+// sentence boundaries.
+type ThreadInput struct {
+	AuthToken    string            `json:"authToken"`
+	RefreshToken string            `json:"refreshToken,omitempty"`
+	DID          string            `json:"did"`
+	ReplyTo      string            `json:"replyTo,omitempty"`
+	Text         string            `json:"text,omitempty"`
+	Posts        []ThreadPostInput `json:"posts,omitempty"`
+}
+
+type ThreadPostInput struct {
+	Media []models.MediaUpload `json:"media,omitempty"`
+
+	models.PostInput
+}
+
+func isThreadsPath(path string) bool {
+	return strings.HasSuffix(strings.TrimRight(path, "/"), "/threads")
+}
+
+func (a *app) routeThreads(ctx context.Context, req apiRequest) apiResponse {
+	if req.Method != http.MethodPost {
+		return errorResponse(&handler.Error{
+			Code: handler.CodeMethodNotAllowed,
+			Err:  errors.New("method not allowed: " + req.Method),
+		})
+	}
+
+	result, err := a.createThread(ctx, req)
+	return respond(http.StatusCreated, result, err)
+}
+
+func (a *app) createThread(ctx context.Context, req apiRequest) (interface{}, error) {
+	var input ThreadInput
+	if err := decodeBody(req, &input); err != nil {
+		return nil, err
+	}
+	if err := handler.RejectServerOwnedFields([]byte(req.Body)); err != nil {
+		return nil, err
+	}
+	if err := a.authorizeForwarded(ctx, input.AuthToken, input.DID); err != nil {
+		return nil, err
+	}
+
+	if len(input.Posts) == 0 {
+		for _, text := range handler.SplitThreadText(input.Text) {
+			input.Posts = append(input.Posts, ThreadPostInput{PostInput: models.PostInput{Text: text}})
+		}
+	} else if input.Text != "" {
+		return nil, &handler.Error{Code: handler.CodeInvalidRequest, Err: errors.New("invalid request: set either 'text' or 'posts', not both")}
+	}
+
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	payload := models.ThreadRequestPayload{
+		AuthToken:    input.AuthToken,
+		RefreshToken: input.RefreshToken,
+		DID:          input.DID,
+		ReplyTo:      input.ReplyTo,
+	}
+	for _, p := range input.Posts {
+		post := p.PostInput.Record()
+		post.NSID = models.FeedPostNSID
+		post.CreatedAt = createdAt
+		payload.Posts = append(payload.Posts, models.ThreadPost{Post: post, Media: p.Media})
+	}
+
+	resp, err := handler.PostThread(ctx, a.client, a.policy, payload)
+	if err != nil {
+		logrus.WithError(err).WithField("code", handler.ErrorCodeOf(err)).Error("PostThread failed")
+		return nil, err
+	}
+
+	return resp, nil
+}
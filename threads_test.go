@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ShareFrame/posting-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func threadEvent(body string) json.RawMessage {
+	return mustMarshal(map[string]interface{}{
+		"httpMethod": http.MethodPost,
+		"path":       "/threads",
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       body,
+	})
+}
+
+func TestCreateThread(t *testing.T) {
+	const sentence = "A sentence that fills some space in the thread. "
+
+	tests := []struct {
+		name          string
+		body          map[string]interface{}
+		expectStatus  int
+		expectedTexts []string
+	}{
+		{
+			name:          "Explicit posts",
+			body:          map[string]interface{}{"authToken": "valid_token", "did": "did:plc:alice", "posts": []map[string]string{{"text": "One"}, {"text": "Two"}}},
+			expectStatus:  http.StatusCreated,
+			expectedTexts: []string{"One", "Two"},
+		},
+		{
+			name:          "Long text is split",
+			body:          map[string]interface{}{"authToken": "valid_token", "did": "did:plc:alice", "text": strings.Repeat(sentence, 10)},
+			expectStatus:  http.StatusCreated,
+			expectedTexts: []string{strings.TrimSpace(strings.Repeat(sentence, 6)), strings.TrimSpace(strings.Repeat(sentence, 4))},
+		},
+		{
+			name:         "Text and posts together",
+			body:         map[string]interface{}{"authToken": "valid_token", "did": "did:plc:alice", "text": "Hi", "posts": []map[string]string{{"text": "One"}}},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "Nothing to post",
+			body:         map[string]interface{}{"authToken": "valid_token", "did": "did:plc:alice"},
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes []models.ApplyWritesCreate
+			a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/xrpc/com.atproto.repo.applyWrites", r.URL.Path)
+				var req models.ApplyWritesRequest
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				writes = req.Writes
+				w.Write([]byte(`{"commit":{"cid":"commit123","rev":"rev123"}}`))
+			})
+
+			resp, err := a.handleEvent(context.Background(), threadEvent(string(mustMarshal(tt.body))))
+			require.NoError(t, err)
+			require.Equal(t, tt.expectStatus, resp.StatusCode, resp.Body)
+			if tt.expectedTexts == nil {
+				assert.Empty(t, writes)
+				return
+			}
+
+			var thread models.ThreadResponse
+			require.NoError(t, json.Unmarshal([]byte(resp.Body), &thread))
+			require.Len(t, thread.Posts, len(tt.expectedTexts))
+			require.Len(t, writes, len(tt.expectedTexts))
+			for i, write := range writes {
+				assert.Equal(t, tt.expectedTexts[i], write.Value.Text)
+				if i == 0 {
+					assert.Nil(t, write.Value.Reply)
+					continue
+				}
+				require.NotNil(t, write.Value.Reply)
+				assert.Equal(t, thread.Posts[0].URI, write.Value.Reply.Root.URI)
+				assert.Equal(t, thread.Posts[i-1].URI, write.Value.Reply.Parent.URI)
+				assert.Equal(t, thread.Posts[i-1].CID, write.Value.Reply.Parent.CID)
+			}
+		})
+	}
+}